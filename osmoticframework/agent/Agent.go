@@ -33,7 +33,10 @@ func main() {
 	}
 	//Remove all containers before starting the agent
 	//This is to ensure there are no dangling containers running if the agent has crashed and restarted
-	stopContainers()
+	//An agent that registered before keeps its containers. It rejoins the controller, which tells it which ones to remove
+	if api.LoadSession() == "" {
+		stopContainers()
+	}
 	api.Init()
 }
 
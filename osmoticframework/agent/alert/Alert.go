@@ -4,4 +4,5 @@ type AlertType string
 
 const (
 	AlertContainerCrash AlertType = "containerCrash"
+	AlertContainerEvent AlertType = "containerEvent"
 )
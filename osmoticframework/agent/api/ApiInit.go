@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/lithammer/shortuuid"
 	"github.com/streadway/amqp"
 	"net"
@@ -10,13 +11,25 @@ import (
 	"osmoticframework/agent/log"
 	"osmoticframework/agent/types"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 )
 
+//Channel of the current connection. Only used while setting up the connection
 var ch *amqp.Channel
+
+//Channel that messages are sent on. Nil while the agent is reconnecting
+//Goroutines like the health check outlive a connection, so it is guarded by publishLock
+//Registration and the queues are set up before it is set, so that messages are never sent with a half set up connection
+var publishChannel *amqp.Channel
+var publishLock sync.RWMutex
 var deployQueue amqp.Queue
 var monitorQueue amqp.Queue
 var agentId string
+
+//Secret given by the controller. It proves the agent's identity when rejoining
+var secret string
 var responseQueue amqp.Queue
 var alertQueue amqp.Queue
 
@@ -27,102 +40,188 @@ var (
 	AGENT      RegisterMessageDirection = "agent"
 )
 
+//Delay before reconnecting to RabbitMQ. It doubles after every failed attempt, up to maxReconnectDelay
+const minReconnectDelay = time.Second
+const maxReconnectDelay = time.Minute
+
+//Returned when the controller rejects the agent ID because the agent has been revoked
+var errRevoked = errors.New("agent revoked")
+
+//Returned when sending a message while the agent is reconnecting
+var errReconnecting = errors.New("not connected to RabbitMQ")
+
+//Monitoring services and the health check only start once. They keep running while the agent reconnects
+var servicesOnce sync.Once
+
+/*
+Connects to RabbitMQ and serves the API. This function never returns.
+When the connection is lost, the agent reconnects and rejoins the controller under the same agent ID.
+The listener runs on goroutines, so this blocks the main thread to keep them alive.
+If you need to run anything else alongside the listener, use a goroutine before running this function.
+*/
 func Init() {
+	if !strings.HasPrefix(constants.GetRabbitAddress(), "amqp://") && !strings.HasPrefix(constants.GetRabbitAddress(), "amqps://") {
+		log.Fatal.Fatalln("Invalid RabbitMQ address. Address must start with amqp:// or amqps://")
+	}
+	delay := minReconnectDelay
+	for {
+		established, err := session()
+		if err == errRevoked {
+			log.Fatal.Fatalf("Agent %s has been revoked by the controller. Remove %s to register as a new agent\n", agentId, constants.GetAgentIdFile())
+		}
+		if established {
+			delay = minReconnectDelay
+		}
+		log.Error.Printf("Connection to RabbitMQ lost. Reconnecting in %s\n", delay)
+		log.Error.Println(err)
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+//Runs one connection to RabbitMQ until it is closed
+//Returns whether the API was started, and why the connection ended
+func session() (bool, error) {
 	//Initialize the connection
 	log.Info.Println("Connecting to RabbitMQ server " + constants.GetRabbitAddress())
-	server, err := amqp.Dial(constants.GetRabbitAddress())
+	server, err := dial()
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	defer server.Close()
 	ch, err = server.Channel()
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	defer ch.Close()
+	serverClosed := server.NotifyClose(make(chan *amqp.Error, 1))
+	channelClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
 
 	//Register itself to the controller
-	register()
+	err = register()
+	if err != nil {
+		return false, err
+	}
 
 	//Start queues and consumers
 	deployQueue, err = declareExpireQueue("deploy-"+agentId, 30000)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	monitorQueue, err = declareExpireQueue("monitor-"+agentId, 30000)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	pingQueue, err := declareExpireQueue("ping", 30000)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 
 	responseQueue, err = declareControllerQueue("response", true)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 
 	alertQueue, err = declareControllerQueue("alert", true)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 
 	deployStream, err := newConsumer(deployQueue.Name)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	monitorStream, err := newConsumer(monitorQueue.Name)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	pingStream, err := newConsumer(pingQueue.Name)
+	if err != nil {
+		return false, err
+	}
 
-	/*
-		This channel does not serve any data.
-		We need to run more than just the API listener, so we need to use goroutines.
-		Since the listener is running on a goroutine. The channel is here to prevent the main thread from terminating.
-		When a main thread of the application terminates, all running goroutines will terminate as well no matter if they are still operating.
-		If you need to run anything else alongside the listener, use a goroutine before running this function.
-		The listener must be fired last in the main thread.
-	*/
-	forever := make(chan bool)
-
+	setPublishChannel(ch)
+	defer setPublishChannel(nil)
 	startRoutine(deployStream, monitorStream, pingStream)
 
 	log.Info.Println("API startup complete. Awaiting instructions")
-	<-forever
+	//Wait until either the connection or the channel is closed. A clean close gives a nil error
+	var closeErr *amqp.Error
+	select {
+	case closeErr = <-serverClosed:
+	case closeErr = <-channelClosed:
+	}
+	if closeErr == nil {
+		return true, errors.New("connection closed")
+	}
+	return true, closeErr
+}
+
+//Replaces the channel that messages are sent on. Waits for messages that are being sent
+func setPublishChannel(channel *amqp.Channel) {
+	publishLock.Lock()
+	defer publishLock.Unlock()
+	publishChannel = channel
 }
 
 //Registration
-func register() {
-	log.Info.Println("Registering")
+//An agent with an ID from a previous session rejoins instead. The controller replies with the containers to remove
+//The reply carries the secret of the agent, so it is sent to a queue that only this connection can read instead of the shared register queue
+//A rejoining agent proves its identity by signing the request with its secret. The secret itself is never sent
+func register() error {
+	rejoin := agentId != ""
+	if rejoin {
+		log.Info.Println("Rejoining as agent " + agentId)
+	} else {
+		log.Info.Println("Registering")
+	}
 	registerQueue, err := declareQueue("register", true)
 	if err != nil {
-		log.Fatal.Println("failed declare queue during registration")
-		log.Fatal.Panicln(err)
-		return
+		log.Error.Println("failed declare queue during registration")
+		return err
 	}
-	regStream, err := newConsumer(registerQueue.Name)
+	replyQueue, err := declareReplyQueue()
 	if err != nil {
-		log.Fatal.Println("Failed starting consumer during registration")
-		log.Fatal.Panicln(err)
-		return
+		log.Error.Println("failed declare reply queue during registration")
+		return err
+	}
+	regStream, err := newConsumer(replyQueue.Name)
+	if err != nil {
+		log.Error.Println("Failed starting consumer during registration")
+		return err
 	}
 
 	//Construct registration message
 	helloId := shortuuid.New()
-	hello, err := json.Marshal(map[string]interface{}{
+	helloMsg := map[string]interface{}{
 		"requestId":     helloId,
 		"direction":     ">>",
+		"replyTo":       replyQueue.Name,
 		"internalIP":    getInternalIP(constants.GetNetworkInterface()),
 		"devSupport":    constants.GetDeviceSupport(),
 		"sensorSupport": constants.GetSensorSupport(),
-	})
+		//The controller asks for the token if the agent registers as a new agent, which can also happen when rejoining
+		"token": constants.GetRegistrationToken(),
+	}
+	if rejoin {
+		helloMsg["agentId"] = agentId
+		helloMsg["containers"] = managedContainers()
+	}
+	hello, err := json.Marshal(helloMsg)
 	if err != nil {
-		log.Fatal.Println("Failed composing register request")
-		log.Fatal.Panicln(err)
-		return
+		log.Error.Println("Failed composing register request")
+		return err
+	}
+	var headers amqp.Table
+	if rejoin && secret != "" {
+		headers, err = sign(secret, registerQueue.Name, agentId, hello, time.Now())
+		if err != nil {
+			log.Error.Println("Failed signing register request")
+			return err
+		}
 	}
 	err = ch.Publish(
 		"",
@@ -131,27 +230,28 @@ func register() {
 		false,
 		amqp.Publishing{
 			ContentType: "application/json",
+			Headers:     headers,
 			Body:        hello,
 		},
 	)
 	if err != nil {
-		log.Fatal.Println("Failed requesting registration")
-		log.Fatal.Panicln(err)
-		return
+		log.Error.Println("Failed requesting registration")
+		return err
 	}
 
 	//Wait for response
-	var consumeTag string
-
-	//Check for timeout
-	go func() {
-		time.Sleep(time.Second * 10)
-		if agentId == "" {
-			log.Fatal.Panicln("Registration timeout")
+	timeout := time.After(time.Second * 10)
+	for {
+		var message amqp.Delivery
+		var ok bool
+		select {
+		case message, ok = <-regStream:
+			if !ok {
+				return errors.New("registration stream ended")
+			}
+		case <-timeout:
+			return errors.New("registration timeout")
 		}
-	}()
-
-	for message := range regStream {
 		var jsonMsg map[string]interface{}
 		err := json.Unmarshal(message.Body, &jsonMsg)
 		//fmt.Println(string(message.Body))
@@ -166,55 +266,62 @@ func register() {
 		//Consume (In RabbitMQ terms, acknowledge) the message and removes it from the queue
 		//Otherwise the message will stay at the queue and resend if anyone reconnects.
 		_ = message.Ack(true)
-		registerFinish := false
+		//Remove the consumer
+		_ = ch.Cancel(message.ConsumerTag, false)
 		switch jsonMsg["status"] {
 		case "success":
-			agentId = jsonMsg["agentId"].(string)
-			log.Info.Println("Registration successful")
-			registerFinish = true
+			newAgentId, _ := jsonMsg["agentId"].(string)
+			if rejoin && newAgentId != agentId {
+				log.Warn.Printf("Controller did not accept agent ID %s\n", agentId)
+			}
+			agentId = newAgentId
+			//The controller only sends a secret if it issued a new one
+			if newSecret, _ := jsonMsg["secret"].(string); newSecret != "" {
+				secret = newSecret
+			}
+			saveSession()
+			//Containers the controller has no record of, e.g. ones moved to other agents while this agent was away
+			remove, _ := jsonMsg["remove"].([]interface{})
+			for _, containerId := range remove {
+				if containerId, ok := containerId.(string); ok {
+					removeContainer(containerId)
+				}
+			}
+			log.Info.Println(">> Registered as agent " + agentId)
+			return nil
 		case "error":
-			log.Fatal.Println("Registration rejected")
-			log.Fatal.Panicln(jsonMsg["error"].(string))
-		}
-		consumeTag = message.ConsumerTag
-		if registerFinish {
-			break
+			log.Error.Println("Registration rejected")
+			errStr, _ := jsonMsg["error"].(string)
+			if errStr == errRevoked.Error() {
+				return errRevoked
+			}
+			return errors.New(errStr)
 		}
 	}
-	//Remove the consumer
-	_ = ch.Cancel(consumeTag, false)
-
-	log.Info.Println(">> Registered as agent " + agentId)
-	return
 }
 
 //Starts all goroutines
-//In the event of panics, all containers that were still deployed have to be manually stopped.
+//The API listeners end when the connection is lost. They are started again after reconnecting
 func startRoutine(deployStream, monitorStream, pingStream <-chan amqp.Delivery) {
 	//API listener
 	//Deploy API
 	go func() {
 		for message := range deployStream {
-			jsonMsg := deserialize(message.Body)
+			jsonMsg := verified(deployQueue.Name, message)
 			//fmt.Println(string(message.Body))
-			_ = message.Ack(true)
 			if jsonMsg != nil {
 				parseDeploy(jsonMsg)
 			}
 		}
-		log.Fatal.Panicln("Deploy stream ended. This should not happen!")
-		//If the program somehow reaches here, something has gone wrong with the agent.
 	}()
 	//Monitoring API
 	go func() {
 		for message := range monitorStream {
-			jsonMsg := deserialize(message.Body)
-			_ = message.Ack(true)
+			jsonMsg := verified(monitorQueue.Name, message)
 			if jsonMsg != nil {
 				parseMonitor(jsonMsg)
 			}
 		}
-		log.Fatal.Panicln("Monitor stream ended. This should not happen!")
 	}()
 
 	//Heartbeat (ping)
@@ -224,6 +331,7 @@ func startRoutine(deployStream, monitorStream, pingStream <-chan amqp.Delivery)
 			//Multiple Ack must be false, otherwise messages to other agents will be lost
 			_ = message.Ack(false)
 			if jsonMsg != nil {
+				checkControllerBoot(jsonMsg)
 				pongTime := time.Now().UnixMilli()
 				latency := pongTime - int64(jsonMsg["ping"].(float64))
 				message, _ := json.Marshal(map[string]interface{}{
@@ -232,57 +340,34 @@ func startRoutine(deployStream, monitorStream, pingStream <-chan amqp.Delivery)
 					"seq":     int64(jsonMsg["seq"].(float64)),
 					"latency": latency,
 				})
-				_ = ch.Publish(
-					"",
-					"pong",
-					false,
-					false,
-					amqp.Publishing{
-						ContentType: "application/json",
-						Body:        message,
-					},
-				)
+				_ = publish("pong", message)
 			}
 		}
-		log.Fatal.Panicln("Ping stream ended. This should not happen!")
 	}()
 
 	//Automatically deploy monitoring applications
 	//Note: The agent will notify the controller it deployed them
 	//Since the controller did not request the containers (meaning no request ID), the agent notifies with the request ID "internal"
-	go func() {
-		log.Info.Println("Deploying monitoring services")
-		for _, deployArg := range constants.DefaultContainers {
-			log.Info.Println("Self deploying " + deployArg.Image)
-			//Start the container
-			containerId, err := docker.Run(deployArg, types.AuthInfo{})
-			if err != nil {
-				replyDeployError("internal", err)
-				panic(err)
-				return
-			}
-			//Construct response
-			response, _ := json.Marshal(map[string]string{
-				"requestId":   "internal",
-				"status":      "ok",
-				"containerId": containerId,
-				"api":         "deploy",
-			})
-			_ = ch.Publish(
-				"",
-				responseQueue.Name,
-				false,
-				false,
-				amqp.Publishing{
-					ContentType: "application/json",
-					Body:        response,
-				},
-			)
-			log.Info.Println("<< Container " + containerId + " deployed")
+	//These only start once. A rejoining agent keeps the ones that are still running
+	servicesOnce.Do(func() {
+		go deployServices()
+		//Health checking
+		//Checks if the containers are still healthy, and alert the controller when something wrong happens
+		go healthCheck()
+	})
+}
+
+//Deploys the monitoring services on the agent
+func deployServices() {
+	log.Info.Println("Deploying monitoring services")
+	for _, deployArg := range constants.DefaultContainers {
+		if isRunning(deployArg.Image) {
+			log.Info.Println(deployArg.Image + " is already running")
+			continue
 		}
-		log.Info.Println("Self deploying Prometheus")
+		log.Info.Println("Self deploying " + deployArg.Image)
 		//Start the container
-		containerId, err := docker.Run(constants.PrometheusContainer(), types.AuthInfo{})
+		containerId, err := docker.Run(deployArg, types.AuthInfo{})
 		if err != nil {
 			replyDeployError("internal", err)
 			panic(err)
@@ -295,22 +380,30 @@ func startRoutine(deployStream, monitorStream, pingStream <-chan amqp.Delivery)
 			"containerId": containerId,
 			"api":         "deploy",
 		})
-		_ = ch.Publish(
-			"",
-			responseQueue.Name,
-			false,
-			false,
-			amqp.Publishing{
-				ContentType: "application/json",
-				Body:        response,
-			},
-		)
+		_ = publish(responseQueue.Name, response)
 		log.Info.Println("<< Container " + containerId + " deployed")
-	}()
-
-	//Health checking
-	//Checks if the containers are still healthy, and alert the controller when something wrong happens
-	go healthCheck()
+	}
+	if isRunning(constants.PrometheusContainer().Image) {
+		log.Info.Println("Prometheus is already running")
+		return
+	}
+	log.Info.Println("Self deploying Prometheus")
+	//Start the container
+	containerId, err := docker.Run(constants.PrometheusContainer(), types.AuthInfo{})
+	if err != nil {
+		replyDeployError("internal", err)
+		panic(err)
+		return
+	}
+	//Construct response
+	response, _ := json.Marshal(map[string]string{
+		"requestId":   "internal",
+		"status":      "ok",
+		"containerId": containerId,
+		"api":         "deploy",
+	})
+	_ = publish(responseQueue.Name, response)
+	log.Info.Println("<< Container " + containerId + " deployed")
 }
 
 //Deserialize json to a map string interface, where we assert the type of the interface (The value of the map) to any type.
@@ -354,6 +447,19 @@ func declareQueue(queueName string, durable bool) (amqp.Queue, error) {
 	return queue, err
 }
 
+//Declares a queue with a name chosen by the server. Only this connection can read it, and it is deleted when its consumer stops
+func declareReplyQueue() (amqp.Queue, error) {
+	queue, err := ch.QueueDeclare(
+		"",
+		false,
+		true,
+		true,
+		false,
+		nil,
+	)
+	return queue, err
+}
+
 //Declares a queue that expires if no messages are received after `expireTime`
 func declareExpireQueue(queueName string, expireTime int) (amqp.Queue, error) {
 	queue, err := ch.QueueDeclare(
@@ -431,16 +537,7 @@ func replyAck(requestId, apiName string) {
 		"status":    "ack",
 		"api":       apiName,
 	})
-	err := ch.Publish(
-		"",
-		responseQueue.Name,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        ack,
-		},
-	)
+	err := publish(responseQueue.Name, ack)
 	if err != nil {
 		log.Error.Println("Failed pushing ack")
 		log.Error.Println(err)
@@ -9,10 +9,10 @@ import (
 	"osmoticframework/agent/log"
 	"osmoticframework/agent/types"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mitchellh/mapstructure"
-	"github.com/streadway/amqp"
 )
 
 //Deploy API endpoint
@@ -175,9 +175,12 @@ func healthCheck() {
 		//create = creation of the container
 		//stop = container stop
 		//start = container start
+		//restart = container restart
 		//die = container exit (or crash)
+		//oom = a process of the container was killed for running out of memory
+		//health_status = change of the result of the Docker healthcheck
 		//destroy = container removal
-		"event": {"create", "stop", "start", "die", "destroy"},
+		"event": {"create", "stop", "start", "restart", "die", "oom", "health_status", "destroy"},
 	}}
 	for {
 		//Create listener
@@ -189,51 +192,54 @@ func healthCheck() {
 			continue
 		}
 		for event := range listener {
-			switch event.Action {
+			lifecycle := containerEvent(event)
+			//The container no longer exists after destroy
+			if lifecycle.Action != "destroy" {
+				restartCount, oomKilled, err := docker.LifecycleState(event.Actor.ID)
+				if err != nil {
+					log.Warn.Printf("Failed inspecting container %s\n", event.Actor.ID)
+					log.Warn.Println(err)
+				}
+				lifecycle.RestartCount = restartCount
+				lifecycle.OOMKilled = oomKilled
+				if lifecycle.Action == "die" && oomKilled {
+					lifecycle.Status = "oom-killed"
+				}
+			}
+			switch lifecycle.Action {
 			case "create":
 				log.Info.Printf("Container %s created\n", event.Actor.ID)
 			case "stop":
 				log.Info.Printf("Container %s stopped\n", event.Actor.ID)
 			case "start":
-				log.Info.Printf("Container %s started\n", event.Actor.ID)
+				log.Info.Printf("Container %s started. Restarted %d times\n", event.Actor.ID, lifecycle.RestartCount)
+			case "restart":
+				log.Info.Printf("Container %s restarted. Restarted %d times\n", event.Actor.ID, lifecycle.RestartCount)
+			case "oom":
+				log.Warn.Printf("Container %s ran out of memory\n", event.Actor.ID)
+			case "health_status":
+				log.Info.Printf("Container %s is %s\n", event.Actor.ID, lifecycle.Health)
 			case "die":
-				exitCode, _ := strconv.ParseInt(event.Actor.Attributes["exitCode"], 10, 64)
 				crash := types.CrashReport{
 					AgentId:  agentId,
 					ID:       event.Actor.ID,
-					Name:     event.Actor.Attributes["name"],
-					Image:    event.Actor.Attributes["image"],
-					ExitCode: int(exitCode),
+					Name:     lifecycle.Name,
+					Image:    lifecycle.Image,
+					ExitCode: lifecycle.ExitCode,
+					Output:   lastOutput(event.Actor.ID),
 				}
-				if exitCode == 0 {
+				if lifecycle.ExitCode == 0 {
 					log.Info.Printf("Container %s has exited with code 0\n", event.Actor.ID)
 					crash.Status = "exited"
 				} else {
-					log.Warn.Printf("Container %s has crashed with exit code %d\n", event.Actor.ID, exitCode)
+					log.Warn.Printf("Container %s has crashed with exit code %d\n", event.Actor.ID, lifecycle.ExitCode)
 					crash.Status = "error"
 				}
-				response, _ := json.Marshal(map[string]interface{}{
-					"type":     alert.AlertContainerCrash,
-					"contents": crash,
-				})
-				//Publish message to controller
-				err = ch.Publish(
-					"",
-					alertQueue.Name,
-					false,
-					false,
-					amqp.Publishing{
-						ContentType: "application/json",
-						Body:        response,
-					},
-				)
-				if err != nil {
-					log.Error.Println("Failed pushing container alert")
-					log.Error.Println(err)
-				}
+				pushAlert(alert.AlertContainerCrash, crash)
 			case "destroy":
 				log.Info.Printf("Container %s removed\n", event.Actor.ID)
 			}
+			pushAlert(alert.AlertContainerEvent, lifecycle)
 		}
 		log.Warn.Printf("Docker has hung up. Restarting event stream")
 		//Deregister the listener and set up a new one
@@ -244,6 +250,61 @@ func healthCheck() {
 	}
 }
 
+//Converts a Docker event to the lifecycle event sent to the controller. The restart count and OOM kill are not included
+func containerEvent(event *api.APIEvents) types.ContainerEvent {
+	lifecycle := types.ContainerEvent{
+		AgentId: agentId,
+		ID:      event.Actor.ID,
+		Name:    event.Actor.Attributes["name"],
+		Image:   event.Actor.Attributes["image"],
+		Action:  event.Action,
+		Time:    event.TimeNano,
+	}
+	//Health events are reported as "health_status: healthy"
+	if strings.HasPrefix(event.Action, "health_status") {
+		lifecycle.Action = "health_status"
+		lifecycle.Health = strings.TrimSpace(strings.TrimPrefix(event.Action, "health_status:"))
+	}
+	switch lifecycle.Action {
+	case "create":
+		lifecycle.Status = "created"
+	case "start", "restart":
+		lifecycle.Status = "running"
+	case "stop":
+		lifecycle.Status = "stopped"
+	case "die":
+		exitCode, _ := strconv.ParseInt(event.Actor.Attributes["exitCode"], 10, 64)
+		lifecycle.ExitCode = int(exitCode)
+		if exitCode == 0 {
+			lifecycle.Status = "exited"
+		} else {
+			lifecycle.Status = "error"
+		}
+	case "health_status":
+		if lifecycle.Health == "healthy" {
+			lifecycle.Status = "running"
+		} else if lifecycle.Health == "unhealthy" {
+			lifecycle.Status = "unhealthy"
+		}
+	case "destroy":
+		lifecycle.Status = "removed"
+	}
+	return lifecycle
+}
+
+//Publishes an alert to the controller
+func pushAlert(alertType alert.AlertType, contents interface{}) {
+	response, _ := json.Marshal(map[string]interface{}{
+		"type":     alertType,
+		"contents": contents,
+	})
+	err := publish(alertQueue.Name, response)
+	if err != nil {
+		log.Error.Println("Failed pushing container alert")
+		log.Error.Println(err)
+	}
+}
+
 //Processes deploy commands only
 func parseDeploy(jsonMsg map[string]interface{}) {
 	requestId, ok := jsonMsg["requestId"].(string)
@@ -275,19 +336,16 @@ func parseDeploy(jsonMsg map[string]interface{}) {
 			response = ListEP(requestId)
 		case "inspect":
 			response = InspectEP(requestId, args)
+		case "logs":
+			response = LogsEP(requestId, args)
+		case "unfollow":
+			response = UnfollowEP(requestId, args)
+		case "exec":
+			response = ExecEP(requestId, args)
 		default:
 			response = replyDeployError(requestId, errors.New("unknown command"))
 		}
-		err := ch.Publish(
-			"",
-			responseQueue.Name,
-			false,
-			false,
-			amqp.Publishing{
-				ContentType: "application/json",
-				Body:        response,
-			},
-		)
+		err := publish(responseQueue.Name, response)
 		if err != nil {
 			log.Error.Println("Failed pushing response")
 			log.Error.Println(err)
@@ -0,0 +1,41 @@
+package api
+
+import (
+	api "github.com/fsouza/go-dockerclient"
+	"testing"
+)
+
+func TestContainerEvent(t *testing.T) {
+	tests := []struct {
+		Action   string
+		ExitCode string
+		Want     string
+		Status   string
+		Health   string
+	}{
+		{"create", "", "create", "created", ""},
+		{"start", "", "start", "running", ""},
+		{"die", "0", "die", "exited", ""},
+		{"die", "137", "die", "error", ""},
+		{"oom", "", "oom", "", ""},
+		{"health_status: unhealthy", "", "health_status", "unhealthy", "unhealthy"},
+		{"health_status: healthy", "", "health_status", "running", "healthy"},
+		{"health_status: starting", "", "health_status", "", "starting"},
+		{"destroy", "", "destroy", "removed", ""},
+	}
+	for _, test := range tests {
+		event := containerEvent(&api.APIEvents{
+			Action: test.Action,
+			Actor: api.APIActor{
+				ID:         "container",
+				Attributes: map[string]string{"name": "fl-client", "image": "fl:latest", "exitCode": test.ExitCode},
+			},
+		})
+		if event.Action != test.Want || event.Status != test.Status || event.Health != test.Health {
+			t.Errorf("Event %q incorrect. Got %s %q %q, Want %s %q %q", test.Action, event.Action, event.Status, event.Health, test.Want, test.Status, test.Health)
+		}
+		if event.ID != "container" || event.Name != "fl-client" || event.Image != "fl:latest" {
+			t.Errorf("Event %q container incorrect. Got %s %s %s", test.Action, event.ID, event.Name, event.Image)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/streadway/amqp"
+	"io/ioutil"
+	"osmoticframework/agent/constants"
+	"strings"
+)
+
+//Dials RabbitMQ. amqps:// addresses use TLS with the certificates in the credential directory
+func dial() (*amqp.Connection, error) {
+	if strings.HasPrefix(constants.GetRabbitAddress(), "amqps://") {
+		return dialTls()
+	}
+	return amqp.Dial(constants.GetRabbitAddress())
+}
+
+//Connects with TLS. The server is verified with the CA certificate, and the agent authenticates with its client certificate
+func dialTls() (*amqp.Connection, error) {
+	tlsConf, err := tlsConfig(constants.GetCACertificate(), constants.GetClientCertificate(), constants.GetClientKey())
+	if err != nil {
+		return nil, err
+	}
+	return amqp.DialTLS(constants.GetRabbitAddress(), tlsConf)
+}
+
+func tlsConfig(caCert, clientCert, clientKey string) (*tls.Config, error) {
+	tlsConf := new(tls.Config)
+	//RootCA
+	tlsConf.RootCAs = x509.NewCertPool()
+	ca, err := ioutil.ReadFile(caCert)
+	if err != nil {
+		return nil, errors.New("failed to load CA certificate: " + err.Error())
+	}
+	if !tlsConf.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates found in " + caCert)
+	}
+	//Load key pair
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		return nil, errors.New("failed to load client certificate: " + err.Error())
+	}
+	tlsConf.Certificates = append(tlsConf.Certificates, cert)
+	return tlsConf, nil
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTlsConfig(t *testing.T) {
+	dir := t.TempDir()
+	//Self signed certificate. It is used as both the CA and the client certificate
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "agent"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "certificate.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	_ = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	tlsConf, err := tlsConfig(certFile, certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed loading credentials. %v", err)
+	}
+	if len(tlsConf.Certificates) != 1 {
+		t.Errorf("Client certificates incorrect. Got %d, Want %d", len(tlsConf.Certificates), 1)
+	}
+	//Missing files
+	if _, err := tlsConfig(filepath.Join(dir, "missing.pem"), certFile, keyFile); err == nil {
+		t.Errorf("Missing CA certificate accepted")
+	}
+	if _, err := tlsConfig(certFile, certFile, filepath.Join(dir, "missing.pem")); err == nil {
+		t.Errorf("Missing client key accepted")
+	}
+	//A key is not a certificate
+	if _, err := tlsConfig(keyFile, certFile, keyFile); err == nil {
+		t.Errorf("Invalid CA certificate accepted")
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"osmoticframework/agent/constants"
+	"osmoticframework/agent/docker"
+	"osmoticframework/agent/log"
+	"strings"
+	"time"
+)
+
+/*
+	Remote exec
+	The "exec" deploy command runs a command inside a container deployed by the controller, and replies with the exit code and the output.
+	It must be enabled with "enable_exec" in the properties file. Core containers, such as the agent itself and Prometheus, are refused.
+	Each output stream is cut at "exec_max_output" bytes. Commands that do not finish in time are abandoned, but keep running in the container, as Docker cannot kill them.
+*/
+
+const (
+	defaultExecTimeout = 30 * time.Second
+	maxExecTimeout     = 5 * time.Minute
+)
+
+//Runs a command inside a container
+func ExecEP(requestId string, args map[string]interface{}) []byte {
+	if !constants.IsExecEnable() {
+		return replyDeployError(requestId, errors.New("exec is disabled on this agent"))
+	}
+	containerId, command, timeout, err := parseExecArgs(args)
+	if err != nil {
+		return replyDeployError(requestId, err)
+	}
+	container, err := docker.Inspect(containerId)
+	if err != nil {
+		return replyDeployError(requestId, err)
+	}
+	if isCoreImage(container.Image) {
+		return replyDeployError(requestId, errors.New("exec is not allowed in core containers"))
+	}
+	log.Info.Printf("Running %q in container %s\n", strings.Join(command, " "), containerId)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	stdout := &cappedBuffer{limit: constants.GetExecMaxOutput()}
+	stderr := &cappedBuffer{limit: constants.GetExecMaxOutput()}
+	exitCode, err := docker.Exec(ctx, containerId, command, stdout, stderr)
+	if ctx.Err() == context.DeadlineExceeded {
+		return replyDeployError(requestId, fmt.Errorf("command did not finish within %s. It may still be running", timeout))
+	}
+	if err != nil {
+		return replyDeployError(requestId, err)
+	}
+	log.Info.Printf("<< Command in container %s exited with code %d\n", containerId, exitCode)
+	response, _ := json.Marshal(map[string]interface{}{
+		"requestId": requestId,
+		"status":    "ok",
+		"api":       "deploy",
+		"result": map[string]interface{}{
+			"exitCode":  exitCode,
+			"stdout":    string(stdout.data),
+			"stderr":    string(stderr.data),
+			"truncated": stdout.truncated || stderr.truncated,
+		},
+	})
+	return response
+}
+
+//Exec has the arguments "containerId", "command" (an array of the program and its arguments) and optionally "timeout" in seconds
+func parseExecArgs(args map[string]interface{}) (string, []string, time.Duration, error) {
+	containerId, ok := args["containerId"].(string)
+	if !ok || containerId == "" {
+		return "", nil, 0, errors.New("cannot parse arguments")
+	}
+	commandRaw, ok := args["command"].([]interface{})
+	if !ok || len(commandRaw) == 0 {
+		return "", nil, 0, errors.New("command is required")
+	}
+	command := make([]string, 0, len(commandRaw))
+	for _, argRaw := range commandRaw {
+		arg, ok := argRaw.(string)
+		if !ok {
+			return "", nil, 0, errors.New("cannot parse arguments")
+		}
+		command = append(command, arg)
+	}
+	timeout := defaultExecTimeout
+	if timeoutRaw, ok := args["timeout"]; ok {
+		seconds, ok := timeoutRaw.(float64)
+		if !ok || seconds <= 0 {
+			return "", nil, 0, errors.New("invalid timeout")
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+		if timeout > maxExecTimeout {
+			timeout = maxExecTimeout
+		}
+	}
+	return containerId, command, timeout, nil
+}
+
+//Keeps the first bytes written to it, up to the limit
+type cappedBuffer struct {
+	limit     int
+	data      []byte
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - len(b.data)
+	if len(p) > room {
+		b.data = append(b.data, p[:room]...)
+		b.truncated = true
+	} else {
+		b.data = append(b.data, p...)
+	}
+	//Report everything as written, so that the rest of the output is discarded instead of failing the stream
+	return len(p), nil
+}
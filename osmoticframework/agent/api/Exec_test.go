@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExecArgs(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Args    map[string]interface{}
+		Valid   bool
+		Timeout time.Duration
+	}{
+		{"defaults", map[string]interface{}{"containerId": "abc", "command": []interface{}{"ls", "-l"}}, true, defaultExecTimeout},
+		{"timeout", map[string]interface{}{"containerId": "abc", "command": []interface{}{"ls"}, "timeout": float64(5)}, true, 5 * time.Second},
+		{"timeout above limit", map[string]interface{}{"containerId": "abc", "command": []interface{}{"ls"}, "timeout": float64(3600)}, true, maxExecTimeout},
+		{"no command", map[string]interface{}{"containerId": "abc", "command": []interface{}{}}, false, 0},
+		{"command string", map[string]interface{}{"containerId": "abc", "command": "ls -l"}, false, 0},
+		{"no container", map[string]interface{}{"command": []interface{}{"ls"}}, false, 0},
+		{"zero timeout", map[string]interface{}{"containerId": "abc", "command": []interface{}{"ls"}, "timeout": float64(0)}, false, 0},
+	}
+	for _, test := range tests {
+		_, _, timeout, err := parseExecArgs(test.Args)
+		if (err == nil) != test.Valid {
+			t.Errorf("%s: Validation incorrect. Got %v, Want valid: %t", test.Name, err, test.Valid)
+			continue
+		}
+		if err == nil && timeout != test.Timeout {
+			t.Errorf("%s: Timeout incorrect. Got %s, Want %s", test.Name, timeout, test.Timeout)
+		}
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	buffer := &cappedBuffer{limit: 8}
+	for _, chunk := range []string{"hello", " world", "!"} {
+		n, err := buffer.Write([]byte(chunk))
+		if n != len(chunk) || err != nil {
+			t.Errorf("Write %q incorrect. Got %d %v, Want %d <nil>", chunk, n, err, len(chunk))
+		}
+	}
+	if string(buffer.data) != "hello wo" || !buffer.truncated {
+		t.Errorf("Buffer incorrect. Got %q truncated %t, Want %q truncated true", buffer.data, buffer.truncated, "hello wo")
+	}
+}
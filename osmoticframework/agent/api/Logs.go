@@ -0,0 +1,336 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"osmoticframework/agent/docker"
+	"osmoticframework/agent/log"
+	"osmoticframework/agent/types"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Container logs
+	The "logs" deploy command replies with the last lines of output of a container, optionally only the lines since a time.
+	In follow mode the agent replies right away, then pushes new lines to the queue "logs-<streamId>" in batches. The controller declares the queue before sending the request.
+	A stream ends when the container stops, the controller sends "unfollow" or the controller restarts. The agent then sends a message with the status "ended".
+*/
+
+const (
+	defaultLogTail = 100
+	maxLogTail     = 10000
+	//Size limit of the output in a reply or a batch. The oldest lines are dropped first
+	maxLogBytes = 1 << 20
+	//Limits the number of Docker connections the controller can hold open
+	maxLogStreams = 8
+	//Lines of a stream are batched, so that chatty containers do not flood the queue
+	logFlushInterval = time.Second
+	//Time limit of reading the output without following
+	logReadTimeout = 30 * time.Second
+	//Lines of output attached to crash reports
+	crashOutputLines = 20
+)
+
+//Stream ID -> *logStream
+var logStreams sync.Map
+
+type logStream struct {
+	id     string
+	cancel context.CancelFunc
+}
+
+type logsArgs struct {
+	containerId string
+	tail        int
+	since       time.Time
+	follow      bool
+	streamId    string
+}
+
+//Reads the output of a container, or starts following it
+func LogsEP(requestId string, args map[string]interface{}) []byte {
+	logArgs, err := parseLogsArgs(args)
+	if err != nil {
+		return replyDeployError(requestId, err)
+	}
+	if logArgs.follow {
+		return followLogs(requestId, logArgs)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), logReadTimeout)
+	defer cancel()
+	lines, err := readLogs(ctx, logArgs.containerId, logArgs.tail, logArgs.since)
+	if err != nil {
+		return replyDeployError(requestId, err)
+	}
+	log.Info.Printf("<< Sending %d lines of output of container %s\n", len(lines), logArgs.containerId)
+	return replyLogs(requestId, lines)
+}
+
+//Stops following the output of a container
+func UnfollowEP(requestId string, args map[string]interface{}) []byte {
+	streamId, ok := args["streamId"].(string)
+	if !ok {
+		return replyDeployError(requestId, errors.New("cannot parse arguments"))
+	}
+	stream, ok := logStreams.LoadAndDelete(streamId)
+	if !ok {
+		return replyDeployError(requestId, errors.New("log stream not found"))
+	}
+	stream.(*logStream).cancel()
+	log.Info.Printf("Log stream %s cancelled\n", streamId)
+	return replyDeployOk(requestId)
+}
+
+func followLogs(requestId string, logArgs logsArgs) []byte {
+	count := 0
+	logStreams.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	if count >= maxLogStreams {
+		return replyDeployError(requestId, fmt.Errorf("agent already has %d log streams", maxLogStreams))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &logStream{id: logArgs.streamId, cancel: cancel}
+	if _, exists := logStreams.LoadOrStore(stream.id, stream); exists {
+		cancel()
+		return replyDeployError(requestId, errors.New("log stream already exists"))
+	}
+	log.Info.Printf("Log stream %s of container %s started\n", stream.id, logArgs.containerId)
+	go runLogStream(ctx, stream, logArgs)
+	return replyLogs(requestId, []types.LogLine{})
+}
+
+//Pushes the output to the queue of the stream until the container stops or the stream is cancelled
+func runLogStream(ctx context.Context, stream *logStream, logArgs logsArgs) {
+	defer stream.cancel()
+	collector := &logCollector{}
+	stdout, stderr := collector.writer("stdout"), collector.writer("stderr")
+	done := make(chan error, 1)
+	go func() {
+		err := docker.Logs(ctx, logArgs.containerId, logArgs.tail, logArgs.since, true, stdout, stderr)
+		stdout.flush()
+		stderr.flush()
+		done <- err
+	}()
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pushLogs(stream.id, collector.take())
+		case err := <-done:
+			pushLogs(stream.id, collector.take())
+			logStreams.Delete(stream.id)
+			log.Info.Printf("Log stream %s ended\n", stream.id)
+			ended := map[string]interface{}{
+				"streamId": stream.id,
+				"status":   "ended",
+			}
+			if err != nil && ctx.Err() == nil {
+				ended["error"] = err.Error()
+			}
+			message, _ := json.Marshal(ended)
+			err = publish(logQueue(stream.id), message)
+			if err != nil {
+				log.Error.Println("Failed notifying the end of log stream " + stream.id)
+				log.Error.Println(err)
+			}
+			return
+		}
+	}
+}
+
+func pushLogs(streamId string, lines []types.LogLine) {
+	if len(lines) == 0 {
+		return
+	}
+	message, _ := json.Marshal(map[string]interface{}{
+		"streamId": streamId,
+		"status":   "ok",
+		"lines":    lines,
+	})
+	err := publish(logQueue(streamId), message)
+	if err != nil {
+		log.Error.Println("Failed pushing output of log stream " + streamId)
+		log.Error.Println(err)
+	}
+}
+
+//Cancels all log streams. Used when the controller restarts
+func stopLogStreams() {
+	logStreams.Range(func(streamId, stream interface{}) bool {
+		logStreams.Delete(streamId)
+		stream.(*logStream).cancel()
+		return true
+	})
+}
+
+func readLogs(ctx context.Context, containerId string, tail int, since time.Time) ([]types.LogLine, error) {
+	collector := &logCollector{}
+	stdout, stderr := collector.writer("stdout"), collector.writer("stderr")
+	err := docker.Logs(ctx, containerId, tail, since, false, stdout, stderr)
+	if err != nil {
+		return nil, err
+	}
+	stdout.flush()
+	stderr.flush()
+	return collector.take(), nil
+}
+
+//The last lines of output of a container, without timestamps. Empty if they cannot be read
+func lastOutput(containerId string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lines, err := readLogs(ctx, containerId, crashOutputLines, time.Time{})
+	if err != nil {
+		log.Warn.Printf("Failed reading output of container %s\n", containerId)
+		log.Warn.Println(err)
+		return nil
+	}
+	output := make([]string, 0, len(lines))
+	for _, line := range lines {
+		output = append(output, line.Text)
+	}
+	return output
+}
+
+func replyLogs(requestId string, lines []types.LogLine) []byte {
+	response, _ := json.Marshal(map[string]interface{}{
+		"requestId": requestId,
+		"status":    "ok",
+		"api":       "deploy",
+		"lines":     lines,
+	})
+	return response
+}
+
+func logQueue(streamId string) string {
+	return "logs-" + streamId
+}
+
+//Logs have the arguments "containerId" and optionally "tail", "since" (UNIX seconds), "follow" and "streamId"
+//The stream ID is required in follow mode
+func parseLogsArgs(args map[string]interface{}) (logsArgs, error) {
+	logArgs := logsArgs{tail: defaultLogTail}
+	containerId, ok := args["containerId"].(string)
+	if !ok || containerId == "" {
+		return logArgs, errors.New("cannot parse arguments")
+	}
+	logArgs.containerId = containerId
+	if tailRaw, ok := args["tail"]; ok {
+		tail, ok := tailRaw.(float64)
+		if !ok || tail < 1 {
+			return logArgs, errors.New("invalid tail")
+		}
+		logArgs.tail = int(tail)
+		if logArgs.tail > maxLogTail {
+			logArgs.tail = maxLogTail
+		}
+	}
+	if sinceRaw, ok := args["since"]; ok {
+		since, ok := sinceRaw.(float64)
+		if !ok || since < 0 {
+			return logArgs, errors.New("invalid since")
+		}
+		logArgs.since = time.Unix(int64(since), 0)
+	}
+	logArgs.follow, _ = args["follow"].(bool)
+	if logArgs.follow {
+		logArgs.streamId, _ = args["streamId"].(string)
+		if logArgs.streamId == "" {
+			return logArgs, errors.New("follow requires a stream ID")
+		}
+	}
+	return logArgs, nil
+}
+
+//Collects the output of a container as lines. Writers of both streams can be used while the lines are taken
+type logCollector struct {
+	lock  sync.Mutex
+	lines []types.LogLine
+	size  int
+}
+
+func (c *logCollector) writer(stream string) *lineWriter {
+	return &lineWriter{collector: c, stream: stream}
+}
+
+//Adds a line. The oldest lines are dropped if the output is above the size limit
+func (c *logCollector) add(line types.LogLine) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lines = append(c.lines, line)
+	c.size += len(line.Text)
+	for c.size > maxLogBytes && len(c.lines) > 1 {
+		c.size -= len(c.lines[0].Text)
+		c.lines = c.lines[1:]
+	}
+}
+
+//Returns the collected lines and empties the collector
+func (c *logCollector) take() []types.LogLine {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	lines := c.lines
+	if lines == nil {
+		lines = []types.LogLine{}
+	}
+	c.lines = nil
+	c.size = 0
+	return lines
+}
+
+//Splits the output of a stream into lines
+type lineWriter struct {
+	collector *logCollector
+	stream    string
+	partial   []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		end := bytes.IndexByte(w.partial, '\n')
+		if end < 0 {
+			break
+		}
+		w.collector.add(parseLogLine(w.stream, string(w.partial[:end])))
+		w.partial = w.partial[end+1:]
+	}
+	//A line without an end is cut, so that it does not grow without limit
+	if len(w.partial) > maxLogBytes {
+		w.flush()
+	}
+	return len(p), nil
+}
+
+//Adds the last line if it did not end with a newline
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.collector.add(parseLogLine(w.stream, string(w.partial)))
+		w.partial = nil
+	}
+}
+
+//Docker starts each line with its timestamp, e.g. "2021-03-04T05:06:07.123456789Z hello"
+func parseLogLine(stream, raw string) types.LogLine {
+	raw = strings.TrimSuffix(raw, "\r")
+	line := types.LogLine{Stream: stream, Text: raw}
+	parts := strings.SplitN(raw, " ", 2)
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return line
+	}
+	line.Time = timestamp
+	line.Text = ""
+	if len(parts) == 2 {
+		line.Text = parts[1]
+	}
+	return line
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogsArgs(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Args  map[string]interface{}
+		Valid bool
+		Tail  int
+	}{
+		{"defaults", map[string]interface{}{"containerId": "abc"}, true, defaultLogTail},
+		{"tail and since", map[string]interface{}{"containerId": "abc", "tail": float64(20), "since": float64(1000)}, true, 20},
+		{"tail above limit", map[string]interface{}{"containerId": "abc", "tail": float64(1e6)}, true, maxLogTail},
+		{"follow", map[string]interface{}{"containerId": "abc", "follow": true, "streamId": "stream"}, true, defaultLogTail},
+		{"follow without stream", map[string]interface{}{"containerId": "abc", "follow": true}, false, 0},
+		{"no container", map[string]interface{}{"tail": float64(20)}, false, 0},
+		{"zero tail", map[string]interface{}{"containerId": "abc", "tail": float64(0)}, false, 0},
+	}
+	for _, test := range tests {
+		logArgs, err := parseLogsArgs(test.Args)
+		if (err == nil) != test.Valid {
+			t.Errorf("%s: Validation incorrect. Got %v, Want valid: %t", test.Name, err, test.Valid)
+			continue
+		}
+		if err == nil && logArgs.tail != test.Tail {
+			t.Errorf("%s: Tail incorrect. Got %d, Want %d", test.Name, logArgs.tail, test.Tail)
+		}
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	collector := &logCollector{}
+	stdout, stderr := collector.writer("stdout"), collector.writer("stderr")
+	_, _ = stdout.Write([]byte("2021-03-04T05:06:07.5Z starting\n2021-03-04T05:06:08Z round"))
+	_, _ = stderr.Write([]byte("no timestamp\r\n"))
+	_, _ = stdout.Write([]byte(" 1\n2021-03-04T05:06:09Z unfinished"))
+	stdout.flush()
+	want := []struct {
+		Stream string
+		Text   string
+		Time   time.Time
+	}{
+		{"stdout", "starting", time.Date(2021, 3, 4, 5, 6, 7, 5e8, time.UTC)},
+		{"stderr", "no timestamp", time.Time{}},
+		{"stdout", "round 1", time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC)},
+		{"stdout", "unfinished", time.Date(2021, 3, 4, 5, 6, 9, 0, time.UTC)},
+	}
+	lines := collector.take()
+	if len(lines) != len(want) {
+		t.Fatalf("Number of lines incorrect. Got %d, Want %d", len(lines), len(want))
+	}
+	for i, line := range lines {
+		if line.Stream != want[i].Stream || line.Text != want[i].Text || !line.Time.Equal(want[i].Time) {
+			t.Errorf("Line %d incorrect. Got %+v, Want %+v", i, line, want[i])
+		}
+	}
+	if len(collector.take()) != 0 {
+		t.Errorf("Collector not emptied")
+	}
+}
@@ -3,8 +3,8 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"github.com/streadway/amqp"
 	monitor2 "osmoticframework/agent/api/monitor"
+	"osmoticframework/agent/constants"
 	"osmoticframework/agent/log"
 	"time"
 )
@@ -14,11 +14,11 @@ import (
 
 //Host CPU usage per core from the last 10 seconds (Separated by core ID)
 func CPUEdgeAvgEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.CPUEdgeAvg(*timestamp)
+	result, err := monitor2.CPUEdgeAvg(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -26,11 +26,11 @@ func CPUEdgeAvgEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func CPUContainerAvgEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.CPUContainerAvg(*containerId, *timestamp)
+	result, err := monitor2.CPUContainerAvg(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -38,11 +38,11 @@ func CPUContainerAvgEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func CPUTimeEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.CPUTimeTotal(*timestamp)
+	result, err := monitor2.CPUTimeTotal(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -50,11 +50,11 @@ func CPUTimeEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func CPUUtilizeEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.CPUUtilization(*timestamp)
+	result, err := monitor2.CPUUtilization(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -62,11 +62,11 @@ func CPUUtilizeEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func MemoryContainerEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.MemoryContainer(*containerId, *timestamp)
+	result, err := monitor2.MemoryContainer(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -74,11 +74,23 @@ func MemoryContainerEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func MemoryEdgeEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.MemoryEdge(*timestamp)
+	result, err := monitor2.MemoryEdge(*window)
+	if err != nil {
+		return replyMonitorError(requestId, err)
+	}
+	return replyMonitorResponse(requestId, *result)
+}
+
+func MemoryEdgeTotalEP(requestId string, args map[string]interface{}) []byte {
+	window, err := parseMonitorEdgeArgs(args)
+	if err != nil {
+		return replyMonitorError(requestId, err)
+	}
+	result, err := monitor2.MemoryEdgeTotal(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -86,11 +98,11 @@ func MemoryEdgeEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func MemoryContainerPeakEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.MemoryContainerPeak(*containerId, *timestamp)
+	result, err := monitor2.MemoryContainerPeak(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -98,11 +110,11 @@ func MemoryContainerPeakEP(requestId string, args map[string]interface{}) []byte
 }
 
 func MemoryEdgePeakEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.MemoryEdgePeak(*timestamp)
+	result, err := monitor2.MemoryEdgePeak(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -110,11 +122,11 @@ func MemoryEdgePeakEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func MemoryContainerLimitSecondsEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.MemoryContainerReachLimitSeconds(*containerId, *timestamp)
+	result, err := monitor2.MemoryContainerReachLimitSeconds(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -122,11 +134,11 @@ func MemoryContainerLimitSecondsEP(requestId string, args map[string]interface{}
 }
 
 func IOEdgeTimeEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.IOEdgeTime(*timestamp)
+	result, err := monitor2.IOEdgeTime(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -134,11 +146,11 @@ func IOEdgeTimeEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func IOContainerTimeEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.IOContainerTime(*containerId, *timestamp)
+	result, err := monitor2.IOContainerTime(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -146,11 +158,11 @@ func IOContainerTimeEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func IOEdgeReadEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.IOReadEdgeBytes(*timestamp)
+	result, err := monitor2.IOReadEdgeBytes(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -158,11 +170,11 @@ func IOEdgeReadEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func IOContainerReadEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.IOReadContainerBytes(*containerId, *timestamp)
+	result, err := monitor2.IOReadContainerBytes(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -170,11 +182,11 @@ func IOContainerReadEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func IOEdgeWriteEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.IOWriteEdgeBytes(*timestamp)
+	result, err := monitor2.IOWriteEdgeBytes(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -182,11 +194,11 @@ func IOEdgeWriteEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func IOContainerWriteEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.IOWriteContainerBytes(*containerId, *timestamp)
+	result, err := monitor2.IOWriteContainerBytes(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -194,11 +206,11 @@ func IOContainerWriteEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func IOFilesystemUsedEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.IOFilesystemUsedBytes(*timestamp)
+	result, err := monitor2.IOFilesystemUsedBytes(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -206,11 +218,11 @@ func IOFilesystemUsedEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func IOFilesystemSizeEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.IOFilesystemSizeBytes(*timestamp)
+	result, err := monitor2.IOFilesystemSizeBytes(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -218,11 +230,11 @@ func IOFilesystemSizeEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetEdgeRxBytesEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkEdgeRxBytes(*timestamp)
+	result, err := monitor2.NetworkEdgeRxBytes(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -230,11 +242,11 @@ func NetEdgeRxBytesEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetEdgeRxPacketsEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkEdgeRxPackets(*timestamp)
+	result, err := monitor2.NetworkEdgeRxPackets(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -242,11 +254,11 @@ func NetEdgeRxPacketsEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetEdgeRxDroppedEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkEdgePacketRxDropped(*timestamp)
+	result, err := monitor2.NetworkEdgePacketRxDropped(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -254,11 +266,11 @@ func NetEdgeRxDroppedEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetEdgeRxErrorEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkEdgeRxError(*timestamp)
+	result, err := monitor2.NetworkEdgeRxError(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -266,11 +278,11 @@ func NetEdgeRxErrorEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetEdgeTxBytesEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkEdgeTxBytes(*timestamp)
+	result, err := monitor2.NetworkEdgeTxBytes(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -278,11 +290,11 @@ func NetEdgeTxBytesEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetEdgeTxPacketsEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkEdgeTxPackets(*timestamp)
+	result, err := monitor2.NetworkEdgeTxPackets(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -290,11 +302,11 @@ func NetEdgeTxPacketsEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetEdgeTxDroppedEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkEdgePacketTxDropped(*timestamp)
+	result, err := monitor2.NetworkEdgePacketTxDropped(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -302,11 +314,11 @@ func NetEdgeTxDroppedEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetEdgeTxErrorEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkEdgeTxError(*timestamp)
+	result, err := monitor2.NetworkEdgeTxError(*window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -314,11 +326,11 @@ func NetEdgeTxErrorEP(requestId string, args map[string]interface{}) []byte {
 }
 
 func NetContainerRxBytesEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkContainerRxBytes(*containerId, *timestamp)
+	result, err := monitor2.NetworkContainerRxBytes(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -326,11 +338,11 @@ func NetContainerRxBytesEP(requestId string, args map[string]interface{}) []byte
 }
 
 func NetContainerRxPacketsEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkContainerRxPackets(*containerId, *timestamp)
+	result, err := monitor2.NetworkContainerRxPackets(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -338,11 +350,11 @@ func NetContainerRxPacketsEP(requestId string, args map[string]interface{}) []by
 }
 
 func NetContainerRxDroppedEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkContainerPacketRxDropped(*containerId, *timestamp)
+	result, err := monitor2.NetworkContainerPacketRxDropped(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -350,11 +362,11 @@ func NetContainerRxDroppedEP(requestId string, args map[string]interface{}) []by
 }
 
 func NetContainerRxErrorEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkContainerRxError(*containerId, *timestamp)
+	result, err := monitor2.NetworkContainerRxError(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -362,11 +374,11 @@ func NetContainerRxErrorEP(requestId string, args map[string]interface{}) []byte
 }
 
 func NetContainerTxBytesEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkContainerTxBytes(*containerId, *timestamp)
+	result, err := monitor2.NetworkContainerTxBytes(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -374,11 +386,11 @@ func NetContainerTxBytesEP(requestId string, args map[string]interface{}) []byte
 }
 
 func NetContainerTxPacketsEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkContainerTxPackets(*containerId, *timestamp)
+	result, err := monitor2.NetworkContainerTxPackets(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -386,11 +398,11 @@ func NetContainerTxPacketsEP(requestId string, args map[string]interface{}) []by
 }
 
 func NetContainerTxDroppedEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkContainerPacketTxDropped(*containerId, *timestamp)
+	result, err := monitor2.NetworkContainerPacketTxDropped(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -398,11 +410,11 @@ func NetContainerTxDroppedEP(requestId string, args map[string]interface{}) []by
 }
 
 func NetContainerTxErrorEP(requestId string, args map[string]interface{}) []byte {
-	containerId, timestamp, err := parseMonitorContainerArgs(args)
+	containerId, window, err := parseMonitorContainerArgs(args)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.NetworkContainerTxError(*containerId, *timestamp)
+	result, err := monitor2.NetworkContainerTxError(*containerId, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -410,11 +422,35 @@ func NetContainerTxErrorEP(requestId string, args map[string]interface{}) []byte
 }
 
 func ThermalsEP(requestId string, args map[string]interface{}) []byte {
-	timestamp, err := parseMonitorEdgeArgs(args)
+	window, err := parseMonitorEdgeArgs(args)
+	if err != nil {
+		return replyMonitorError(requestId, err)
+	}
+	result, err := monitor2.Thermals(*window)
+	if err != nil {
+		return replyMonitorError(requestId, err)
+	}
+	return replyMonitorResponse(requestId, *result)
+}
+
+//Runs a PromQL query supplied by the controller. Only if enabled on the agent, and only on allowed metrics
+func PromQLEP(requestId string, args map[string]interface{}) []byte {
+	if !constants.IsPromQLEnable() {
+		return replyMonitorError(requestId, errors.New("promql queries are disabled on this agent"))
+	}
+	query, ok := args["query"].(string)
+	if !ok {
+		return replyMonitorError(requestId, errors.New("monitor - cannot parse request arguments"))
+	}
+	window, err := parseMonitorEdgeArgs(args)
+	if err != nil {
+		return replyMonitorError(requestId, err)
+	}
+	err = monitor2.CheckQuery(query, constants.GetPromQLAllowedMetrics())
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
-	result, err := monitor2.Thermals(*timestamp)
+	result, err := monitor2.PromQL(query, *window)
 	if err != nil {
 		return replyMonitorError(requestId, err)
 	}
@@ -438,109 +474,21 @@ func parseMonitor(jsonMsg map[string]interface{}) {
 	//Also the RabbitMQ server will disconnect the agent if the client does not process the message on time
 	go func() {
 		var response []byte
-		switch jsonMsg["command"] {
-		//CPU average usage per core over the last 10 seconds
-		case "cpu_edge_avg":
-			response = CPUEdgeAvgEP(requestId, args)
-		case "cpu_container_avg":
-			response = CPUContainerAvgEP(requestId, args)
-		//CPU time by core
-		case "cpu_time":
-			response = CPUTimeEP(requestId, args)
-		//CPU overall utilization over time
-		case "cpu_utilization":
-			response = CPUUtilizeEP(requestId, args)
-		//Memory usage in bytes
-		case "memory_container":
-			response = MemoryContainerEP(requestId, args)
-		case "memory_edge":
-			response = MemoryEdgeEP(requestId, args)
-		//Maximum memory usage in bytes
-		case "memory_container_peak":
-			response = MemoryContainerPeakEP(requestId, args)
-		case "memory_edge_peak":
-			response = MemoryEdgePeakEP(requestId, args)
-		//Seconds of the container reaching its memory soft limits
-		case "memory_container_limit_seconds":
-			response = MemoryContainerLimitSecondsEP(requestId, args)
-		//Time spent in io
-		case "io_edge_time":
-			response = IOEdgeTimeEP(requestId, args)
-		case "io_container_time":
-			response = IOContainerTimeEP(requestId, args)
-		//Bytes read in io
-		case "io_edge_read":
-			response = IOEdgeReadEP(requestId, args)
-		case "io_container_read":
-			response = IOContainerReadEP(requestId, args)
-		//Bytes written in io
-		case "io_edge_write":
-			response = IOEdgeWriteEP(requestId, args)
-		case "io_container_write":
-			response = IOContainerWriteEP(requestId, args)
-		//Filesystem usage in bytes
-		case "io_filesystem_used":
-			response = IOFilesystemUsedEP(requestId, args)
-		//Total filesystem size in bytes
-		case "io_filesystem_size":
-			response = IOFilesystemSizeEP(requestId, args)
-		//Edge side network queries
-		//Received bytes
-		case "net_edge_rx_bytes":
-			response = NetEdgeRxBytesEP(requestId, args)
-		//Received packets
-		case "net_edge_rx_packets":
-			response = NetEdgeRxPacketsEP(requestId, args)
-		//Received packets dropped
-		case "net_edge_rx_dropped":
-			response = NetEdgeRxDroppedEP(requestId, args)
-		//Received errors
-		case "net_edge_rx_error":
-			response = NetEdgeRxErrorEP(requestId, args)
-		//Transmitted bytes
-		case "net_edge_tx_bytes":
-			response = NetEdgeTxBytesEP(requestId, args)
-		//Transmitted packets
-		case "net_edge_tx_packets":
-			response = NetEdgeTxPacketsEP(requestId, args)
-		//Transmitted packets dropped
-		case "net_edge_tx_dropped":
-			response = NetEdgeTxDroppedEP(requestId, args)
-		//Transmission errors
-		case "net_edge_tx_error":
-			response = NetEdgeTxErrorEP(requestId, args)
-		//Container side network queries. Same functionality as the edge side.
-		case "net_container_rx_bytes":
-			response = NetContainerRxBytesEP(requestId, args)
-		case "net_container_rx_packets":
-			response = NetContainerRxPacketsEP(requestId, args)
-		case "net_container_rx_dropped":
-			response = NetContainerRxDroppedEP(requestId, args)
-		case "net_container_rx_error":
-			response = NetContainerRxErrorEP(requestId, args)
-		case "net_container_tx_bytes":
-			response = NetContainerTxBytesEP(requestId, args)
-		case "net_container_tx_packets":
-			response = NetContainerTxPacketsEP(requestId, args)
-		case "net_container_tx_dropped":
-			response = NetContainerTxDroppedEP(requestId, args)
-		case "net_container_tx_error":
-			response = NetContainerTxErrorEP(requestId, args)
-		case "thermal":
-			response = ThermalsEP(requestId, args)
+		command, _ := jsonMsg["command"].(string)
+		switch command {
+		case "subscribe":
+			response = SubscribeEP(requestId, args)
+		case "unsubscribe":
+			response = UnsubscribeEP(requestId, args)
 		default:
-			response = replyMonitorError(requestId, errors.New("unknown command"))
+			endpoint := monitorEndpoint(command)
+			if endpoint == nil {
+				response = replyMonitorError(requestId, errors.New("unknown command"))
+			} else {
+				response = endpoint(requestId, args)
+			}
 		}
-		err := ch.Publish(
-			"",
-			responseQueue.Name,
-			false,
-			false,
-			amqp.Publishing{
-				ContentType: "application/json",
-				Body:        response,
-			},
-		)
+		err := publish(responseQueue.Name, response)
 		if err != nil {
 			log.Error.Println("Failed pushing metric response")
 			log.Error.Println(err)
@@ -548,27 +496,158 @@ func parseMonitor(jsonMsg map[string]interface{}) {
 	}()
 }
 
+//Returns the endpoint of a metric command, or nil if the command is unknown
+//Subscriptions run the same endpoints at an interval
+func monitorEndpoint(command string) func(requestId string, args map[string]interface{}) []byte {
+	switch command {
+	//CPU average usage per core over the last 10 seconds
+	case "cpu_edge_avg":
+		return CPUEdgeAvgEP
+	case "cpu_container_avg":
+		return CPUContainerAvgEP
+	//CPU time by core
+	case "cpu_time":
+		return CPUTimeEP
+	//CPU overall utilization over time
+	case "cpu_utilization":
+		return CPUUtilizeEP
+	//Memory usage in bytes
+	case "memory_container":
+		return MemoryContainerEP
+	case "memory_edge":
+		return MemoryEdgeEP
+	//Total memory in bytes
+	case "memory_edge_total":
+		return MemoryEdgeTotalEP
+	//Maximum memory usage in bytes
+	case "memory_container_peak":
+		return MemoryContainerPeakEP
+	case "memory_edge_peak":
+		return MemoryEdgePeakEP
+	//Seconds of the container reaching its memory soft limits
+	case "memory_container_limit_seconds":
+		return MemoryContainerLimitSecondsEP
+	//Time spent in io
+	case "io_edge_time":
+		return IOEdgeTimeEP
+	case "io_container_time":
+		return IOContainerTimeEP
+	//Bytes read in io
+	case "io_edge_read":
+		return IOEdgeReadEP
+	case "io_container_read":
+		return IOContainerReadEP
+	//Bytes written in io
+	case "io_edge_write":
+		return IOEdgeWriteEP
+	case "io_container_write":
+		return IOContainerWriteEP
+	//Filesystem usage in bytes
+	case "io_filesystem_used":
+		return IOFilesystemUsedEP
+	//Total filesystem size in bytes
+	case "io_filesystem_size":
+		return IOFilesystemSizeEP
+	//Edge side network queries
+	//Received bytes
+	case "net_edge_rx_bytes":
+		return NetEdgeRxBytesEP
+	//Received packets
+	case "net_edge_rx_packets":
+		return NetEdgeRxPacketsEP
+	//Received packets dropped
+	case "net_edge_rx_dropped":
+		return NetEdgeRxDroppedEP
+	//Received errors
+	case "net_edge_rx_error":
+		return NetEdgeRxErrorEP
+	//Transmitted bytes
+	case "net_edge_tx_bytes":
+		return NetEdgeTxBytesEP
+	//Transmitted packets
+	case "net_edge_tx_packets":
+		return NetEdgeTxPacketsEP
+	//Transmitted packets dropped
+	case "net_edge_tx_dropped":
+		return NetEdgeTxDroppedEP
+	//Transmission errors
+	case "net_edge_tx_error":
+		return NetEdgeTxErrorEP
+	//Container side network queries. Same functionality as the edge side.
+	case "net_container_rx_bytes":
+		return NetContainerRxBytesEP
+	case "net_container_rx_packets":
+		return NetContainerRxPacketsEP
+	case "net_container_rx_dropped":
+		return NetContainerRxDroppedEP
+	case "net_container_rx_error":
+		return NetContainerRxErrorEP
+	case "net_container_tx_bytes":
+		return NetContainerTxBytesEP
+	case "net_container_tx_packets":
+		return NetContainerTxPacketsEP
+	case "net_container_tx_dropped":
+		return NetContainerTxDroppedEP
+	case "net_container_tx_error":
+		return NetContainerTxErrorEP
+	case "thermal":
+		return ThermalsEP
+	//Queries supplied by the controller
+	case "promql":
+		return PromQLEP
+	default:
+		return nil
+	}
+}
+
 //Parsing arguments
-func parseMonitorContainerArgs(args map[string]interface{}) (*string, *time.Time, error) {
+func parseMonitorContainerArgs(args map[string]interface{}) (*string, *monitor2.Window, error) {
 	containerId, ok := args["containerId"].(string)
 	if !ok {
 		return nil, nil, errors.New("monitor - cannot parse request arguments")
 	}
-	timestampRaw, ok := args["time"].(int64)
-	if !ok {
-		return nil, nil, errors.New("monitor - cannot parse request arguments")
+	window, err := parseMonitorEdgeArgs(args)
+	if err != nil {
+		return nil, nil, err
 	}
-	timestamp := time.Unix(timestampRaw, 0)
-	return &containerId, &timestamp, nil
+	return &containerId, window, nil
 }
 
-func parseMonitorEdgeArgs(args map[string]interface{}) (*time.Time, error) {
-	timestampRaw, ok := args["time"].(int64)
+//Instant queries have the argument "time". Range queries have "from", "to" and "step" instead
+//Times are in UNIX seconds and the step is in seconds
+func parseMonitorEdgeArgs(args map[string]interface{}) (*monitor2.Window, error) {
+	if _, ok := args["step"]; ok {
+		return parseMonitorRangeArgs(args)
+	}
+	//JSON numbers are always decoded as float64
+	timestampRaw, ok := args["time"].(float64)
 	if !ok {
 		return nil, errors.New("monitor - cannot parse request arguments")
 	}
-	timestamp := time.Unix(timestampRaw, 0)
-	return &timestamp, nil
+	window := monitor2.At(time.Unix(int64(timestampRaw), 0))
+	return &window, nil
+}
+
+func parseMonitorRangeArgs(args map[string]interface{}) (*monitor2.Window, error) {
+	fromRaw, fromOk := args["from"].(float64)
+	toRaw, toOk := args["to"].(float64)
+	stepRaw, stepOk := args["step"].(float64)
+	if !fromOk || !toOk || !stepOk {
+		return nil, errors.New("monitor - cannot parse request arguments")
+	}
+	//Prometheus smallest scale in time is second
+	if stepRaw < 1 {
+		return nil, errors.New("monitor - step must be at least 1 second")
+	}
+	if toRaw < fromRaw {
+		return nil, errors.New("monitor - range ends before it starts")
+	}
+	window := monitor2.Window{
+		From: time.Unix(int64(fromRaw), 0),
+		To:   time.Unix(int64(toRaw), 0),
+		Step: time.Duration(stepRaw) * time.Second,
+	}
+	return &window, nil
 }
 
 func replyMonitorError(requestId string, err error) []byte {
@@ -585,16 +664,17 @@ func replyMonitorResponse(requestId string, metric monitor2.Metric) []byte {
 	return response
 }
 
+//Replies to requests that have no result, such as subscriptions
+func replyMonitorOk(requestId string) []byte {
+	response, _ := json.Marshal(map[string]interface{}{
+		"requestId": requestId,
+		"status":    "ok",
+		"api":       "monitor",
+	})
+	return response
+}
+
 func replyReject(requestId string) {
 	response := replyMonitorError(requestId, errors.New("monitoring api not enabled as prometheus is not deployed"))
-	_ = ch.Publish(
-		"",
-		responseQueue.Name,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        response,
-		},
-	)
+	_ = publish(responseQueue.Name, response)
 }
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	monitor2 "osmoticframework/agent/api/monitor"
+	"osmoticframework/agent/constants"
+	"testing"
+	"time"
+)
+
+func TestParseMonitorEdgeArgs(t *testing.T) {
+	tests := []struct {
+		Args     map[string]interface{}
+		Expected *monitor2.Window
+	}{
+		{
+			Args:     map[string]interface{}{"time": float64(1000)},
+			Expected: &monitor2.Window{To: time.Unix(1000, 0)},
+		},
+		{
+			Args:     map[string]interface{}{"from": float64(1000), "to": float64(1600), "step": float64(15)},
+			Expected: &monitor2.Window{From: time.Unix(1000, 0), To: time.Unix(1600, 0), Step: 15 * time.Second},
+		},
+		//No time
+		{
+			Args:     map[string]interface{}{},
+			Expected: nil,
+		},
+		//Range without the end
+		{
+			Args:     map[string]interface{}{"from": float64(1000), "step": float64(15)},
+			Expected: nil,
+		},
+		//Steps shorter than a second
+		{
+			Args:     map[string]interface{}{"from": float64(1000), "to": float64(1600), "step": 0.5},
+			Expected: nil,
+		},
+		//Range ending before it starts
+		{
+			Args:     map[string]interface{}{"from": float64(1600), "to": float64(1000), "step": float64(15)},
+			Expected: nil,
+		},
+	}
+	for _, test := range tests {
+		window, err := parseMonitorEdgeArgs(test.Args)
+		if test.Expected == nil {
+			if err == nil {
+				t.Errorf("Arguments %v accepted. Got %v, Want an error", test.Args, *window)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Arguments %v rejected. Got %v, Want %v", test.Args, err, *test.Expected)
+			continue
+		}
+		if !window.From.Equal(test.Expected.From) || !window.To.Equal(test.Expected.To) || window.Step != test.Expected.Step {
+			t.Errorf("Window not correct. Got %v, Want %v", *window, *test.Expected)
+		}
+		if window.IsRange() != test.Expected.IsRange() {
+			t.Errorf("Range not correct. Got %t, Want %t", window.IsRange(), test.Expected.IsRange())
+		}
+	}
+}
+
+func TestPromQLEP(t *testing.T) {
+	tests := []struct {
+		Config string
+		Args   map[string]interface{}
+	}{
+		//Disabled
+		{`{"enable_promql": false}`, map[string]interface{}{"time": float64(1000), "query": "node_load1"}},
+		//Metric not allowed
+		{`{"enable_promql": true, "promql_allowed_metrics": ["DCGM_"]}`, map[string]interface{}{"time": float64(1000), "query": "node_load1"}},
+		//No query
+		{`{"enable_promql": true, "promql_allowed_metrics": null}`, map[string]interface{}{"time": float64(1000)}},
+	}
+	//Loading a config only overwrites the fields it has
+	defer constants.Load([]byte(`{"enable_promql": false, "promql_allowed_metrics": null}`))
+	for _, test := range tests {
+		constants.Load([]byte(test.Config))
+		var reply map[string]string
+		err := json.Unmarshal(PromQLEP("request", test.Args), &reply)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply["status"] != "failed" {
+			t.Errorf("Query with config %s not rejected. Got %s, Want failed", test.Config, reply["status"])
+		}
+	}
+}
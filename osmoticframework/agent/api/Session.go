@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"osmoticframework/agent/constants"
+	"osmoticframework/agent/docker"
+	"osmoticframework/agent/log"
+	"path/filepath"
+	"strings"
+)
+
+/*
+	Agent identity across restarts and reconnections
+	The agent ID and the secret given by the controller are written to a file after registration. When the agent starts again or loses its connection to RabbitMQ,
+	it rejoins the controller under the same ID and reports the containers it still runs.
+	The controller replies with the containers it does not know about, which the agent then removes.
+*/
+
+//Contents of the session file
+type sessionFile struct {
+	AgentId string `json:"agentId"`
+	Secret  string `json:"secret"`
+}
+
+//Reads the agent ID and secret of the previous session. Returns an empty string if the agent has never registered
+func LoadSession() string {
+	content, err := ioutil.ReadFile(constants.GetAgentIdFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn.Println("Failed reading agent ID. Registering as a new agent")
+			log.Warn.Println(err)
+		}
+		return ""
+	}
+	var session sessionFile
+	if json.Unmarshal(content, &session) != nil {
+		//Older agents only wrote the agent ID
+		session = sessionFile{AgentId: strings.TrimSpace(string(content))}
+	}
+	agentId = session.AgentId
+	secret = session.Secret
+	return agentId
+}
+
+//Writes the agent ID and secret so the agent can rejoin after a restart
+func saveSession() {
+	content, err := json.Marshal(sessionFile{AgentId: agentId, Secret: secret})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(constants.GetAgentIdFile()), 0700)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(constants.GetAgentIdFile(), content, 0600)
+	}
+	if err != nil {
+		log.Warn.Println("Failed saving agent ID. The agent will register as a new agent after restarting")
+		log.Warn.Println(err)
+	}
+}
+
+//Lists the containers the controller is responsible for
+//The agent itself, monitoring services and whitelisted containers are not reported
+func managedContainers() []string {
+	containerIds := make([]string, 0)
+	containers, err := docker.DockerList()
+	if err != nil {
+		log.Warn.Println("Cannot check Docker status. Rejoining without containers")
+		log.Warn.Println(err)
+		return containerIds
+	}
+	for _, container := range containers {
+		if !isCoreImage(container.Image) {
+			containerIds = append(containerIds, container.ID)
+		}
+	}
+	return containerIds
+}
+
+//Checks if a container with the image is running. Monitoring services are only deployed if they are not running already
+func isRunning(image string) bool {
+	containers, err := docker.DockerList()
+	if err != nil {
+		return false
+	}
+	for _, container := range containers {
+		if container.State == "running" && sameImage(container.Image, image) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCoreImage(image string) bool {
+	if strings.HasPrefix(image, "osmotic_agent") {
+		return true
+	}
+	for _, whiteList := range constants.GetContainerWhitelist() {
+		if image == whiteList {
+			return true
+		}
+	}
+	for _, deployArg := range constants.DefaultContainers {
+		if sameImage(image, deployArg.Image) {
+			return true
+		}
+	}
+	return sameImage(image, constants.PrometheusContainer().Image)
+}
+
+//Docker lists images without a tag if they were run without one. e.g. prom/prometheus and prom/prometheus:latest
+func sameImage(image, other string) bool {
+	return strings.TrimSuffix(image, ":latest") == strings.TrimSuffix(other, ":latest")
+}
+
+//Stops and deletes a container the controller does not know about
+func removeContainer(containerId string) {
+	log.Info.Printf("Removing unknown container %s\n", containerId)
+	err := docker.Stop(containerId)
+	if err != nil && !strings.HasPrefix(err.Error(), "Container not running") {
+		log.Error.Printf("Failed stopping container %s\n", containerId)
+		log.Error.Println(err)
+	}
+	err = docker.Delete(containerId, false)
+	if err != nil {
+		log.Error.Printf("Failed removing container %s. This may affect performance on the edge device\n", containerId)
+		log.Error.Println(err)
+	}
+}
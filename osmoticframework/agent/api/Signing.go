@@ -0,0 +1,153 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/streadway/amqp"
+	"osmoticframework/agent/log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+	Message signing
+	Requests from the controller and messages to the controller are signed with HMAC-SHA256, using the secret the agent received when it registered.
+	The signature is sent in the message headers along with the agent ID, a timestamp and a random nonce. It covers the queue name,
+	so a message cannot be replayed to another queue. Messages older than maxMessageAge, or with a nonce that has been seen, are rejected.
+	Registration and ping messages are not signed.
+*/
+
+//Messages older than this are rejected. Nonces are remembered for the same time
+//This also allows for the clock difference between the controller and agents
+const maxMessageAge = 5 * time.Minute
+
+var errUnsigned = errors.New("message is not signed")
+var errWrongAgent = errors.New("message is for another agent")
+var errBadSignature = errors.New("invalid signature")
+var errExpired = errors.New("message expired")
+var errReplayed = errors.New("message replayed")
+
+//Nonces of verified messages. Nonce -> time.Time of the message
+var seenNonces = nonceCache{seen: make(map[string]time.Time)}
+
+type nonceCache struct {
+	mutex     sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+//Records a nonce. Returns false if the nonce has been seen
+func (c *nonceCache) add(nonce string, timestamp, now time.Time) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	//Expired messages are rejected before their nonce is checked, so their nonces can be forgotten
+	if now.Sub(c.lastPrune) > time.Minute {
+		for seenNonce, seenTime := range c.seen {
+			if now.Sub(seenTime) > maxMessageAge {
+				delete(c.seen, seenNonce)
+			}
+		}
+		c.lastPrune = now
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = timestamp
+	return true
+}
+
+//Signs a message and publishes it to the controller
+//Returns an error while the agent is reconnecting
+func publish(routingKey string, body []byte) error {
+	//The connection is not replaced while a message is being sent
+	publishLock.RLock()
+	defer publishLock.RUnlock()
+	if publishChannel == nil {
+		return errReconnecting
+	}
+	headers, err := sign(secret, routingKey, agentId, body, time.Now())
+	if err != nil {
+		return err
+	}
+	return publishChannel.Publish(
+		"",
+		routingKey,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType: "application/json",
+			Headers:     headers,
+			Body:        body,
+		},
+	)
+}
+
+//Creates the signature headers of a message
+func sign(secret, routingKey, agentId string, body []byte, now time.Time) (amqp.Table, error) {
+	buffer := make([]byte, 16)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(buffer)
+	timestamp := now.UnixMilli()
+	return amqp.Table{
+		"agentId":   agentId,
+		"timestamp": timestamp,
+		"nonce":     nonce,
+		"signature": signature(secret, routingKey, agentId, timestamp, nonce, body),
+	}, nil
+}
+
+func signature(secret, routingKey, agentId string, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(routingKey + "\n" + agentId + "\n" + strconv.FormatInt(timestamp, 10) + "\n" + nonce + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//Acknowledges a request from the controller and checks its signature. Returns the request, or nil if it is unsigned, forged or replayed
+func verified(queueName string, message amqp.Delivery) map[string]interface{} {
+	_ = message.Ack(false)
+	err := verify(secret, queueName, agentId, message.Headers, message.Body, time.Now())
+	if err != nil {
+		log.Warn.Printf("Dropped message on queue %s: %s\n", queueName, err)
+		return nil
+	}
+	return deserialize(message.Body)
+}
+
+func verify(secret, routingKey, agentId string, headers amqp.Table, body []byte, now time.Time) error {
+	if secret == "" {
+		return errUnsigned
+	}
+	signer, _ := headers["agentId"].(string)
+	timestamp, ok := headers["timestamp"].(int64)
+	if !ok {
+		return errUnsigned
+	}
+	nonce, _ := headers["nonce"].(string)
+	received, _ := headers["signature"].(string)
+	if nonce == "" || received == "" {
+		return errUnsigned
+	}
+	if signer != agentId {
+		return errWrongAgent
+	}
+	expected := signature(secret, routingKey, agentId, timestamp, nonce, body)
+	if !hmac.Equal([]byte(received), []byte(expected)) {
+		return errBadSignature
+	}
+	sent := time.UnixMilli(timestamp)
+	if now.Sub(sent) > maxMessageAge || sent.Sub(now) > maxMessageAge {
+		return errExpired
+	}
+	if !seenNonces.add(nonce, sent, now) {
+		return errReplayed
+	}
+	return nil
+}
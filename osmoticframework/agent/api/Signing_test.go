@@ -0,0 +1,45 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignature(t *testing.T) {
+	//The controller computes the same signature. See controller/queue/Signing_test.go
+	expected := "cc975cdeaf96acc9af432611e52e9e2e7badee5fcc15c4a3c0194b2ab4c5a69b"
+	if result := signature("secret", "response", "agent", 1000, "nonce", []byte("{}")); result != expected {
+		t.Errorf("Signature incorrect. Got %s, Want %s", result, expected)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"requestId":"a"}`)
+	tests := []struct {
+		Name     string
+		AgentId  string
+		Body     []byte
+		Time     time.Time
+		Expected error
+	}{
+		{Name: "valid", AgentId: "agent", Body: body, Time: now, Expected: nil},
+		{Name: "other agent", AgentId: "other", Body: body, Time: now, Expected: errWrongAgent},
+		{Name: "tampered", AgentId: "agent", Body: []byte(`{"requestId":"b"}`), Time: now, Expected: errBadSignature},
+		{Name: "expired", AgentId: "agent", Body: body, Time: now.Add(-maxMessageAge - time.Second), Expected: errExpired},
+	}
+	for _, test := range tests {
+		headers, err := sign("secret", "deploy-agent", "agent", body, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := verify("secret", "deploy-agent", test.AgentId, headers, test.Body, test.Time); err != test.Expected {
+			t.Errorf("%s: Verification incorrect. Got %v, Want %v", test.Name, err, test.Expected)
+		}
+	}
+	headers, _ := sign("secret", "deploy-agent", "agent", body, now)
+	_ = verify("secret", "deploy-agent", "agent", headers, body, now)
+	if err := verify("secret", "deploy-agent", "agent", headers, body, now); err != errReplayed {
+		t.Errorf("Replay not rejected. Got %v, Want %v", err, errReplayed)
+	}
+}
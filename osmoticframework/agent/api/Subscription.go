@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"osmoticframework/agent/log"
+	"sync"
+	"time"
+)
+
+/*
+	Metric subscriptions
+	The controller subscribes to a set of metrics at an interval. The agent queries them and pushes the samples to the queue of the subscription until it is cancelled.
+	The controller declares the queue "subscription-<subscriptionId>" before subscribing. Samples are signed like any other message to the controller.
+	A subscription with a duration ends by itself. The agent then sends a message with the status "ended".
+	Subscriptions are cancelled without notice when the controller restarts, as the new controller does not know about them.
+*/
+
+//Prometheus smallest scale in time is second
+const minSubscriptionInterval = time.Second
+
+//Limits the load the controller can put on Prometheus
+const maxSubscriptions = 16
+
+//Subscription ID -> *subscription
+var subscriptions sync.Map
+
+//Boot time of the controller, from the last ping. Used to find out if the controller restarted
+var controllerBoot int64
+
+type subscription struct {
+	id string
+	//Arguments of each metric command, including the command itself
+	metrics  []map[string]interface{}
+	interval time.Duration
+	//Zero if the subscription runs until cancelled
+	duration time.Duration
+	cancel   chan struct{}
+	once     sync.Once
+}
+
+func (s *subscription) stop() {
+	s.once.Do(func() {
+		close(s.cancel)
+	})
+}
+
+//Starts pushing samples of the requested metrics to the subscription queue
+func SubscribeEP(requestId string, args map[string]interface{}) []byte {
+	sub, err := parseSubscribeArgs(args)
+	if err != nil {
+		return replyMonitorError(requestId, err)
+	}
+	count := 0
+	subscriptions.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	if count >= maxSubscriptions {
+		return replyMonitorError(requestId, fmt.Errorf("monitor - agent already has %d subscriptions", maxSubscriptions))
+	}
+	if _, exists := subscriptions.LoadOrStore(sub.id, sub); exists {
+		return replyMonitorError(requestId, errors.New("monitor - subscription already exists"))
+	}
+	log.Info.Printf("Subscription %s started. %d metrics every %s\n", sub.id, len(sub.metrics), sub.interval)
+	go runSubscription(sub)
+	return replyMonitorOk(requestId)
+}
+
+//Stops a subscription. Samples that are already queued are still delivered
+func UnsubscribeEP(requestId string, args map[string]interface{}) []byte {
+	subscriptionId, ok := args["subscriptionId"].(string)
+	if !ok {
+		return replyMonitorError(requestId, errors.New("monitor - cannot parse request arguments"))
+	}
+	_sub, ok := subscriptions.LoadAndDelete(subscriptionId)
+	if !ok {
+		return replyMonitorError(requestId, errors.New("monitor - subscription not found"))
+	}
+	_sub.(*subscription).stop()
+	log.Info.Printf("Subscription %s cancelled\n", subscriptionId)
+	return replyMonitorOk(requestId)
+}
+
+//Queries the metrics at every interval until the subscription is cancelled or runs out
+func runSubscription(sub *subscription) {
+	ticker := time.NewTicker(sub.interval)
+	defer ticker.Stop()
+	var expire <-chan time.Time
+	if sub.duration > 0 {
+		timer := time.NewTimer(sub.duration)
+		defer timer.Stop()
+		expire = timer.C
+	}
+	pushSamples(sub, time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			pushSamples(sub, now)
+		case <-expire:
+			subscriptions.Delete(sub.id)
+			log.Info.Printf("Subscription %s ended\n", sub.id)
+			message, _ := json.Marshal(map[string]interface{}{
+				"subscriptionId": sub.id,
+				"status":         "ended",
+			})
+			err := publish(subscriptionQueue(sub.id), message)
+			if err != nil {
+				log.Error.Println("Failed notifying the end of subscription " + sub.id)
+				log.Error.Println(err)
+			}
+			return
+		case <-sub.cancel:
+			return
+		}
+	}
+}
+
+//Queries every metric of the subscription and pushes the samples
+func pushSamples(sub *subscription, now time.Time) {
+	for _, metricArgs := range sub.metrics {
+		select {
+		case <-sub.cancel:
+			return
+		default:
+		}
+		err := publish(subscriptionQueue(sub.id), sample(sub.id, metricArgs, now))
+		if err != nil {
+			log.Error.Println("Failed pushing sample of subscription " + sub.id)
+			log.Error.Println(err)
+		}
+	}
+}
+
+//Runs a metric command at the given time, and converts the response into a sample
+func sample(subscriptionId string, metricArgs map[string]interface{}, now time.Time) []byte {
+	command := metricArgs["command"].(string)
+	args := make(map[string]interface{})
+	for key, value := range metricArgs {
+		args[key] = value
+	}
+	args["time"] = float64(now.Unix())
+	//The sample has the status and the metric or error of the reply
+	reply := make(map[string]interface{})
+	_ = json.Unmarshal(monitorEndpoint(command)(subscriptionId, args), &reply)
+	delete(reply, "requestId")
+	delete(reply, "api")
+	reply["subscriptionId"] = subscriptionId
+	reply["command"] = command
+	reply["time"] = now.Unix()
+	if containerId, ok := metricArgs["containerId"]; ok {
+		reply["containerId"] = containerId
+	}
+	message, _ := json.Marshal(reply)
+	return message
+}
+
+//Cancels all subscriptions and log streams if the controller restarted. The ping of the controller carries its boot time
+func checkControllerBoot(ping map[string]interface{}) {
+	boot, ok := ping["boot"].(float64)
+	if !ok {
+		return
+	}
+	previous := controllerBoot
+	controllerBoot = int64(boot)
+	if previous == 0 || previous == int64(boot) {
+		return
+	}
+	log.Warn.Println("Controller restarted. Cancelling all subscriptions and log streams")
+	subscriptions.Range(func(subscriptionId, sub interface{}) bool {
+		subscriptions.Delete(subscriptionId)
+		sub.(*subscription).stop()
+		return true
+	})
+	stopLogStreams()
+}
+
+func subscriptionQueue(subscriptionId string) string {
+	return "subscription-" + subscriptionId
+}
+
+//Subscriptions have the arguments "subscriptionId", "metrics", "interval" and optionally "duration"
+//Each metric is an object with the command and its arguments, except the time. e.g. {"command": "cpu_container_avg", "containerId": "..."}
+//The interval and duration are in seconds
+func parseSubscribeArgs(args map[string]interface{}) (*subscription, error) {
+	subscriptionId, ok := args["subscriptionId"].(string)
+	if !ok || subscriptionId == "" {
+		return nil, errors.New("monitor - cannot parse request arguments")
+	}
+	intervalRaw, ok := args["interval"].(float64)
+	if !ok {
+		return nil, errors.New("monitor - cannot parse request arguments")
+	}
+	interval := time.Duration(intervalRaw * float64(time.Second))
+	if interval < minSubscriptionInterval {
+		return nil, errors.New("monitor - interval must be at least 1 second")
+	}
+	var duration time.Duration
+	if durationRaw, ok := args["duration"]; ok {
+		seconds, ok := durationRaw.(float64)
+		if !ok || seconds < 0 {
+			return nil, errors.New("monitor - cannot parse request arguments")
+		}
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+	metricsRaw, ok := args["metrics"].([]interface{})
+	if !ok || len(metricsRaw) == 0 {
+		return nil, errors.New("monitor - subscription has no metrics")
+	}
+	metrics := make([]map[string]interface{}, 0)
+	for _, metricRaw := range metricsRaw {
+		metricArgs, ok := metricRaw.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("monitor - cannot parse request arguments")
+		}
+		command, _ := metricArgs["command"].(string)
+		if monitorEndpoint(command) == nil {
+			return nil, errors.New("monitor - unknown command " + command)
+		}
+		//Samples are instant queries
+		if _, ok := metricArgs["step"]; ok {
+			return nil, errors.New("monitor - subscriptions cannot have range queries")
+		}
+		metrics = append(metrics, metricArgs)
+	}
+	return &subscription{
+		id:       subscriptionId,
+		metrics:  metrics,
+		interval: interval,
+		duration: duration,
+		cancel:   make(chan struct{}),
+	}, nil
+}
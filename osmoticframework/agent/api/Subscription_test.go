@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseSubscribeArgs(t *testing.T) {
+	tests := []struct {
+		Args     string
+		Valid    bool
+		Interval time.Duration
+		Duration time.Duration
+	}{
+		{`{"subscriptionId": "a", "interval": 5, "metrics": [{"command": "cpu_utilization"}]}`, true, 5 * time.Second, 0},
+		{`{"subscriptionId": "a", "interval": 1, "duration": 60, "metrics": [{"command": "cpu_container_avg", "containerId": "b"}, {"command": "memory_edge"}]}`, true, time.Second, time.Minute},
+		//No ID
+		{`{"interval": 5, "metrics": [{"command": "cpu_utilization"}]}`, false, 0, 0},
+		//Interval shorter than a second
+		{`{"subscriptionId": "a", "interval": 0.5, "metrics": [{"command": "cpu_utilization"}]}`, false, 0, 0},
+		//No metrics
+		{`{"subscriptionId": "a", "interval": 5, "metrics": []}`, false, 0, 0},
+		//Unknown command
+		{`{"subscriptionId": "a", "interval": 5, "metrics": [{"command": "cpu"}]}`, false, 0, 0},
+		//Subscriptions cannot subscribe
+		{`{"subscriptionId": "a", "interval": 5, "metrics": [{"command": "subscribe"}]}`, false, 0, 0},
+		//Range queries
+		{`{"subscriptionId": "a", "interval": 5, "metrics": [{"command": "cpu_utilization", "step": 15}]}`, false, 0, 0},
+		//Negative duration
+		{`{"subscriptionId": "a", "interval": 5, "duration": -1, "metrics": [{"command": "cpu_utilization"}]}`, false, 0, 0},
+	}
+	for _, test := range tests {
+		var args map[string]interface{}
+		if err := json.Unmarshal([]byte(test.Args), &args); err != nil {
+			t.Fatal(err)
+		}
+		sub, err := parseSubscribeArgs(args)
+		if !test.Valid {
+			if err == nil {
+				t.Errorf("Arguments %s accepted. Got subscription %s, Want an error", test.Args, sub.id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Arguments %s rejected. Got %v, Want nil", test.Args, err)
+			continue
+		}
+		if sub.interval != test.Interval || sub.duration != test.Duration {
+			t.Errorf("Subscription timing incorrect. Got %s/%s, Want %s/%s", sub.interval, sub.duration, test.Interval, test.Duration)
+		}
+	}
+}
+
+func TestCheckControllerBoot(t *testing.T) {
+	defer func() { controllerBoot = 0 }()
+	sub := &subscription{id: "boot", cancel: make(chan struct{})}
+	subscriptions.Store(sub.id, sub)
+	defer subscriptions.Delete(sub.id)
+	//First ping, and pings of the same controller
+	checkControllerBoot(map[string]interface{}{"boot": float64(1000)})
+	checkControllerBoot(map[string]interface{}{"boot": float64(1000)})
+	//Controllers without a boot time
+	checkControllerBoot(map[string]interface{}{})
+	select {
+	case <-sub.cancel:
+		t.Fatalf("Subscription cancelled without a controller restart")
+	default:
+	}
+	checkControllerBoot(map[string]interface{}{"boot": float64(2000)})
+	select {
+	case <-sub.cancel:
+	default:
+		t.Errorf("Subscription not cancelled after a controller restart")
+	}
+	if _, ok := subscriptions.Load(sub.id); ok {
+		t.Errorf("Subscription not removed after a controller restart")
+	}
+}
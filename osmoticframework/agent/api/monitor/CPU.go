@@ -1,17 +1,14 @@
 package monitor
 
-import (
-	"fmt"
-	"time"
-)
+import "fmt"
 
 //CPU related queries
-//You will need to specify the agent ID (which refers to an edge device), container ID (If applicable), and time.
+//You will need to specify the agent ID (which refers to an edge device), container ID (If applicable), and the time or range in time.
 
 //Host average usage per core from the last 10 seconds (Separated by core ID)
-func CPUEdgeAvg(time time.Time) (*Metric, error) {
+func CPUEdgeAvg(window Window) (*Metric, error) {
 	const query = "sum by (cpu) (rate(node_cpu_seconds_total{mode!='idle'}[10s]))"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -19,9 +16,9 @@ func CPUEdgeAvg(time time.Time) (*Metric, error) {
 }
 
 //Container average usage per core from the last 10 seconds (Separated by core ID)
-func CPUContainerAvg(containerId string, time time.Time) (*Metric, error) {
+func CPUContainerAvg(containerId string, window Window) (*Metric, error) {
 	const query = "sum by (cpu, id) (rate(container_cpu_usage_seconds_total{id='/docker/%s'}[10s]))"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -29,9 +26,9 @@ func CPUContainerAvg(containerId string, time time.Time) (*Metric, error) {
 }
 
 //Cumulative CPU time by core
-func CPUTimeTotal(time time.Time) (*Metric, error) {
+func CPUTimeTotal(window Window) (*Metric, error) {
 	const query = "sum by (cpu) (node_cpu_seconds_total{mode!='idle'})"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -39,9 +36,9 @@ func CPUTimeTotal(time time.Time) (*Metric, error) {
 }
 
 //Overall edge CPU utilization over the lastr 10 seconds
-func CPUUtilization(time time.Time) (*Metric, error) {
+func CPUUtilization(window Window) (*Metric, error) {
 	const query = "sum(sum by (cpu) (irate(node_cpu_seconds_total{mode!='idle'}[10s]))) / count(count(node_cpu_seconds_total) without (mode))"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
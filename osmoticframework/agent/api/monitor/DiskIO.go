@@ -1,18 +1,15 @@
 package monitor
 
-import (
-	"fmt"
-	"time"
-)
+import "fmt"
 
 //Disk IO related queries
-//You will need to specify the agent ID (which refers to an edge device), container ID (If applicable), and time.
+//You will need to specify the agent ID (which refers to an edge device), container ID (If applicable), and the time or range in time.
 
 //Number of seconds spent doing IO operations on an edge device over the last 1 minute
 //If the time is high, either the system is thrashing or there's a hard drive failure.
-func IOEdgeTime(time time.Time) (*Metric, error) {
+func IOEdgeTime(window Window) (*Metric, error) {
 	const query = "rate(node_disk_io_time_seconds_total[1m])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -20,9 +17,9 @@ func IOEdgeTime(time time.Time) (*Metric, error) {
 }
 
 //Number of seconds spent doing IO operations on a container over the last 1 minute
-func IOContainerTime(containerId string, time time.Time) (*Metric, error) {
+func IOContainerTime(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_fs_io_time_seconds{id='/docker/%s'}[1m])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -30,9 +27,9 @@ func IOContainerTime(containerId string, time time.Time) (*Metric, error) {
 }
 
 //Bytes read on an edge device over the last 10 seconds
-func IOReadEdgeBytes(time time.Time) (*Metric, error) {
+func IOReadEdgeBytes(window Window) (*Metric, error) {
 	const query = "rate(node_disk_read_bytes_total[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -40,9 +37,9 @@ func IOReadEdgeBytes(time time.Time) (*Metric, error) {
 }
 
 //Bytes read on a container over the last 10 seconds
-func IOReadContainerBytes(containerId string, time time.Time) (*Metric, error) {
+func IOReadContainerBytes(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_fs_reads_bytes_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -50,9 +47,9 @@ func IOReadContainerBytes(containerId string, time time.Time) (*Metric, error) {
 }
 
 //Bytes written on an edge device over the last 10 seconds
-func IOWriteEdgeBytes(time time.Time) (*Metric, error) {
+func IOWriteEdgeBytes(window Window) (*Metric, error) {
 	const query = "rate(node_disk_written_bytes_total[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -60,9 +57,9 @@ func IOWriteEdgeBytes(time time.Time) (*Metric, error) {
 }
 
 //Bytes written on a container over the last 10 seconds
-func IOWriteContainerBytes(containerId string, time time.Time) (*Metric, error) {
+func IOWriteContainerBytes(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_fs_writes_bytes_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -70,9 +67,9 @@ func IOWriteContainerBytes(containerId string, time time.Time) (*Metric, error)
 }
 
 //Total disk space used in bytes. Edge side only
-func IOFilesystemUsedBytes(time time.Time) (*Metric, error) {
+func IOFilesystemUsedBytes(window Window) (*Metric, error) {
 	const query = "node_filesystem_size_bytes - node_filesystem_avail_bytes"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -80,9 +77,9 @@ func IOFilesystemUsedBytes(time time.Time) (*Metric, error) {
 }
 
 //Total disk size in bytes. Edge size only
-func IOFilesystemSizeBytes(time time.Time) (*Metric, error) {
+func IOFilesystemSizeBytes(window Window) (*Metric, error) {
 	const query = "node_filesystem_size_bytes"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -14,6 +14,31 @@ import (
 //However, we do need to open ports for the monitoring services (cAdvisor, Node exporter)
 const promAddress = "http://localhost:9090/api/v1"
 
+//The time of a query. Instant queries only set To
+//Range queries return a matrix with a point every step from From to To
+type Window struct {
+	From time.Time
+	To   time.Time
+	Step time.Duration
+}
+
+//Window of an instant query
+func At(time time.Time) Window {
+	return Window{To: time}
+}
+
+func (window Window) IsRange() bool {
+	return window.Step > 0
+}
+
+//Runs either an instant or a range query depending on the window
+func execute(query string, window Window) (*Metric, error) {
+	if window.IsRange() {
+		return restQueryRange(query, window.From, window.To, window.Step)
+	}
+	return restQuery(query, window.To)
+}
+
 //Queries in a specific point in time.
 func restQuery(query string, time time.Time) (*Metric, error) {
 	client := resty.New()
@@ -44,9 +69,9 @@ func restQueryRange(query string, from, to time.Time, step time.Duration) (*Metr
 	response, err := client.R().
 		SetQueryParams(map[string]string{
 			"query": query,
-			"from":  strconv.FormatInt(from.UnixNano(), 10),
-			"to":    strconv.FormatInt(to.UnixNano(), 10),
-			"step":  strconv.Itoa(int(step.Seconds())),
+			"start": strconv.FormatInt(from.Unix(), 10),
+			"end":   strconv.FormatInt(to.Unix(), 10),
+			"step":  strconv.FormatInt(int64(step.Seconds()), 10),
 		}).
 		Get(promAddress + "/query_range")
 	if err != nil {
@@ -69,6 +69,29 @@ func TestScalar(t *testing.T) {
 	}
 }
 
+func TestRange(t *testing.T) {
+	_, err := http.Get(promAddress)
+	if err != nil {
+		t.Skip("Prometheus not reachable. Skipping")
+	}
+	now := time.Now()
+	window := Window{From: now.Add(-5 * time.Minute), To: now, Step: 15 * time.Second}
+	metric, err := execute("prometheus_http_requests_total", window)
+	if err != nil {
+		t.Error("Request failed")
+		t.Error(err)
+		t.FailNow()
+	}
+	mType := metric.Type
+	if mType != MatrixType {
+		t.Errorf("Incorrect return type. Got %s, Want %s", mType, MatrixType)
+	}
+	_, ok := metric.Data.([]Matrix)
+	if !ok {
+		t.Errorf("Cannot assert to matrix")
+	}
+}
+
 func TestParseScalar(t *testing.T) {
 	now := time.Now().UnixNano()
 	tests := []struct {
@@ -128,7 +151,7 @@ func TestParseScalar(t *testing.T) {
 }
 
 func testCPUEdgeAvg(t *testing.T) {
-	_, err := CPUEdgeAvg(time.Now())
+	_, err := CPUEdgeAvg(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -136,7 +159,7 @@ func testCPUEdgeAvg(t *testing.T) {
 }
 
 func testCPUContainerAvg(t *testing.T) {
-	_, err := CPUContainerAvg("foo-container-id", time.Now())
+	_, err := CPUContainerAvg("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -144,7 +167,7 @@ func testCPUContainerAvg(t *testing.T) {
 }
 
 func testCPUTimeTotal(t *testing.T) {
-	_, err := CPUTimeTotal(time.Now())
+	_, err := CPUTimeTotal(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -152,7 +175,7 @@ func testCPUTimeTotal(t *testing.T) {
 }
 
 func testCPUUtilization(t *testing.T) {
-	_, err := CPUUtilization(time.Now())
+	_, err := CPUUtilization(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -160,7 +183,7 @@ func testCPUUtilization(t *testing.T) {
 }
 
 func testMemoryContainer(t *testing.T) {
-	_, err := MemoryContainer("foo-container-id", time.Now())
+	_, err := MemoryContainer("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -168,7 +191,7 @@ func testMemoryContainer(t *testing.T) {
 }
 
 func testMemoryEdge(t *testing.T) {
-	_, err := MemoryEdge(time.Now())
+	_, err := MemoryEdge(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -176,7 +199,7 @@ func testMemoryEdge(t *testing.T) {
 }
 
 func testMemoryContainerPeak(t *testing.T) {
-	_, err := MemoryContainerPeak("foo-container-id", time.Now())
+	_, err := MemoryContainerPeak("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -184,7 +207,7 @@ func testMemoryContainerPeak(t *testing.T) {
 }
 
 func testMemoryEdgePeak(t *testing.T) {
-	_, err := MemoryEdgePeak(time.Now())
+	_, err := MemoryEdgePeak(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -192,7 +215,7 @@ func testMemoryEdgePeak(t *testing.T) {
 }
 
 func testMemoryContainerReachLimitSeconds(t *testing.T) {
-	_, err := MemoryContainerReachLimitSeconds("foo-container-id", time.Now())
+	_, err := MemoryContainerReachLimitSeconds("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -200,7 +223,7 @@ func testMemoryContainerReachLimitSeconds(t *testing.T) {
 }
 
 func testIOEdgeTime(t *testing.T) {
-	_, err := IOEdgeTime(time.Now())
+	_, err := IOEdgeTime(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -208,7 +231,7 @@ func testIOEdgeTime(t *testing.T) {
 }
 
 func testIOContainerTime(t *testing.T) {
-	_, err := IOContainerTime("foo-container-id", time.Now())
+	_, err := IOContainerTime("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -216,7 +239,7 @@ func testIOContainerTime(t *testing.T) {
 }
 
 func testIOReadEdgeBytes(t *testing.T) {
-	_, err := IOReadEdgeBytes(time.Now())
+	_, err := IOReadEdgeBytes(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -224,7 +247,7 @@ func testIOReadEdgeBytes(t *testing.T) {
 }
 
 func testIOReadContainerBytes(t *testing.T) {
-	_, err := IOReadContainerBytes("foo-container-id", time.Now())
+	_, err := IOReadContainerBytes("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -232,7 +255,7 @@ func testIOReadContainerBytes(t *testing.T) {
 }
 
 func testIOWriteEdgeBytes(t *testing.T) {
-	_, err := IOWriteEdgeBytes(time.Now())
+	_, err := IOWriteEdgeBytes(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -240,7 +263,7 @@ func testIOWriteEdgeBytes(t *testing.T) {
 }
 
 func testIOWriteContainerBytes(t *testing.T) {
-	_, err := IOWriteContainerBytes("foo-container-id", time.Now())
+	_, err := IOWriteContainerBytes("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -248,7 +271,7 @@ func testIOWriteContainerBytes(t *testing.T) {
 }
 
 func testIOFilesystemUsedBytes(t *testing.T) {
-	_, err := IOFilesystemUsedBytes(time.Now())
+	_, err := IOFilesystemUsedBytes(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -256,7 +279,7 @@ func testIOFilesystemUsedBytes(t *testing.T) {
 }
 
 func testIOFilesystemSizeBytes(t *testing.T) {
-	_, err := IOFilesystemSizeBytes(time.Now())
+	_, err := IOFilesystemSizeBytes(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -264,7 +287,7 @@ func testIOFilesystemSizeBytes(t *testing.T) {
 }
 
 func testNetworkEdgeRxBytes(t *testing.T) {
-	_, err := NetworkEdgeRxBytes(time.Now())
+	_, err := NetworkEdgeRxBytes(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -272,7 +295,7 @@ func testNetworkEdgeRxBytes(t *testing.T) {
 }
 
 func testNetworkContainerRxBytes(t *testing.T) {
-	_, err := NetworkContainerRxBytes("foo-container-id", time.Now())
+	_, err := NetworkContainerRxBytes("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -280,7 +303,7 @@ func testNetworkContainerRxBytes(t *testing.T) {
 }
 
 func testNetworkEdgeTxBytes(t *testing.T) {
-	_, err := NetworkEdgeTxBytes(time.Now())
+	_, err := NetworkEdgeTxBytes(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -288,7 +311,7 @@ func testNetworkEdgeTxBytes(t *testing.T) {
 }
 
 func testNetworkContainerTxBytes(t *testing.T) {
-	_, err := NetworkContainerTxBytes("foo-container-id", time.Now())
+	_, err := NetworkContainerTxBytes("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -296,7 +319,7 @@ func testNetworkContainerTxBytes(t *testing.T) {
 }
 
 func testNetworkEdgeRxPackets(t *testing.T) {
-	_, err := NetworkEdgeRxPackets(time.Now())
+	_, err := NetworkEdgeRxPackets(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -304,7 +327,7 @@ func testNetworkEdgeRxPackets(t *testing.T) {
 }
 
 func testNetworkContainerRxPackets(t *testing.T) {
-	_, err := NetworkContainerRxPackets("foo-container-id", time.Now())
+	_, err := NetworkContainerRxPackets("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -312,7 +335,7 @@ func testNetworkContainerRxPackets(t *testing.T) {
 }
 
 func testNetworkEdgeTxPackets(t *testing.T) {
-	_, err := NetworkEdgeTxPackets(time.Now())
+	_, err := NetworkEdgeTxPackets(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -320,7 +343,7 @@ func testNetworkEdgeTxPackets(t *testing.T) {
 }
 
 func testNetworkContainerTxPackets(t *testing.T) {
-	_, err := NetworkContainerTxPackets("foo-container-id", time.Now())
+	_, err := NetworkContainerTxPackets("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -328,7 +351,7 @@ func testNetworkContainerTxPackets(t *testing.T) {
 }
 
 func testNetworkEdgePacketRxDropped(t *testing.T) {
-	_, err := NetworkEdgePacketRxDropped(time.Now())
+	_, err := NetworkEdgePacketRxDropped(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -336,7 +359,7 @@ func testNetworkEdgePacketRxDropped(t *testing.T) {
 }
 
 func testNetworkPacketContainerRxDropped(t *testing.T) {
-	_, err := NetworkContainerPacketRxDropped("foo-container-id", time.Now())
+	_, err := NetworkContainerPacketRxDropped("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -344,7 +367,7 @@ func testNetworkPacketContainerRxDropped(t *testing.T) {
 }
 
 func testNetworkEdgePacketTxDropped(t *testing.T) {
-	_, err := NetworkEdgePacketTxDropped(time.Now())
+	_, err := NetworkEdgePacketTxDropped(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -352,7 +375,7 @@ func testNetworkEdgePacketTxDropped(t *testing.T) {
 }
 
 func testNetworkPacketContainerTxDropped(t *testing.T) {
-	_, err := NetworkContainerPacketTxDropped("foo-container-id", time.Now())
+	_, err := NetworkContainerPacketTxDropped("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -360,7 +383,7 @@ func testNetworkPacketContainerTxDropped(t *testing.T) {
 }
 
 func testNetworkEdgeRxError(t *testing.T) {
-	_, err := NetworkEdgeRxError(time.Now())
+	_, err := NetworkEdgeRxError(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -368,7 +391,7 @@ func testNetworkEdgeRxError(t *testing.T) {
 }
 
 func testNetworkContainerRxError(t *testing.T) {
-	_, err := NetworkContainerRxError("foo-container-id", time.Now())
+	_, err := NetworkContainerRxError("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -376,7 +399,7 @@ func testNetworkContainerRxError(t *testing.T) {
 }
 
 func testNetworkEdgeTxError(t *testing.T) {
-	_, err := NetworkEdgeTxError(time.Now())
+	_, err := NetworkEdgeTxError(At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
@@ -384,7 +407,7 @@ func testNetworkEdgeTxError(t *testing.T) {
 }
 
 func testNetworkContainerTxError(t *testing.T) {
-	_, err := NetworkContainerTxError("foo-container-id", time.Now())
+	_, err := NetworkContainerTxError("foo-container-id", At(time.Now()))
 	if err != nil {
 		t.Error("Request failed")
 		t.Error(err)
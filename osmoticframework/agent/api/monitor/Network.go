@@ -1,17 +1,14 @@
 package monitor
 
-import (
-	"fmt"
-	"time"
-)
+import "fmt"
 
 //Network related queries
-//You will need to specify the agent ID (which refers to an edge device), container ID (If applicable), and time.
+//You will need to specify the agent ID (which refers to an edge device), container ID (If applicable), and the time or range in time.
 
 //Bytes received on the edge device over the past 10 seconds
-func NetworkEdgeRxBytes(time time.Time) (*Metric, error) {
+func NetworkEdgeRxBytes(window Window) (*Metric, error) {
 	const query = "rate(node_network_receive_bytes_total{device!='lo'}[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -19,9 +16,9 @@ func NetworkEdgeRxBytes(time time.Time) (*Metric, error) {
 }
 
 //Bytes received on the container over the past 10 seconds
-func NetworkContainerRxBytes(containerId string, time time.Time) (*Metric, error) {
+func NetworkContainerRxBytes(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_network_receive_bytes_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -29,9 +26,9 @@ func NetworkContainerRxBytes(containerId string, time time.Time) (*Metric, error
 }
 
 //Bytes transmitted on the edge device over the past 10 seconds
-func NetworkEdgeTxBytes(time time.Time) (*Metric, error) {
+func NetworkEdgeTxBytes(window Window) (*Metric, error) {
 	const query = "rate(node_network_transmit_bytes_total{device!='lo'}[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -39,9 +36,9 @@ func NetworkEdgeTxBytes(time time.Time) (*Metric, error) {
 }
 
 //Bytes transmitted on the container over the past 10 seconds
-func NetworkContainerTxBytes(containerId string, time time.Time) (*Metric, error) {
+func NetworkContainerTxBytes(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_network_transmit_bytes_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -49,9 +46,9 @@ func NetworkContainerTxBytes(containerId string, time time.Time) (*Metric, error
 }
 
 //Packets received on the edge device over the past 10 seconds
-func NetworkEdgeRxPackets(time time.Time) (*Metric, error) {
+func NetworkEdgeRxPackets(window Window) (*Metric, error) {
 	const query = "rate(node_network_receive_packets_total{device!='lo'}[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -59,9 +56,9 @@ func NetworkEdgeRxPackets(time time.Time) (*Metric, error) {
 }
 
 //Packets received on the container over the past 10 seconds
-func NetworkContainerRxPackets(containerId string, time time.Time) (*Metric, error) {
+func NetworkContainerRxPackets(containerId string, window Window) (*Metric, error) {
 	const query = "container_network_receive_packets_total{id='/docker/%s'}"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -69,9 +66,9 @@ func NetworkContainerRxPackets(containerId string, time time.Time) (*Metric, err
 }
 
 //Packets transmitted on the edge device over the past 10 seconds
-func NetworkEdgeTxPackets(time time.Time) (*Metric, error) {
+func NetworkEdgeTxPackets(window Window) (*Metric, error) {
 	const query = "rate(node_network_transmit_packets_total{device!='lo'}[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -79,9 +76,9 @@ func NetworkEdgeTxPackets(time time.Time) (*Metric, error) {
 }
 
 //Packets transmitted on the container over the past 10 seconds
-func NetworkContainerTxPackets(containerId string, time time.Time) (*Metric, error) {
+func NetworkContainerTxPackets(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_network_transmit_packets_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -89,9 +86,9 @@ func NetworkContainerTxPackets(containerId string, time time.Time) (*Metric, err
 }
 
 //Packets dropped during receiving on the edge device over the past 10 seconds
-func NetworkEdgePacketRxDropped(time time.Time) (*Metric, error) {
+func NetworkEdgePacketRxDropped(window Window) (*Metric, error) {
 	const query = "rate(node_network_receive_drop_total{device!='lo'}[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -99,9 +96,9 @@ func NetworkEdgePacketRxDropped(time time.Time) (*Metric, error) {
 }
 
 //Packets dropped during receiving on the container over the past 10 seconds
-func NetworkContainerPacketRxDropped(containerId string, time time.Time) (*Metric, error) {
+func NetworkContainerPacketRxDropped(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_network_receive_packets_dropped_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -109,9 +106,9 @@ func NetworkContainerPacketRxDropped(containerId string, time time.Time) (*Metri
 }
 
 //Packets dropped during transmitting on the edge device over the past 10 seconds
-func NetworkEdgePacketTxDropped(time time.Time) (*Metric, error) {
+func NetworkEdgePacketTxDropped(window Window) (*Metric, error) {
 	const query = "rate(node_network_transmit_drop_total{device!='lo'}[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -119,9 +116,9 @@ func NetworkEdgePacketTxDropped(time time.Time) (*Metric, error) {
 }
 
 //Packets dropped during transmitting on the container over the past 10 seconds
-func NetworkContainerPacketTxDropped(containerId string, time time.Time) (*Metric, error) {
+func NetworkContainerPacketTxDropped(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_network_transmit_packets_dropped_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -129,9 +126,9 @@ func NetworkContainerPacketTxDropped(containerId string, time time.Time) (*Metri
 }
 
 //Errors occurred during receiving on the edge device over the past 10 seconds
-func NetworkEdgeRxError(time time.Time) (*Metric, error) {
+func NetworkEdgeRxError(window Window) (*Metric, error) {
 	const query = "rate(node_network_receive_errs_total{device!='lo'}[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -139,9 +136,9 @@ func NetworkEdgeRxError(time time.Time) (*Metric, error) {
 }
 
 //Errors occurred during receiving on the container over the past 10 seconds
-func NetworkContainerRxError(containerId string, time time.Time) (*Metric, error) {
+func NetworkContainerRxError(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_network_receive_errors_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
@@ -149,9 +146,9 @@ func NetworkContainerRxError(containerId string, time time.Time) (*Metric, error
 }
 
 //Errors occurred during transmitting on the edge device over the past 10 seconds
-func NetworkEdgeTxError(time time.Time) (*Metric, error) {
+func NetworkEdgeTxError(window Window) (*Metric, error) {
 	const query = "rate(node_network_transmit_errs_total{device!='lo'}[10s])"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
@@ -159,9 +156,9 @@ func NetworkEdgeTxError(time time.Time) (*Metric, error) {
 }
 
 //Errors occurred during transmitting on the container over the past 10 seconds
-func NetworkContainerTxError(containerId string, time time.Time) (*Metric, error) {
+func NetworkContainerTxError(containerId string, window Window) (*Metric, error) {
 	const query = "rate(container_network_transmit_errors_total{id='/docker/%s'}[10s])"
-	metric, err := restQuery(fmt.Sprintf(query, containerId), time)
+	metric, err := execute(fmt.Sprintf(query, containerId), window)
 	if err != nil {
 		return nil, err
 	}
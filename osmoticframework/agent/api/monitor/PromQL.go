@@ -0,0 +1,207 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//Queries supplied by the controller
+//Prometheus' query API is read only, so a query can only read metrics. The allow-list limits which metrics it can read.
+
+//Longer queries are rejected. Prometheus would spend too long parsing them
+const maxQueryLength = 4096
+
+//Words in PromQL that look like metric names, but are operators, aggregations or literals
+var promqlKeywords = map[string]bool{
+	"and": true, "or": true, "unless": true, "bool": true, "offset": true, "atan2": true,
+	"by": true, "without": true, "on": true, "ignoring": true, "group_left": true, "group_right": true,
+	"sum": true, "min": true, "max": true, "avg": true, "group": true, "stddev": true, "stdvar": true,
+	"count": true, "count_values": true, "bottomk": true, "topk": true, "quantile": true,
+	"inf": true, "nan": true,
+}
+
+//Keywords followed by a list of labels in brackets, e.g. sum by (cpu)
+var labelListKeywords = map[string]bool{
+	"by": true, "without": true, "on": true, "ignoring": true, "group_left": true, "group_right": true,
+}
+
+//Runs a query supplied by the controller. Call CheckQuery first
+func PromQL(query string, window Window) (*Metric, error) {
+	metric, err := execute(query, window)
+	if err != nil {
+		return nil, err
+	}
+	return metric, nil
+}
+
+//Checks that a query only reads metrics with one of the allowed prefixes. All metrics are allowed if there are no prefixes
+//Selectors without a metric name, e.g. {job="node"} or {__name__=~".+"}, can read any metric and are rejected
+func CheckQuery(query string, allowed []string) error {
+	if strings.TrimSpace(query) == "" {
+		return errors.New("empty query")
+	}
+	if len(query) > maxQueryLength {
+		return fmt.Errorf("query longer than %d characters", maxQueryLength)
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	names, err := metricNames(query)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !hasPrefix(name, allowed) {
+			return fmt.Errorf("metric %s is not allowed", name)
+		}
+	}
+	return nil
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+//Lists the metric names a query reads. This is not a full PromQL parser. It only tells metric names apart from functions, keywords and labels
+func metricNames(query string) ([]string, error) {
+	names := make([]string, 0)
+	//A selector in curly brackets must follow a metric name
+	afterName := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			end := skipString(query, i)
+			if end < 0 {
+				return nil, errors.New("unterminated string")
+			}
+			i = end
+			afterName = false
+		case c == '#':
+			//Comment until the end of the line
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return names, nil
+			}
+			i += end
+		case c == '{':
+			end := matching(query, i, '{', '}')
+			if end < 0 {
+				return nil, errors.New("unterminated selector")
+			}
+			if !afterName {
+				return nil, errors.New("selectors without a metric name are not allowed")
+			}
+			if strings.Contains(query[i:end], "__name__") {
+				return nil, errors.New("selecting metrics by __name__ is not allowed")
+			}
+			i = end + 1
+			afterName = false
+		case c == '[':
+			//Ranges and subqueries only contain durations
+			end := matching(query, i, '[', ']')
+			if end < 0 {
+				return nil, errors.New("unterminated range")
+			}
+			i = end + 1
+			afterName = false
+		case isIdentStart(c):
+			end := i
+			for end < len(query) && isIdentChar(query[end]) {
+				end++
+			}
+			word := query[i:end]
+			next := end
+			for next < len(query) && isSpace(query[next]) {
+				next++
+			}
+			afterName = false
+			switch {
+			case labelListKeywords[strings.ToLower(word)] && next < len(query) && query[next] == '(':
+				closing := matching(query, next, '(', ')')
+				if closing < 0 {
+					return nil, errors.New("unterminated label list")
+				}
+				end = closing + 1
+			case promqlKeywords[strings.ToLower(word)]:
+			case next < len(query) && query[next] == '(':
+				//Function call
+			default:
+				names = append(names, word)
+				afterName = true
+			}
+			i = end
+		case c >= '0' && c <= '9' || c == '.':
+			//Numbers and durations, e.g. 1e3 or 5m
+			for i < len(query) && (isIdentChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			afterName = false
+		default:
+			if !isSpace(c) {
+				afterName = false
+			}
+			i++
+		}
+	}
+	return names, nil
+}
+
+//Returns the index after the closing quote, or -1 if the string is not closed
+func skipString(query string, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		//Raw strings in backticks have no escapes
+		if query[i] == '\\' && quote != '`' {
+			i++
+			continue
+		}
+		if query[i] == quote {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+//Returns the index of the matching closing bracket, or -1 if there is none. Brackets in strings are ignored
+func matching(query string, start int, openBracket, closeBracket byte) int {
+	depth := 0
+	for i := start; i < len(query); {
+		switch query[i] {
+		case '"', '\'', '`':
+			end := skipString(query, i)
+			if end < 0 {
+				return -1
+			}
+			i = end
+			continue
+		case openBracket:
+			depth++
+		case closeBracket:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
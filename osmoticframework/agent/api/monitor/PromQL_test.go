@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetricNames(t *testing.T) {
+	tests := []struct {
+		Query    string
+		Expected []string
+	}{
+		{"node_load1", []string{"node_load1"}},
+		{"sum by (cpu) (rate(node_cpu_seconds_total{mode!='idle'}[10s]))", []string{"node_cpu_seconds_total"}},
+		{"sum(rate(DCGM_FI_DEV_GPU_UTIL[1m] offset 5m)) without (gpu) / on(instance) group_left node_uname_info", []string{"DCGM_FI_DEV_GPU_UTIL", "node_uname_info"}},
+		{`label_replace(up{job="node"}, "host", "$1", "instance", "(.*):.*")`, []string{"up"}},
+		{"histogram_quantile(0.9, rate(http_request_duration_seconds_bucket[5m:30s])) > 1e3", []string{"http_request_duration_seconds_bucket"}},
+		{"job:request_rate:sum # recording rule", []string{"job:request_rate:sum"}},
+	}
+	for _, test := range tests {
+		names, err := metricNames(test.Query)
+		if err != nil {
+			t.Errorf("Query %s rejected. Got %v, Want %v", test.Query, err, test.Expected)
+			continue
+		}
+		if !reflect.DeepEqual(names, test.Expected) {
+			t.Errorf("Metric names of %s incorrect. Got %v, Want %v", test.Query, names, test.Expected)
+		}
+	}
+}
+
+func TestCheckQuery(t *testing.T) {
+	allowed := []string{"node_", "DCGM_"}
+	tests := []struct {
+		Query   string
+		Allowed []string
+		Valid   bool
+	}{
+		{"rate(node_network_receive_bytes_total[10s])", allowed, true},
+		{"DCGM_FI_DEV_GPU_TEMP > node_hwmon_temp_celsius", allowed, true},
+		{"container_memory_usage_bytes", allowed, false},
+		{"node_load1 + on() group_left up", allowed, false},
+		{`{job="node"}`, allowed, false},
+		{`node_load1 or {__name__=~".+"}`, allowed, false},
+		{`node_load1{job="node}`, allowed, false},
+		{"container_memory_usage_bytes", nil, true},
+		{"", nil, false},
+		{strings.Repeat("node_load1 + ", maxQueryLength), nil, false},
+	}
+	for _, test := range tests {
+		err := CheckQuery(test.Query, test.Allowed)
+		if (err == nil) != test.Valid {
+			t.Errorf("Check of %.40s incorrect. Got %v, Want valid %t", test.Query, err, test.Valid)
+		}
+	}
+}
@@ -1,10 +1,8 @@
 package monitor
 
-import "time"
-
-func Thermals(time time.Time) (*Metric, error) {
+func Thermals(window Window) (*Metric, error) {
 	const query = "node_thermal_zone_temp"
-	metric, err := restQuery(query, time)
+	metric, err := execute(query, window)
 	if err != nil {
 		return nil, err
 	}
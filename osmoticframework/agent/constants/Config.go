@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"golang.org/x/net/nettest"
 	"net"
+	"os"
 	"osmoticframework/agent/log"
+	"path/filepath"
 	"sync"
 )
 
@@ -16,6 +18,19 @@ type configStruct struct {
 	DeviceSupport      []string `json:"device_support"`
 	SensorSupport      []string `json:"sensor_support"`
 	ContainerWhitelist []string `json:"container_whitelist"`
+	AgentIdFile        string   `json:"agent_id_file"`
+	RegistrationToken  string   `json:"registration_token"`
+	//Queries the controller can run on the local Prometheus with the promql monitor command
+	EnablePromQL         bool     `json:"enable_promql"`
+	PromQLAllowedMetrics []string `json:"promql_allowed_metrics"`
+	//Commands the controller can run inside the containers of the agent with the exec deploy command
+	EnableExec    bool `json:"enable_exec"`
+	ExecMaxOutput int  `json:"exec_max_output"`
+	//TLS credentials for amqps:// addresses. File names are relative to the credential directory
+	CredDirectory     string `json:"cred_directory"`
+	CACertificate     string `json:"ca_certificate"`
+	ClientCertificate string `json:"client_certificate"`
+	ClientKey         string `json:"client_key"`
 }
 
 func Load(jsonBytes []byte) {
@@ -59,3 +74,91 @@ func GetSensorSupport() []string {
 func GetContainerWhitelist() []string {
 	return config.ContainerWhitelist
 }
+
+//File the agent ID is kept in. The agent rejoins under the same ID after restarts and reconnections
+func GetAgentIdFile() string {
+	if config.AgentIdFile == "" {
+		return "agent-id"
+	}
+	return config.AgentIdFile
+}
+
+//Bootstrap token presented to the controller when registering. Only required if the controller enables registration authentication
+func GetRegistrationToken() string {
+	return config.RegistrationToken
+}
+
+//Allows the controller to run its own PromQL queries. Disabled by default
+func IsPromQLEnable() bool {
+	return config.EnablePromQL
+}
+
+//Prefixes of the metric names PromQL queries may read, e.g. node_ or DCGM_. All metrics are allowed if empty
+func GetPromQLAllowedMetrics() []string {
+	return config.PromQLAllowedMetrics
+}
+
+//Allows the controller to run commands inside the containers it deployed. Disabled by default
+func IsExecEnable() bool {
+	return config.EnableExec
+}
+
+//Bytes of stdout and stderr each returned by an exec command. The rest is cut. Defaults to 64 KiB
+func GetExecMaxOutput() int {
+	if config.ExecMaxOutput <= 0 {
+		return 64 * 1024
+	}
+	return config.ExecMaxOutput
+}
+
+//Directory of the TLS credentials. Defaults to agent-cred in the current directory
+//The directory must only be accessible by the owner, as it holds the private key
+func GetCredDirectory() string {
+	credDir := config.CredDirectory
+	if credDir == "" {
+		pwd, err := os.Getwd()
+		if err != nil {
+			log.Fatal.Println("Unable to obtain current directory")
+			log.Fatal.Panicln(err)
+		}
+		credDir = filepath.Join(pwd, "agent-cred")
+	}
+	stat, err := os.Stat(credDir)
+	if os.IsNotExist(err) {
+		err = os.MkdirAll(credDir, 0700)
+		log.Info.Println("Agent credential directory created")
+	} else if err == nil && stat.Mode() != (0700|os.ModeDir) {
+		log.Fatal.Fatalln("Credential directory insecure. Must be 0700")
+	}
+	if err != nil {
+		log.Fatal.Println("failed creating credential directory")
+		log.Fatal.Panicln(err)
+	}
+	return credDir
+}
+
+//CA certificate used to verify the RabbitMQ server
+func GetCACertificate() string {
+	return credFile(config.CACertificate, "ca_certificate.pem")
+}
+
+//Client certificate the agent authenticates with
+func GetClientCertificate() string {
+	return credFile(config.ClientCertificate, "client_certificate.pem")
+}
+
+//Private key of the client certificate
+func GetClientKey() string {
+	return credFile(config.ClientKey, "client_key.pem")
+}
+
+//Resolves a credential file. Absolute paths are used as is
+func credFile(name, fallback string) string {
+	if name == "" {
+		name = fallback
+	}
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(GetCredDirectory(), name)
+}
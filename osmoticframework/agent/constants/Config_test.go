@@ -62,5 +62,11 @@ func TestConfig(t *testing.T) {
 	if whitelist[1] != expectWhitelist[1] {
 		t.Errorf("Container whitelist incorrect. Got %s, Want %s", whitelist[1], expectWhitelist[1])
 	}
-
+	//Exec is disabled unless enabled explicitly
+	if IsExecEnable() {
+		t.Errorf("Exec enabled incorrect. Got %t, Want %t", true, false)
+	}
+	if GetExecMaxOutput() != 64*1024 {
+		t.Errorf("Exec output limit incorrect. Got %d, Want %d", GetExecMaxOutput(), 64*1024)
+	}
 }
@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"osmoticframework/agent/log"
 	"osmoticframework/agent/types"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
 )
@@ -332,6 +334,7 @@ func Inspect(containerId string) (*types.Container, error) {
 		Image:      iContainer.Config.Image,
 		Command:    strings.Join(iContainer.Config.Cmd, " "),
 		Status:     iContainer.State.Status,
+		ExitCode:   iContainer.State.ExitCode,
 		SizeRootFs: iContainer.SizeRootFs,
 		SizeRw:     iContainer.SizeRw,
 	}
@@ -428,6 +431,83 @@ func dockerInspect(containerID string) (*docker.Container, error) {
 	})
 }
 
+//Gets the restart count and whether the container was killed for running out of memory
+func LifecycleState(containerId string) (int, bool, error) {
+	iContainer, err := dockerInspect(containerId)
+	if err != nil {
+		return 0, false, err
+	}
+	return iContainer.RestartCount, iContainer.State.OOMKilled, nil
+}
+
+//Writes the output of a container to stdout and stderr. Each line starts with its timestamp
+//tail is the number of lines from the end, or all lines if it is negative. A zero since time reads from the start
+//Follow mode blocks until the container stops or the context is cancelled
+func Logs(ctx context.Context, containerId string, tail int, since time.Time, follow bool, stdout, stderr io.Writer) error {
+	iContainer, err := dockerInspect(containerId)
+	if err != nil {
+		return err
+	}
+	options := docker.LogsOptions{
+		Context:      ctx,
+		Container:    containerId,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		Stdout:       true,
+		Stderr:       true,
+		Follow:       follow,
+		Timestamps:   true,
+		Tail:         "all",
+		//Output of containers with a TTY is not multiplexed. It all goes to stdout
+		RawTerminal: iContainer.Config.Tty,
+	}
+	if tail >= 0 {
+		options.Tail = strconv.Itoa(tail)
+	}
+	if !since.IsZero() {
+		options.Since = since.Unix()
+	}
+	return client.Logs(options)
+}
+
+//Runs a command inside a container, and waits for it to finish. Returns the exit code of the command
+//Docker cannot kill exec processes. If the context is cancelled, this returns but the command keeps running in the container
+func Exec(ctx context.Context, containerId string, command []string, stdout, stderr io.Writer) (int, error) {
+	exec, err := client.CreateExec(docker.CreateExecOptions{
+		Context:      ctx,
+		Container:    containerId,
+		Cmd:          command,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = client.StartExec(exec.ID, docker.StartExecOptions{
+		Context:      ctx,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+	})
+	if err != nil {
+		return 0, err
+	}
+	//The stream can end before the exit code is set
+	for {
+		inspect, err := client.InspectExec(exec.ID)
+		if err != nil {
+			return 0, err
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
 func RegisterListener(options docker.EventsOptions, listener chan *docker.APIEvents) error {
 	return client.AddEventListenerWithOptions(options, listener)
 }
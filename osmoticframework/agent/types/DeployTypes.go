@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 /*
 	The deploying structure. The controller needs to pass this struct to agent or update a container.
 */
@@ -62,6 +64,35 @@ type CrashReport struct {
 	Image    string
 	Status   string
 	ExitCode int
+	//The last lines of output of the container
+	Output []string
+}
+
+//A line of output of a container
+type LogLine struct {
+	//stdout or stderr
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+}
+
+//Lifecycle event of a container, forwarded to the controller
+type ContainerEvent struct {
+	AgentId string
+	ID      string
+	Name    string
+	Image   string
+	//The Docker event. create, start, restart, stop, die, oom, health_status or destroy
+	Action string
+	//Status of the container after the event. Empty if the event does not change it
+	Status   string
+	ExitCode int
+	//Result of the Docker healthcheck. healthy, unhealthy or starting. Only set for health_status events
+	Health       string
+	RestartCount int
+	OOMKilled    bool
+	//UNIX time in nanoseconds
+	Time int64
 }
 
 type RestartPolicy string
@@ -123,6 +154,8 @@ type Container struct {
 	//Container arguments
 	Command string
 	Status  string
+	//Exit code of the last run. Only meaningful once the container has exited
+	ExitCode int
 	//Total container file size
 	SizeRootFs int64
 	//Container file size, excluding its base image
@@ -9,10 +9,18 @@ import (
 	"os/signal"
 	"osmoticframework/controller/api"
 	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/api/rest"
 	"osmoticframework/controller/auto"
+	"osmoticframework/controller/autoscale"
+	"osmoticframework/controller/history"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
+	"osmoticframework/controller/notify"
 	"osmoticframework/controller/queue"
+	"osmoticframework/controller/reconcile"
 	"osmoticframework/controller/recovery"
+	"osmoticframework/controller/replicaset"
+	"osmoticframework/controller/rules"
 	"osmoticframework/controller/types"
 	_ "osmoticframework/controller/util"
 	"osmoticframework/controller/vars"
@@ -91,12 +99,38 @@ func main() {
 	}
 	//Initialize connections to servers
 	queue.Init()
+	//Start sending alerts to the notification sinks
+	notify.Init()
+	//Start storing the event history
+	history.Start()
 	//Start recovery
 	recovery.Recover()
+	//Start keeping the replicas of the edge replica sets. Before the auto deploy logic, which applies its own sets
+	replicaset.Init()
 	//Start auto deploy logic
 	go auto.AutoMain()
 	//Initialize the API
 	api.Init()
+	//Start the REST management API
+	if vars.IsRestApiEnable() {
+		rest.Init()
+	}
+	//Serve Prometheus metrics of the controller
+	if vars.IsMetricsEnable() {
+		metrics.Init()
+	}
+	//Start reconciling edge workloads against the manifest
+	if vars.GetManifestPath() != "" {
+		go reconcile.Start()
+	}
+	//Start evaluating alerting rules on edge metrics
+	if len(vars.GetAlertRules()) > 0 {
+		go rules.Start()
+	}
+	//Start scaling cloud deployments from their metrics
+	if len(vars.GetScalingPolicies()) > 0 {
+		go autoscale.Start()
+	}
 
 	//Wait for SIGTERM (Ctrl+C). And start the teardown procedure
 	log.Info.Println("Listener startup complete. Listening to response")
@@ -193,6 +227,5 @@ func disconnect() {
 	vars.SetTerminate()
 	//Gracefully disconnect
 	//Disconnecting from the servers should close down all of the RabbitMQ streams
-	_ = queue.Ch.Close()
-	_ = queue.Server.Close()
+	queue.Close()
 }
@@ -0,0 +1,80 @@
+package alert
+
+import (
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	Alert event bus
+	Every subscriber of an alert receives every event of that alert, in its own buffered channel.
+	Publishing never blocks. If a subscriber falls behind and its buffer is full, the event is dropped for that subscriber only, and counted.
+	Subscribers receive interface{}. Each alert in Channels.go only adds a typed Publish, so that the report type of an alert cannot be mixed up
+	Subscribers assert the report type of the alert, e.g. event.(types.ContainerCrashReport)
+*/
+
+//Buffer of subscribers that do not need a specific size
+const DefaultBuffer = 100
+
+type bus struct {
+	name        string
+	lock        sync.RWMutex
+	subscribers []*Subscription
+}
+
+//A subscriber of a bus. Close it to stop receiving events
+type Subscription struct {
+	name    string
+	bus     *bus
+	dropped uint64
+	events  chan interface{}
+}
+
+func newBus(name string) *bus {
+	return &bus{name: name}
+}
+
+//Receives every event from now on. The name identifies the subscriber in the metrics and logs
+func (b *bus) Subscribe(name string, buffer int) (<-chan interface{}, *Subscription) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	subscription := &Subscription{name: name, bus: b, events: make(chan interface{}, buffer)}
+	b.subscribers = append(b.subscribers, subscription)
+	return subscription.events, subscription
+}
+
+func (b *bus) publish(event interface{}) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, subscription := range b.subscribers {
+		//Never block on a full channel
+		select {
+		case subscription.events <- event:
+			continue
+		default:
+		}
+		atomic.AddUint64(&subscription.dropped, 1)
+		metrics.AlertsDropped.Inc(b.name, subscription.name)
+		log.Warn.Printf("Subscriber %s is falling behind. Dropped %s alert\n", subscription.name, b.name)
+	}
+}
+
+//Number of events dropped because the subscriber was falling behind
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+//Stops the subscription and closes its channel
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	for i, subscription := range s.bus.subscribers {
+		if subscription == s {
+			s.bus.subscribers = append(s.bus.subscribers[:i], s.bus.subscribers[i+1:]...)
+			close(s.events)
+			return
+		}
+	}
+}
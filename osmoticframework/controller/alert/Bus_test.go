@@ -0,0 +1,62 @@
+package alert
+
+import (
+	"osmoticframework/controller/types"
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	crashes := ContainerCrashBus{newBus("container-crash")}
+	first, firstSubscription := crashes.Subscribe("first", 2)
+	second, secondSubscription := crashes.Subscribe("second", 1)
+	crashes.Publish(types.ContainerCrashReport{ID: "a"})
+	//The second subscriber is full
+	crashes.Publish(types.ContainerCrashReport{ID: "b"})
+	if len(first) != 2 || len(second) != 1 {
+		t.Fatalf("Alerts not delivered to every subscriber. Got %d and %d, Want 2 and 1", len(first), len(second))
+	}
+	if report := (<-second).(types.ContainerCrashReport); report.ID != "a" {
+		t.Errorf("Alert incorrect. Got %s, Want a", report.ID)
+	}
+	if firstSubscription.Dropped() != 0 || secondSubscription.Dropped() != 1 {
+		t.Errorf("Dropped alerts incorrect. Got %d and %d, Want 0 and 1", firstSubscription.Dropped(), secondSubscription.Dropped())
+	}
+	secondSubscription.Close()
+	crashes.Publish(types.ContainerCrashReport{ID: "c"})
+	if _, open := <-second; open {
+		t.Errorf("Channel of a closed subscription is still open")
+	}
+	//Closing twice does nothing
+	secondSubscription.Close()
+	if len(first) != 2 {
+		t.Errorf("Alerts of the first subscriber incorrect. Got %d, Want 2", len(first))
+	}
+}
+
+func TestParseAlert(t *testing.T) {
+	crashes, subscription := ContainerCrash.Subscribe("test", 1)
+	defer subscription.Close()
+	report := map[string]interface{}{"AgentId": "agent", "ID": "container", "ExitCode": float64(137)}
+	//Agents can only report their own containers
+	ParseAlert(map[string]interface{}{"type": "containerCrash", "contents": report}, "other")
+	if len(crashes) != 0 {
+		t.Fatalf("Crash report of another agent accepted")
+	}
+	ParseAlert(map[string]interface{}{"type": "containerCrash", "contents": report}, "agent")
+	if len(crashes) != 1 {
+		t.Fatalf("Crash report not published")
+	}
+	if crash := (<-crashes).(types.ContainerCrashReport); crash.ID != "container" || crash.ExitCode != 137 {
+		t.Errorf("Crash report incorrect. Got %+v", crash)
+	}
+	events, eventSubscription := ContainerEvents.Subscribe("test", 1)
+	defer eventSubscription.Close()
+	lifecycle := map[string]interface{}{"AgentId": "agent", "ID": "container", "Action": "die", "Status": "oom-killed", "RestartCount": float64(3), "OOMKilled": true}
+	ParseAlert(map[string]interface{}{"type": "containerEvent", "contents": lifecycle}, "agent")
+	if len(events) != 1 {
+		t.Fatalf("Container event not published")
+	}
+	if event := (<-events).(types.ContainerEventReport); event.Status != "oom-killed" || event.RestartCount != 3 || !event.OOMKilled {
+		t.Errorf("Container event incorrect. Got %+v", event)
+	}
+}
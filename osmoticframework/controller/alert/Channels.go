@@ -4,15 +4,68 @@ import "osmoticframework/controller/types"
 
 //The alert system
 //The controller will fire a notification (A message to a channel) in the event any critical events occurred
+//Each alert is a bus. Publish to raise an alert. Subscribe to receive every alert from then on. See Bus.go
 
 // AgentDisconnect Returns the agent ID that has disconnected.
-var AgentDisconnect = make(chan types.OfflineAgent)
+var AgentDisconnect = AgentDisconnectBus{newBus("agent-disconnect")}
+
+// AgentConnect Returns the agent ID that has registered or rejoined. This is not an alert, but lets workloads be placed on new agents
+var AgentConnect = AgentConnectBus{newBus("agent-connect")}
 
 // DatabaseErrors Database errors
-var DatabaseErrors = make(chan types.DatabaseErrorReport)
+var DatabaseErrors = DatabaseErrorBus{newBus("database-error")}
 
 // ContainerCrash Container crash
-var ContainerCrash = make(chan types.ContainerCrashReport)
+var ContainerCrash = ContainerCrashBus{newBus("container-crash")}
+
+// ContainerEvents Lifecycle events of containers on agents. These are not alerts, but keep the container status up to date
+var ContainerEvents = ContainerEventBus{newBus("container-event")}
 
 // PerformanceIssues Performace issues
-var PerformanceIssues = make(chan types.PerformanceReport)
+var PerformanceIssues = PerformanceBus{newBus("performance")}
+
+// PodStatus Kubernetes pod transitions. These are not alerts, but are kept in the event history
+var PodStatus = PodStatusBus{newBus("pod-status")}
+
+// DeploymentScaling Replica changes made by the autoscaler. These are not alerts, but are kept in the event history
+var DeploymentScaling = DeploymentScalingBus{newBus("deployment-scaling")}
+
+//Subscribers receive types.OfflineAgent
+type AgentDisconnectBus struct{ *bus }
+
+func (b AgentDisconnectBus) Publish(agent types.OfflineAgent) { b.publish(agent) }
+
+//Subscribers receive the agent ID as a string
+type AgentConnectBus struct{ *bus }
+
+func (b AgentConnectBus) Publish(agentId string) { b.publish(agentId) }
+
+//Subscribers receive types.DatabaseErrorReport
+type DatabaseErrorBus struct{ *bus }
+
+func (b DatabaseErrorBus) Publish(report types.DatabaseErrorReport) { b.publish(report) }
+
+//Subscribers receive types.ContainerCrashReport
+type ContainerCrashBus struct{ *bus }
+
+func (b ContainerCrashBus) Publish(report types.ContainerCrashReport) { b.publish(report) }
+
+//Subscribers receive types.ContainerEventReport
+type ContainerEventBus struct{ *bus }
+
+func (b ContainerEventBus) Publish(report types.ContainerEventReport) { b.publish(report) }
+
+//Subscribers receive types.PerformanceReport
+type PerformanceBus struct{ *bus }
+
+func (b PerformanceBus) Publish(report types.PerformanceReport) { b.publish(report) }
+
+//Subscribers receive types.PodStatusReport
+type PodStatusBus struct{ *bus }
+
+func (b PodStatusBus) Publish(report types.PodStatusReport) { b.publish(report) }
+
+//Subscribers receive types.ScalingReport
+type DeploymentScalingBus struct{ *bus }
+
+func (b DeploymentScalingBus) Publish(report types.ScalingReport) { b.publish(report) }
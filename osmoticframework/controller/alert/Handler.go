@@ -3,15 +3,17 @@ package alert
 import (
 	"github.com/mitchellh/mapstructure"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
 	"osmoticframework/controller/types"
 )
 
-func ParseAlert(jsonMsg map[string]interface{}) {
+//agentId is the agent that signed the message. Agents can only report their own containers
+func ParseAlert(jsonMsg map[string]interface{}, agentId string) {
 	if jsonMsg["type"] == nil {
 		return
 	}
 	switch jsonMsg["type"] {
-	case ContainerCrash:
+	case string(types.AlertContainerCrash):
 		var crashReport types.ContainerCrashReport
 		err := mapstructure.Decode(jsonMsg["contents"], &crashReport)
 		if err != nil {
@@ -20,7 +22,25 @@ func ParseAlert(jsonMsg map[string]interface{}) {
 			//Ignore if cannot decode
 			return
 		}
-		ContainerCrash <- crashReport
+		if crashReport.AgentId != agentId {
+			log.Warn.Printf("Agent %s sent a crash report for agent %s. Ignoring\n", agentId, crashReport.AgentId)
+			return
+		}
+		metrics.Alerts.Inc("container-crash")
+		ContainerCrash.Publish(crashReport)
+	case string(types.AlertContainerEvent):
+		var event types.ContainerEventReport
+		err := mapstructure.Decode(jsonMsg["contents"], &event)
+		if err != nil {
+			log.Error.Println("Cannot decode container event")
+			log.Error.Println(err)
+			return
+		}
+		if event.AgentId != agentId {
+			log.Warn.Printf("Agent %s sent a container event for agent %s. Ignoring\n", agentId, event.AgentId)
+			return
+		}
+		ContainerEvents.Publish(event)
 	default:
 		return
 	}
@@ -11,7 +11,9 @@ import (
 	"osmoticframework/controller/api/impl/request/monitor"
 	"osmoticframework/controller/auto"
 	"osmoticframework/controller/database"
+	"osmoticframework/controller/failover"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
 	"osmoticframework/controller/queue"
 	"osmoticframework/controller/types"
 	"osmoticframework/controller/vars"
@@ -26,77 +28,105 @@ var alertQueue amqp.Queue
 Sets up the response queue and listens to all agent respond.
 The response messages are in separate queues to prevent clashing with request messages
 This function must be run at last in the main thread. If you need to run something else alongside the listener, please use the goroutine in Controller.go
+When the connection to RabbitMQ is lost, the queues and consumers are declared again after reconnecting.
 */
 func Init() {
+	err := startConsumers()
+	if err != nil {
+		log.Fatal.Panicln(err)
+	}
+	queue.OnReconnect(reconnect)
+	registerMetrics()
+	startRoutines()
+}
+
+//Runs after the controller reconnects to RabbitMQ
+func reconnect() {
+	//Agents could not send heartbeats while the controller was disconnected. Give them time to report back
+	vars.Agents.Range(func(agentId, _agent interface{}) bool {
+		agent := _agent.(types.Agent)
+		agent.LastAlive = time.Now().Unix()
+		vars.Agents.Store(agentId, agent)
+		//Queues of agents are not durable. They are gone if RabbitMQ restarted
+		queue.SetupAgentQueue(agentId.(string))
+		return true
+	})
+	err := startConsumers()
+	if err != nil {
+		log.Error.Println(err)
+	}
+	request.ResumeSubscriptions()
+	request.ResumeLogStreams()
+}
+
+//Declares the controller queues and starts listening to them
+func startConsumers() error {
 	var err error
 	//Queue and consumer declaration
 	responseQueue, err = queue.DeclareControllerQueue("response", true)
 	if err != nil {
-		log.Fatal.Println("Failed declaring queue response")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed declaring queue response")
+		return err
 	}
 	registerQueue, err = queue.DeclareQueue("register", true)
 	if err != nil {
-		log.Fatal.Println("Failed declaring queue register")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed declaring queue register")
+		return err
 	}
 	pongQueue, err := queue.DeclareControllerQueue("pong", true)
 	if err != nil {
-		log.Fatal.Println("Failed declaring queue pong")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed declaring queue pong")
+		return err
 	}
 	alertQueue, err = queue.DeclareControllerQueue("alert", true)
 	if err != nil {
-		log.Fatal.Println("Failed declaring queue alert")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed declaring queue alert")
+		return err
 	}
 	customInQueue, err := queue.DeclareControllerQueue("custom-in", true)
 	if err != nil {
-		log.Fatal.Println("Failed declaring queue custom-in")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed declaring queue custom-in")
+		return err
 	}
 
 	responseStream, err := queue.NewConsumer(responseQueue.Name)
 	if err != nil {
-		log.Fatal.Println("Failed creating response consumer")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed creating response consumer")
+		return err
 	}
 
 	regStream, err := queue.NewConsumer(registerQueue.Name)
 	if err != nil {
-		log.Fatal.Println("Failed creating register consumer")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed creating register consumer")
+		return err
 	}
 
 	pongStream, err := queue.NewConsumer(pongQueue.Name)
 	if err != nil {
-		log.Fatal.Println("Failed creating pong consumer")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed creating pong consumer")
+		return err
 	}
 
 	alertStream, err := queue.NewConsumer(alertQueue.Name)
 	if err != nil {
-		log.Fatal.Println("Failed creating alert consumer")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed creating alert consumer")
+		return err
 	}
 
 	customInStream, err := queue.NewConsumer(customInQueue.Name)
 	if err != nil {
-		log.Fatal.Println("Failed creating event consumer")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed creating event consumer")
+		return err
 	}
 
-	startRoutines(responseStream, regStream, pongStream, alertStream, customInStream)
+	consume(responseStream, regStream, pongStream, alertStream, customInStream)
+	return nil
 }
 
-//Go routines for the API
-//Channel stream handlers must not jump out of the loop as the controller will stop receiving messages
-func startRoutines(responseStream, regStream, pongStream, alertStream, customInStream <-chan amqp.Delivery) {
+//Stream handlers. Each stream ends when the connection to RabbitMQ is lost, and is consumed again after reconnecting
+func consume(responseStream, regStream, pongStream, alertStream, customInStream <-chan amqp.Delivery) {
 	//Register listener
 	go func() {
-		//Kick start the registration thread
-		//This must run under a go function so that the controller can stay connected to the channel
-		go func() { callback.RegisterThread() }()
 		for message := range regStream {
 			jsonMsg := deserialize(message.Body)
 			//fmt.Println(string(message.Body))
@@ -113,17 +143,24 @@ func startRoutines(responseStream, regStream, pongStream, alertStream, customInS
 					log.Error.Println(err)
 					continue
 				}
+				//Rejoining agents prove their identity with the signature
+				if currentRequest.AgentId != "" {
+					signer, err := queue.Verify(registerQueue.Name, message)
+					currentRequest.Authenticated = err == nil && signer == currentRequest.AgentId
+				}
 				callback.RegisterQueue <- currentRequest
 			}
 		}
-		if !vars.IsTerminate() {
-			log.Fatal.Panicln("Registration stream ended. This is not supposed to happen!")
-		}
+		streamEnded("Registration")
 	}()
 
 	//API response
 	go func() {
 		for message := range responseStream {
+			agentId, ok := verified(responseQueue.Name, message)
+			if !ok {
+				continue
+			}
 			jsonMsg := deserialize(message.Body)
 			//fmt.Println(string(message.Body))
 			if jsonMsg != nil {
@@ -132,76 +169,144 @@ func startRoutines(responseStream, regStream, pongStream, alertStream, customInS
 					//No API specified. Ignore
 					continue
 				}
-				_ = message.Ack(true)
 				switch api {
 				//This must run under a go function so that the controller can stay connected to the channel
 				case "deploy":
-					go func() { callback.ParseDeploy(jsonMsg) }()
+					go func() { callback.ParseDeploy(jsonMsg, agentId) }()
 				case "monitor":
-					go func() { callback.ParseMonitor(jsonMsg) }()
+					go func() { callback.ParseMonitor(jsonMsg, agentId) }()
 				default:
 					log.Error.Println("Agent sent unknown response. Ignoring")
 				}
 			}
 		}
-		if !vars.IsTerminate() {
-			log.Fatal.Panicln("Response stream ended. This is not supposed to happen!")
-		}
+		streamEnded("Response")
 	}()
 
 	//Keep alive listener
 	go func() {
-		go func() {
-			//Pause for 30 seconds before first alive check
-			var seq int64 = 0
-			for {
-				message, _ := json.Marshal(map[string]interface{}{
-					"ping": time.Now().UnixMilli(),
-					"seq":  seq,
-				})
-				seq++
-				err := queue.Ch.Publish(
-					"",
-					"ping",
-					false,
-					false,
-					amqp.Publishing{
-						ContentType: "application/json",
-						Body:        message,
-					})
-				if err != nil {
-					log.Error.Println("Failed sending ping")
-					log.Error.Println(err)
-				}
-				deadAgents := make(map[string]types.Agent)
-				vars.Agents.Range(func(agentId, agent interface{}) bool {
-					lastAlive := time.Unix(agent.(types.Agent).LastAlive, 0)
-					//An agent is considered dead if not connected to controller for more than 30 seconds
-					if math.Abs(time.Now().Sub(lastAlive).Seconds()) >= 30 {
-						deadAgents[agentId.(string)] = agent.(types.Agent)
-					}
-					return true
-				})
-				for agentId, agent := range deadAgents {
-					log.Error.Printf("Agent %s has disconnected\n", agentId)
-					//Push alert to channel
-					alert.AgentDisconnect <- types.OfflineAgent{ID: agentId, Agent: agent}
-					//Delete the agent from memory and database
-					database.Unregister(agentId)
-				}
-				time.Sleep(time.Second * 5)
-			}
-		}()
 		for message := range pongStream {
+			agentId, ok := verified("pong", message)
+			if !ok {
+				continue
+			}
 			jsonMsg := deserialize(message.Body)
 			if jsonMsg != nil {
-				_ = message.Ack(true)
 				//Update alive status in another thread
-				go callback.ProcessPing(jsonMsg)
+				go callback.ProcessPing(jsonMsg, agentId)
+			}
+		}
+		streamEnded("Ping")
+	}()
+
+	//Alert notifications from agents
+	go func() {
+		for message := range alertStream {
+			agentId, ok := verified(alertQueue.Name, message)
+			if !ok {
+				continue
+			}
+			jsonMsg := deserialize(message.Body)
+			if jsonMsg == nil {
+				continue
+			}
+			alert.ParseAlert(jsonMsg, agentId)
+		}
+		streamEnded("Alert")
+	}()
+
+	//Event stream. For trigger based events for the controller
+	//There are cases where certain containers needs to be deployed due to other conditions
+	//Those containers can contact the controller via this queue
+	go func() {
+		for message := range customInStream {
+			auto.Events <- string(message.Body)
+			_ = message.Ack(true)
+		}
+		streamEnded("Event")
+	}()
+}
+
+//Acknowledges a message from an agent and checks its signature. Unsigned, forged and replayed messages are dropped
+//Returns the ID of the agent that sent the message
+func verified(queueName string, message amqp.Delivery) (string, bool) {
+	_ = message.Ack(false)
+	agentId, err := queue.Verify(queueName, message)
+	if err != nil {
+		log.Warn.Printf("Dropped message on queue %s from agent %q: %s\n", queueName, agentId, err)
+		return "", false
+	}
+	return agentId, true
+}
+
+//Logs the end of a stream. Streams end when the connection is lost, or when the controller shuts down
+func streamEnded(name string) {
+	if !vars.IsTerminate() {
+		log.Warn.Println(name + " stream ended. Waiting for the connection to RabbitMQ to recover")
+	}
+}
+
+//Go routines for the API that are not bound to the RabbitMQ connection
+func startRoutines() {
+	//Kick start the registration thread
+	//This must run under a go function so that the controller can stay connected to the channel
+	go func() { callback.RegisterThread() }()
+
+	//Samples of metric subscriptions
+	go func() {
+		for message := range request.SampleQueue {
+			jsonMsg := deserialize(message.Body)
+			if jsonMsg != nil {
+				callback.ParseSample(message.Subscription, jsonMsg)
 			}
 		}
-		if !vars.IsTerminate() {
-			log.Fatal.Panicln("Ping stream ended. This is not supposed to happen!")
+	}()
+
+	//Heartbeat
+	go func() {
+		//Pause for 30 seconds before first alive check
+		var seq int64 = 0
+		//Agents cancel their subscriptions when the boot time changes, as a restarted controller does not know about them
+		boot := time.Now().UnixMilli()
+		for {
+			message, _ := json.Marshal(map[string]interface{}{
+				"ping": time.Now().UnixMilli(),
+				"seq":  seq,
+				"boot": boot,
+			})
+			seq++
+			err := queue.Publish("ping", message)
+			if err != nil {
+				log.Error.Println("Failed sending ping")
+				log.Error.Println(err)
+			}
+			//Agents cannot reply while the controller is disconnected
+			if !queue.IsConnected() {
+				time.Sleep(time.Second * 5)
+				continue
+			}
+			deadAgents := make(map[string]types.Agent)
+			vars.Agents.Range(func(agentId, agent interface{}) bool {
+				lastAlive := time.Unix(agent.(types.Agent).LastAlive, 0)
+				//An agent is considered dead if not connected to controller for more than 30 seconds
+				if math.Abs(time.Now().Sub(lastAlive).Seconds()) >= 30 {
+					deadAgents[agentId.(string)] = agent.(types.Agent)
+				}
+				return true
+			})
+			for agentId, agent := range deadAgents {
+				log.Error.Printf("Agent %s has disconnected\n", agentId)
+				//Move the agent's containers to other agents after the grace period
+				failover.AgentLost(agentId)
+				request.EndSubscriptions(agentId)
+				request.EndLogStreams(agentId)
+				//Push alert to channel
+				metrics.Alerts.Inc("agent-disconnect")
+				alert.AgentDisconnect.Publish(types.OfflineAgent{ID: agentId, Agent: agent})
+				//Delete the agent from memory and database
+				database.Unregister(agentId)
+			}
+			time.Sleep(time.Second * 5)
 		}
 	}()
 
@@ -216,20 +321,22 @@ func startRoutines(responseStream, regStream, pongStream, alertStream, customInS
 				diff := time.Now().Sub(currentRequest.Time)
 				if diff.Seconds() >= currentRequest.Timeout && !currentRequest.Ack {
 					log.Error.Println("Deploy request " + requestId.(string) + " timeout")
+					metrics.RequestTimeouts.Inc("deploy")
 					callback.CallbackError(requestId.(string), errors.New("timeout"))
 					request.DeployRequests.Delete(requestId)
 				}
-				return false
+				return true
 			})
 			request.MonitorRequests.Range(func(requestId, value interface{}) bool {
 				currentRequest := value.(request.ImplRequestTask)
 				diff := time.Now().Sub(currentRequest.Time)
 				if diff.Seconds() >= currentRequest.Timeout && !currentRequest.Ack {
 					log.Error.Println("Monitor request " + requestId.(string) + " timeout")
+					metrics.RequestTimeouts.Inc("monitor")
 					callback.CallbackError(requestId.(string), errors.New("timeout"))
 					request.MonitorRequests.Delete(requestId)
 				}
-				return false
+				return true
 			})
 			if vars.IsTerminate() {
 				break
@@ -237,31 +344,6 @@ func startRoutines(responseStream, regStream, pongStream, alertStream, customInS
 		}
 	}()
 
-	//Alert notifications from agents
-	go func() {
-		for message := range alertStream {
-			jsonMsg := deserialize(message.Body)
-			if jsonMsg == nil {
-				continue
-			}
-			_ = message.Ack(true)
-			alert.ParseAlert(jsonMsg)
-		}
-		if !vars.IsTerminate() {
-			log.Fatal.Panicln("Alert stream ended. This is not supposed to happen!")
-		}
-	}()
-
-	//Event stream. For trigger based events for the controller
-	//There are cases where certain containers needs to be deployed due to other conditions
-	//Those containers can contact the controller via this queue
-	go func() {
-		for message := range customInStream {
-			auto.Events <- string(message.Body)
-			_ = message.Ack(true)
-		}
-	}()
-
 	//Kubernetes event watcher
 	//Only monitor specific resources
 	//If we simply try to get all events in the cluster. Kubernetes will trim events which means we lose information.
@@ -273,9 +355,20 @@ func startRoutines(responseStream, regStream, pongStream, alertStream, customInS
 		log.Warn.Println("Monitoring functions on cloud is disabled")
 	}
 
+	//Keeps the status of the containers in the database up to date
+	containerEvents, _ := alert.ContainerEvents.Subscribe("database", alert.DefaultBuffer)
+	go func() {
+		for _event := range containerEvents {
+			event := _event.(types.ContainerEventReport)
+			callback.ContainerEvent(event)
+		}
+	}()
 	//Database error handler
+	databaseErrors, _ := alert.DatabaseErrors.Subscribe("log", alert.DefaultBuffer)
 	go func() {
-		for err := range alert.DatabaseErrors {
+		for _err := range databaseErrors {
+			err := _err.(types.DatabaseErrorReport)
+			metrics.DatabaseErrors.Inc()
 			alert.DBErrorHandler(err)
 		}
 	}()
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/metrics"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"sync"
+)
+
+//Gauges of the current state of the controller. They are read when Prometheus scrapes the controller
+func registerMetrics() {
+	metrics.NewGaugeFunc("osmotic_agents_registered", "Agents registered to the controller.", nil, func() []metrics.Sample {
+		count := 0
+		vars.Agents.Range(func(_, _ interface{}) bool {
+			count++
+			return true
+		})
+		return []metrics.Sample{{Value: float64(count)}}
+	})
+	metrics.NewGaugeFunc("osmotic_agent_ping_latency_milliseconds", "Latency of the last ping of each agent.", []string{"agent"}, func() []metrics.Sample {
+		samples := make([]metrics.Sample, 0)
+		vars.Agents.Range(func(agentId, agent interface{}) bool {
+			samples = append(samples, metrics.Sample{
+				Labels: metrics.Labels{agentId.(string)},
+				Value:  float64(agent.(types.Agent).Latency),
+			})
+			return true
+		})
+		return samples
+	})
+	metrics.NewGaugeFunc("osmotic_requests_in_flight", "Requests to agents waiting for a result.", []string{"api", "acknowledged"}, func() []metrics.Sample {
+		return append(inFlight("deploy", &request.DeployRequests), inFlight("monitor", &request.MonitorRequests)...)
+	})
+}
+
+//Counts the requests waiting for a result, by whether the agent has acknowledged them
+func inFlight(api string, requests *sync.Map) []metrics.Sample {
+	var acknowledged, waiting float64
+	requests.Range(func(_, requestTask interface{}) bool {
+		if requestTask.(request.ImplRequestTask).Ack {
+			acknowledged++
+		} else {
+			waiting++
+		}
+		return true
+	})
+	return []metrics.Sample{
+		{Labels: metrics.Labels{api, "true"}, Value: acknowledged},
+		{Labels: metrics.Labels{api, "false"}, Value: waiting},
+	}
+}
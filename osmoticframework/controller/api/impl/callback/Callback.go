@@ -2,13 +2,21 @@ package callback
 
 import (
 	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/metrics"
+	"osmoticframework/controller/queue"
+	"time"
 )
 
 //These functions return API calls to the result channel.
+//The request is finished afterwards, and removed from memory.
 
 func CallbackError(requestId string, err error) {
-	_reply, _ := request.DeployTaskList.Load(requestId)
-	reply := _reply.(request.RequestTask)
+	queue.Done(requestId)
+	defer finish(requestId, request.Error)
+	reply, ok := loadTask(requestId)
+	if !ok {
+		return
+	}
 	reply.Result <- request.Result{
 		ResultType: request.Error,
 		Content:    err,
@@ -16,10 +24,41 @@ func CallbackError(requestId string, err error) {
 }
 
 func CallbackOk(requestId string, content interface{}) {
-	_reply, _ := request.DeployTaskList.Load(requestId)
-	reply := _reply.(request.RequestTask)
+	queue.Done(requestId)
+	defer finish(requestId, request.Ok)
+	reply, ok := loadTask(requestId)
+	if !ok {
+		return
+	}
 	reply.Result <- request.Result{
 		ResultType: request.Ok,
 		Content:    content,
 	}
 }
+
+//Deploy and monitor requests are stored in separate task lists
+func loadTask(requestId string) (request.RequestTask, bool) {
+	if _reply, ok := request.DeployTaskList.Load(requestId); ok {
+		return _reply.(request.RequestTask), true
+	}
+	if _reply, ok := request.MonitorTaskList.Load(requestId); ok {
+		return _reply.(request.RequestTask), true
+	}
+	return request.RequestTask{}, false
+}
+
+//Records the latency of a request and removes it from memory
+func finish(requestId string, result request.ResultType) {
+	_requestTask, ok := request.DeployRequests.Load(requestId)
+	if !ok {
+		_requestTask, ok = request.MonitorRequests.Load(requestId)
+	}
+	if ok {
+		requestTask := _requestTask.(request.ImplRequestTask)
+		metrics.RequestDuration.Observe(time.Since(requestTask.Time).Seconds(), requestTask.API, requestTask.Command, string(result))
+	}
+	request.DeployRequests.Delete(requestId)
+	request.MonitorRequests.Delete(requestId)
+	request.DeployTaskList.Delete(requestId)
+	request.MonitorTaskList.Delete(requestId)
+}
@@ -0,0 +1,44 @@
+package callback
+
+import (
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/database"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+)
+
+//How a container event changes the row of the container in the database
+type rowChange int
+
+const (
+	keepRow rowChange = iota
+	removeRow
+	setStatus
+)
+
+//Keeps the status of the containers in the database up to date
+func ContainerEvent(event types.ContainerEventReport) {
+	switch containerRowChange(event) {
+	case removeRow:
+		if _, ok := vars.Agents.Load(event.AgentId); ok {
+			database.RemoveContainer(event.AgentId, event.ID)
+		}
+	case setStatus:
+		_ = database.SetContainerStatus(event.AgentId, event.ID, event.Status, event.RestartCount)
+	}
+}
+
+func containerRowChange(event types.ContainerEventReport) rowChange {
+	if event.Action == "destroy" {
+		//The row of an updated container is moved to the new container by the update callback, which may arrive after this event
+		if request.Updating(event.AgentId, event.ID) {
+			return keepRow
+		}
+		//Removed containers are gone for good. Drop their rows instead of keeping them around
+		return removeRow
+	}
+	if event.Status == "" {
+		return keepRow
+	}
+	return setStatus
+}
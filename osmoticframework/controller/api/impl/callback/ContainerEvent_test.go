@@ -0,0 +1,38 @@
+package callback
+
+import (
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/types"
+	"testing"
+)
+
+func TestContainerRowChange(t *testing.T) {
+	destroy := types.ContainerEventReport{AgentId: "agent", ID: "old", Action: "destroy", Status: "removed"}
+	//The agent removes the old container before it replies to the update
+	request.DeployRequests.Store("update", request.ImplRequestTask{
+		AgentId: "agent",
+		API:     "deploy",
+		Command: "update",
+		Args:    map[string]string{"oldContainerId": "old"},
+	})
+	if change := containerRowChange(destroy); change != keepRow {
+		t.Errorf("Destroy during update incorrect. Got %v, Want %v", change, keepRow)
+	}
+	other := types.ContainerEventReport{AgentId: "other", ID: "old", Action: "destroy", Status: "removed"}
+	if change := containerRowChange(other); change != removeRow {
+		t.Errorf("Destroy on another agent incorrect. Got %v, Want %v", change, removeRow)
+	}
+	//The update callback is done with the request
+	request.DeployRequests.Delete("update")
+	if change := containerRowChange(destroy); change != removeRow {
+		t.Errorf("Destroy incorrect. Got %v, Want %v", change, removeRow)
+	}
+	stop := types.ContainerEventReport{AgentId: "agent", ID: "old", Action: "stop", Status: "stopped"}
+	if change := containerRowChange(stop); change != setStatus {
+		t.Errorf("Stop incorrect. Got %v, Want %v", change, setStatus)
+	}
+	health := types.ContainerEventReport{AgentId: "agent", ID: "old", Action: "health_status", Health: "healthy"}
+	if change := containerRowChange(health); change != keepRow {
+		t.Errorf("Health status incorrect. Got %v, Want %v", change, keepRow)
+	}
+}
@@ -1,17 +1,21 @@
 package callback
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/mitchellh/mapstructure"
 	"osmoticframework/controller/api/impl/request"
 	"osmoticframework/controller/database"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/queue"
 	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
 )
 
 //Reads the response message from the agent.
 //See README.md for the message structure
-func ParseDeploy(message map[string]interface{}) {
+//agentId is the agent that signed the message. Agents can only respond to their own requests
+func ParseDeploy(message map[string]interface{}, agentId string) {
 	requestId, ok := message["requestId"].(string)
 	if !ok {
 		//Cannot figure out what the request is. Ignore completely.
@@ -27,13 +31,16 @@ func ParseDeploy(message map[string]interface{}) {
 	//The message itself only contains the request ID. It does not have any info on what the request to
 	//Here we refer the message request ID to the request ID stored in the controller, where it contains information such as the api, command, arguments, etc.
 	requestTask := _requestTask.(request.ImplRequestTask)
+	if requestTask.AgentId != agentId {
+		log.Warn.Printf("%s (req: %s) >> Agent responded to a request for agent %s. Ignoring\n", agentId, requestId, requestTask.AgentId)
+		return
+	}
 	command := requestTask.Command
 	status, ok := message["status"].(string)
 	if !ok {
 		//No response status. Ignore
 		return
 	}
-	agentId := requestTask.AgentId
 	switch status {
 	//If the agent returns an ack.
 	case "ack":
@@ -49,6 +56,8 @@ func ParseDeploy(message map[string]interface{}) {
 			Timeout: requestTask.Timeout,
 		}
 		request.DeployRequests.Store(requestId, ongoingRequest)
+		//The agent has the request. It is not sent again if the controller reconnects
+		queue.Done(requestId)
 	//The agent completes the command and returns ok
 	case "ok":
 		switch command {
@@ -61,19 +70,26 @@ func ParseDeploy(message map[string]interface{}) {
 				CallbackError(requestId, err)
 				return
 			}
-			database.AddContainer(agentId, containerId)
+			spec := loadSpec(requestId, agentId)
+			database.AddContainer(agentId, containerId, spec)
+			if spec != nil {
+				vars.ContainerSpecs.Store(containerId, *spec)
+			}
 			CallbackOk(requestId, containerId)
 			log.Info.Printf("%s (req: %s) >> Container %s started\n", agentId, requestId, containerId)
 		case "stop":
 			//We have the container ID stored in memory. No need to parse the message
 			containerId := requestTask.Args.(map[string]string)["containerId"]
 			database.StopContainer(agentId, containerId)
+			//Stopped containers are not moved to other agents
+			vars.ContainerSpecs.Delete(containerId)
 			log.Info.Printf("%s (req: %s) >> Container %s stopped\n", agentId, requestId, containerId)
 			CallbackOk(requestId, nil)
 		case "delete":
 			//We have the container ID stored in memory. No need to parse the message
 			containerId := requestTask.Args.(map[string]string)["containerId"]
 			database.RemoveContainer(agentId, containerId)
+			vars.ContainerSpecs.Delete(containerId)
 			CallbackOk(requestId, nil)
 			log.Info.Printf("%s (req: %s) >> Container %s deleted\n", agentId, requestId, containerId)
 		case "update":
@@ -92,8 +108,17 @@ func ParseDeploy(message map[string]interface{}) {
 				CallbackError(requestId, err)
 				return
 			}
+			spec := loadSpec(requestId, agentId)
+			//The updated container keeps the origin of the old one
+			if old, ok := vars.ContainerSpecs.Load(containerId); ok && spec != nil {
+				spec.Origin = old.(types.ContainerSpec).Origin
+			}
 			//Update the database
-			database.UpdateContainer(agentId, containerId, newContainerId)
+			database.UpdateContainer(agentId, containerId, newContainerId, spec)
+			vars.ContainerSpecs.Delete(containerId)
+			if spec != nil {
+				vars.ContainerSpecs.Store(newContainerId, *spec)
+			}
 			CallbackOk(requestId, newContainerId)
 			log.Info.Printf("%s (req: %s) >> Container %s updated to %s\n", agentId, requestId, containerId, newContainerId)
 		case "list":
@@ -120,6 +145,32 @@ func ParseDeploy(message map[string]interface{}) {
 			}
 			CallbackOk(requestId, container)
 			log.Info.Printf("%s (req: %s) << Received container inspection\n", agentId, requestId)
+		case "logs":
+			//Lines are decoded with encoding/json, which parses the timestamps
+			var lines []types.LogLine
+			raw, _ := json.Marshal(message["lines"])
+			err := json.Unmarshal(raw, &lines)
+			if err != nil {
+				log.Error.Printf("%s (req: %s) >> Failed to decode container output\n", agentId, requestId)
+				log.Error.Println(err)
+				CallbackError(requestId, err)
+				return
+			}
+			CallbackOk(requestId, lines)
+			log.Info.Printf("%s (req: %s) >> Received %d lines of container output\n", agentId, requestId, len(lines))
+		case "unfollow":
+			CallbackOk(requestId, nil)
+		case "exec":
+			var result types.ExecResult
+			err := mapstructure.Decode(message["result"], &result)
+			if err != nil {
+				log.Error.Printf("%s (req: %s) >> Failed to decode exec result\n", agentId, requestId)
+				log.Error.Println(err)
+				CallbackError(requestId, err)
+				return
+			}
+			CallbackOk(requestId, result)
+			log.Info.Printf("%s (req: %s) >> Command exited with code %d\n", agentId, requestId, result.ExitCode)
 		case "prom":
 			containerId, ok := message["containerId"].(string)
 			if !ok {
@@ -153,3 +204,32 @@ func ParseDeploy(message map[string]interface{}) {
 		request.DeployRequests.Delete(requestId)
 	}
 }
+
+//Gets the arguments a container is deployed with. The arguments are stored in the request task of run and update requests
+//Returns nil if the request task is gone
+func loadSpec(requestId, agentId string) *types.ContainerSpec {
+	task, ok := loadTask(requestId)
+	if !ok {
+		return nil
+	}
+	args, ok := task.Args.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	deployArgs, ok := args["deployArgs"].(types.DeployArgs)
+	if !ok {
+		return nil
+	}
+	authInfo, _ := args["authInfo"].(types.AuthInfo)
+	origin, ok := args["origin"].(types.ContainerOrigin)
+	if !ok {
+		origin = types.OriginAPI
+	}
+	return &types.ContainerSpec{
+		AgentId:     agentId,
+		DeployArgs:  deployArgs,
+		AuthInfo:    authInfo,
+		RequestTime: task.Time,
+		Origin:      origin,
+	}
+}
@@ -1,17 +1,20 @@
 package callback
 
 import (
+	"encoding/json"
 	"errors"
-	"github.com/mitchellh/mapstructure"
 	"osmoticframework/controller/api/impl/request"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/queue"
 	"osmoticframework/controller/types/metric"
 	"strconv"
+	"strings"
 )
 
 //Reads the response message from the agent.
 //See README.md for the message structure
-func ParseMonitor(message map[string]interface{}) {
+//agentId is the agent that signed the message. Agents can only respond to their own requests
+func ParseMonitor(message map[string]interface{}, agentId string) {
 	requestId, ok := message["requestId"].(string)
 	if !ok {
 		//Cannot figure out request content. Ignore
@@ -23,6 +26,10 @@ func ParseMonitor(message map[string]interface{}) {
 		return
 	}
 	requestTask := _requestTask.(request.ImplRequestTask)
+	if requestTask.AgentId != agentId {
+		log.Warn.Printf("%s (req: %s) >> Agent responded to a request for agent %s. Ignoring\n", agentId, requestId, requestTask.AgentId)
+		return
+	}
 	status, ok := message["status"].(string)
 	if !ok {
 		//No status. Ignore
@@ -40,7 +47,14 @@ func ParseMonitor(message map[string]interface{}) {
 			Timeout: requestTask.Timeout,
 		}
 		request.MonitorRequests.Store(requestId, req)
+		//The agent has the request. It is not sent again if the controller reconnects
+		queue.Done(requestId)
 	case "ok":
+		//Subscriptions have no result
+		if requestTask.Command == "subscribe" || requestTask.Command == "unsubscribe" {
+			CallbackOk(requestId, nil)
+			return
+		}
 		//Metrics requests
 		//Deserialize metric
 		if message["metric"] == nil {
@@ -50,14 +64,18 @@ func ParseMonitor(message map[string]interface{}) {
 			CallbackError(requestId, err)
 			return
 		}
-		var promMetric metric.PromMetric
-		err := mapstructure.Decode(message["metric"], &promMetric)
+		promMetric, err := decodeMetric(message["metric"])
 		if err != nil {
 			log.Error.Println("Failed decoding reply in monitoring API")
 			log.Error.Println(err)
 			CallbackError(requestId, err)
 			return
 		}
+		//Range queries return matrices
+		if promMetric.Type == metric.MatrixType {
+			CallbackOk(requestId, parseSeries(requestTask.AgentId, requestTask.Command, promMetric))
+			return
+		}
 		CallbackOk(requestId, parseMetric(requestTask.AgentId, requestTask.Command, promMetric))
 	case "failed":
 		var err error
@@ -74,6 +92,52 @@ func ParseMonitor(message map[string]interface{}) {
 	}
 }
 
+//Decodes the metric sent by the agent. The data is decoded into []Matrix, []Vector or Scalar depending on the metric type
+//mapstructure cannot be used here. It leaves the data as a generic map and cannot decode timestamps
+func decodeMetric(raw interface{}) (metric.PromMetric, error) {
+	var promMetric metric.PromMetric
+	var envelope struct {
+		Type metric.MetricType `json:"type"`
+		Data json.RawMessage   `json:"data"`
+	}
+	bytes, err := json.Marshal(raw)
+	if err != nil {
+		return promMetric, err
+	}
+	err = json.Unmarshal(bytes, &envelope)
+	if err != nil {
+		return promMetric, err
+	}
+	promMetric.Type = envelope.Type
+	switch envelope.Type {
+	case metric.MatrixType:
+		data := make([]metric.Matrix, 0)
+		err = json.Unmarshal(envelope.Data, &data)
+		promMetric.Data = data
+	case metric.VectorType:
+		data := make([]metric.Vector, 0)
+		err = json.Unmarshal(envelope.Data, &data)
+		promMetric.Data = data
+	case metric.ScalarType:
+		var data metric.Scalar
+		err = json.Unmarshal(envelope.Data, &data)
+		promMetric.Data = data
+	default:
+		err = errors.New("unknown metric type " + string(envelope.Type))
+	}
+	return promMetric, err
+}
+
+//Returns the first vector of a metric. Used by queries that only return one value, such as the overall CPU utilization
+//An empty vector is returned if Prometheus has no data
+func firstVector(promMetric metric.PromMetric) metric.Vector {
+	vectors, ok := promMetric.Data.([]metric.Vector)
+	if !ok || len(vectors) == 0 {
+		return metric.Vector{Key: map[string]string{}, Scalar: metric.Scalar{Undefined: true}}
+	}
+	return vectors[0]
+}
+
 func parseMetric(agentId, command string, promMetric metric.PromMetric) interface{} {
 	switch command {
 	case "cpu_edge_avg":
@@ -90,8 +154,8 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.CpuContainerMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			cpuContainerMetric := metric.CpuContainerMetric{}
-			cpuContainerMetric.Container = m.Key["container"][8:]
-			cpuContainerMetric.Core, _ = strconv.Atoi(m.Key["cpu"][3:])
+			cpuContainerMetric.Container = seriesContainer(m.Key)
+			cpuContainerMetric.Core = seriesCore(m.Key)
 			cpuContainerMetric.Agent = agentId
 			cpuContainerMetric.Usage = m.Scalar.Value
 			ret = append(ret, cpuContainerMetric)
@@ -101,7 +165,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.CpuEdgeTimeMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			cpuEdgeTimeMetric := metric.CpuEdgeTimeMetric{}
-			cpuEdgeTimeMetric.Core, _ = strconv.Atoi(m.Key["cpu"][3:])
+			cpuEdgeTimeMetric.Core = seriesCore(m.Key)
 			cpuEdgeTimeMetric.Agent = agentId
 			cpuEdgeTimeMetric.Time = m.Scalar.Value
 			ret = append(ret, cpuEdgeTimeMetric)
@@ -110,35 +174,40 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 	case "cpu_utilization":
 		ret := metric.CpuEdgeOverallUsageMetric{}
 		ret.Agent = agentId
-		ret.Usage = promMetric.Data.(metric.Vector).Scalar.Value
+		ret.Usage = firstVector(promMetric).Scalar.Value
 		return ret
 	case "memory_container":
 		ret := metric.MemoryContainerMetric{}
-		ret.Container = promMetric.Data.(metric.Vector).Key["container"][8:]
+		ret.Container = seriesContainer(firstVector(promMetric).Key)
 		ret.Agent = agentId
-		ret.Usage = uint64(promMetric.Data.(metric.Vector).Scalar.Value)
+		ret.Usage = uint64(firstVector(promMetric).Scalar.Value)
 		return ret
 	case "memory_edge":
 		ret := metric.MemoryEdgeMetric{}
 		ret.Agent = agentId
-		ret.Usage = uint64(promMetric.Data.(metric.Vector).Scalar.Value)
+		ret.Usage = uint64(firstVector(promMetric).Scalar.Value)
 		return ret
 	case "memory_container_peak":
 		ret := metric.MemoryContainerMetric{}
-		ret.Container = promMetric.Data.(metric.Vector).Key["container"][8:]
+		ret.Container = seriesContainer(firstVector(promMetric).Key)
+		ret.Agent = agentId
+		ret.Usage = uint64(firstVector(promMetric).Scalar.Value)
+		return ret
+	case "memory_edge_total":
+		ret := metric.MemoryEdgeMetric{}
 		ret.Agent = agentId
-		ret.Usage = uint64(promMetric.Data.(metric.Vector).Scalar.Value)
+		ret.Usage = uint64(firstVector(promMetric).Scalar.Value)
 		return ret
 	case "memory_edge_peak":
 		ret := metric.MemoryEdgeMetric{}
 		ret.Agent = agentId
-		ret.Usage = uint64(promMetric.Data.(metric.Vector).Scalar.Value)
+		ret.Usage = uint64(firstVector(promMetric).Scalar.Value)
 		return ret
 	case "memory_container_limit_seconds":
 		ret := metric.MemoryContainerLimitSecondsMetric{}
-		ret.Container = promMetric.Data.(metric.Vector).Key["container"][8:]
+		ret.Container = seriesContainer(firstVector(promMetric).Key)
 		ret.Agent = agentId
-		ret.Time = uint64(promMetric.Data.(metric.Vector).Scalar.Value)
+		ret.Time = uint64(firstVector(promMetric).Scalar.Value)
 		return ret
 	case "io_edge_time":
 		ret := make([]metric.IOEdgeTimeMetric, 0)
@@ -154,7 +223,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.IOContainerTimeMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			ioContainerTimeMetric := metric.IOContainerTimeMetric{}
-			ioContainerTimeMetric.Container = m.Key["container"][8:]
+			ioContainerTimeMetric.Container = seriesContainer(m.Key)
 			ioContainerTimeMetric.Device = m.Key["device"]
 			ioContainerTimeMetric.Agent = agentId
 			ioContainerTimeMetric.Time = m.Scalar.Value
@@ -175,7 +244,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.IOContainerBytesMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			ioContainerBytesMetric := metric.IOContainerBytesMetric{}
-			ioContainerBytesMetric.Container = m.Key["container"][8:]
+			ioContainerBytesMetric.Container = seriesContainer(m.Key)
 			ioContainerBytesMetric.Device = m.Key["device"]
 			ioContainerBytesMetric.Agent = agentId
 			ioContainerBytesMetric.Bytes = uint64(m.Scalar.Value)
@@ -196,7 +265,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.IOContainerBytesMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			ioContainerBytesMetric := metric.IOContainerBytesMetric{}
-			ioContainerBytesMetric.Container = m.Key["container"][8:]
+			ioContainerBytesMetric.Container = seriesContainer(m.Key)
 			ioContainerBytesMetric.Device = m.Key["device"]
 			ioContainerBytesMetric.Agent = agentId
 			ioContainerBytesMetric.Bytes = uint64(m.Scalar.Value)
@@ -239,7 +308,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.NetworkContainerBytesMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			networkContainerBytesMetric := metric.NetworkContainerBytesMetric{}
-			networkContainerBytesMetric.Container = m.Key["container"][8:]
+			networkContainerBytesMetric.Container = seriesContainer(m.Key)
 			networkContainerBytesMetric.Device = m.Key["device"]
 			networkContainerBytesMetric.Agent = agentId
 			networkContainerBytesMetric.Bytes = uint64(m.Scalar.Value)
@@ -260,7 +329,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.NetworkContainerBytesMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			networkContainerBytesMetric := metric.NetworkContainerBytesMetric{}
-			networkContainerBytesMetric.Container = m.Key["container"][8:]
+			networkContainerBytesMetric.Container = seriesContainer(m.Key)
 			networkContainerBytesMetric.Device = m.Key["device"]
 			networkContainerBytesMetric.Agent = agentId
 			networkContainerBytesMetric.Bytes = uint64(m.Scalar.Value)
@@ -281,7 +350,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.NetworkContainerPacketsMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			networkContainerPacketsMetric := metric.NetworkContainerPacketsMetric{}
-			networkContainerPacketsMetric.Container = m.Key["container"][8:]
+			networkContainerPacketsMetric.Container = seriesContainer(m.Key)
 			networkContainerPacketsMetric.Device = m.Key["device"]
 			networkContainerPacketsMetric.Packets = uint64(m.Scalar.Value)
 			ret = append(ret, networkContainerPacketsMetric)
@@ -301,7 +370,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.NetworkContainerPacketsMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			networkContainerPacketsMetric := metric.NetworkContainerPacketsMetric{}
-			networkContainerPacketsMetric.Container = m.Key["container"][8:]
+			networkContainerPacketsMetric.Container = seriesContainer(m.Key)
 			networkContainerPacketsMetric.Device = m.Key["device"]
 			networkContainerPacketsMetric.Packets = uint64(m.Scalar.Value)
 			ret = append(ret, networkContainerPacketsMetric)
@@ -321,7 +390,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.NetworkContainerErrorsMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			networkContainerErrorsMetric := metric.NetworkContainerErrorsMetric{}
-			networkContainerErrorsMetric.Container = m.Key["container"][8:]
+			networkContainerErrorsMetric.Container = seriesContainer(m.Key)
 			networkContainerErrorsMetric.Device = m.Key["device"]
 			networkContainerErrorsMetric.Errors = uint64(m.Scalar.Value)
 			ret = append(ret, networkContainerErrorsMetric)
@@ -341,7 +410,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.NetworkContainerErrorsMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			networkContainerErrorsMetric := metric.NetworkContainerErrorsMetric{}
-			networkContainerErrorsMetric.Container = m.Key["container"][8:]
+			networkContainerErrorsMetric.Container = seriesContainer(m.Key)
 			networkContainerErrorsMetric.Device = m.Key["device"]
 			networkContainerErrorsMetric.Errors = uint64(m.Scalar.Value)
 			ret = append(ret, networkContainerErrorsMetric)
@@ -361,7 +430,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.NetworkContainerPacketsMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			networkContainerDroppedMetric := metric.NetworkContainerPacketsMetric{}
-			networkContainerDroppedMetric.Container = m.Key["container"][8:]
+			networkContainerDroppedMetric.Container = seriesContainer(m.Key)
 			networkContainerDroppedMetric.Device = m.Key["device"]
 			networkContainerDroppedMetric.Packets = uint64(m.Scalar.Value)
 			ret = append(ret, networkContainerDroppedMetric)
@@ -381,7 +450,7 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		ret := make([]metric.NetworkContainerPacketsMetric, 0)
 		for _, m := range promMetric.Data.([]metric.Vector) {
 			networkContainerDroppedMetric := metric.NetworkContainerPacketsMetric{}
-			networkContainerDroppedMetric.Container = m.Key["container"][8:]
+			networkContainerDroppedMetric.Container = seriesContainer(m.Key)
 			networkContainerDroppedMetric.Device = m.Key["device"]
 			networkContainerDroppedMetric.Packets = uint64(m.Scalar.Value)
 			ret = append(ret, networkContainerDroppedMetric)
@@ -402,3 +471,79 @@ func parseMetric(agentId, command string, promMetric metric.PromMetric) interfac
 		return promMetric
 	}
 }
+
+//Converts the result of a range query to series. The series are grouped the same way as the results of instant queries
+func parseSeries(agentId, command string, promMetric metric.PromMetric) interface{} {
+	matrices, ok := promMetric.Data.([]metric.Matrix)
+	if !ok {
+		return promMetric
+	}
+	switch command {
+	case "cpu_edge_avg", "cpu_time":
+		ret := make([]metric.CpuEdgeSeries, 0)
+		for _, m := range matrices {
+			ret = append(ret, metric.CpuEdgeSeries{Core: seriesCore(m.Key), Agent: agentId, Values: m.Values})
+		}
+		return ret
+	case "cpu_container_avg":
+		ret := make([]metric.CpuContainerSeries, 0)
+		for _, m := range matrices {
+			ret = append(ret, metric.CpuContainerSeries{Container: seriesContainer(m.Key), Core: seriesCore(m.Key), Agent: agentId, Values: m.Values})
+		}
+		return ret
+	case "cpu_utilization", "memory_edge", "memory_edge_total", "memory_edge_peak":
+		ret := make([]metric.EdgeSeries, 0)
+		for _, m := range matrices {
+			ret = append(ret, metric.EdgeSeries{Agent: agentId, Values: m.Values})
+		}
+		return ret
+	case "memory_container", "memory_container_peak", "memory_container_limit_seconds":
+		ret := make([]metric.ContainerSeries, 0)
+		for _, m := range matrices {
+			ret = append(ret, metric.ContainerSeries{Container: seriesContainer(m.Key), Agent: agentId, Values: m.Values})
+		}
+		return ret
+	case "io_edge_time", "io_edge_read", "io_edge_write",
+		"net_edge_rx_bytes", "net_edge_rx_packets", "net_edge_rx_dropped", "net_edge_rx_error",
+		"net_edge_tx_bytes", "net_edge_tx_packets", "net_edge_tx_dropped", "net_edge_tx_error":
+		ret := make([]metric.DeviceEdgeSeries, 0)
+		for _, m := range matrices {
+			ret = append(ret, metric.DeviceEdgeSeries{Agent: agentId, Device: m.Key["device"], Values: m.Values})
+		}
+		return ret
+	case "io_container_time", "io_container_read", "io_container_write",
+		"net_container_rx_bytes", "net_container_rx_packets", "net_container_rx_dropped", "net_container_rx_error",
+		"net_container_tx_bytes", "net_container_tx_packets", "net_container_tx_dropped", "net_container_tx_error":
+		ret := make([]metric.DeviceContainerSeries, 0)
+		for _, m := range matrices {
+			ret = append(ret, metric.DeviceContainerSeries{Container: seriesContainer(m.Key), Agent: agentId, Device: m.Key["device"], Values: m.Values})
+		}
+		return ret
+	case "io_filesystem_used", "io_filesystem_size":
+		ret := make([]metric.FilesystemSeries, 0)
+		for _, m := range matrices {
+			ret = append(ret, metric.FilesystemSeries{Agent: agentId, Device: m.Key["device"], MountPoint: m.Key["mountpoint"], Values: m.Values})
+		}
+		return ret
+	case "thermal":
+		ret := make([]metric.ThermalSeries, 0)
+		for _, m := range matrices {
+			ret = append(ret, metric.ThermalSeries{Agent: agentId, ZoneName: m.Key["type"], ZoneUUID: m.Key["zone"], Values: m.Values})
+		}
+		return ret
+	default:
+		return promMetric
+	}
+}
+
+//Node exporter labels cores as "0", cAdvisor as "cpu00"
+//These helpers are shared with instant queries. Missing labels give zero values instead of panicking
+func seriesCore(key map[string]string) int {
+	core, _ := strconv.Atoi(strings.TrimPrefix(key["cpu"], "cpu"))
+	return core
+}
+
+//cAdvisor labels containers by their cgroup, e.g. /docker/<container ID>
+func seriesContainer(key map[string]string) string {
+	return strings.TrimPrefix(key["id"], "/docker/")
+}
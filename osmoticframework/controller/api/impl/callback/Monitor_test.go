@@ -0,0 +1,66 @@
+package callback
+
+import (
+	"encoding/json"
+	"osmoticframework/controller/types/metric"
+	"testing"
+)
+
+func TestParseMetric(t *testing.T) {
+	//Metric as sent by the agent, after the AMQP message is deserialized
+	const message = `{"type": "vector", "data": [{"key": {"mountpoint": "/", "device": "sda1"}, "scalar": {"time": "2021-01-01T00:00:00Z", "value": 1024, "undefined": false}}]}`
+	var raw interface{}
+	if err := json.Unmarshal([]byte(message), &raw); err != nil {
+		t.Fatal(err)
+	}
+	promMetric, err := decodeMetric(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filesystems, ok := parseMetric("agent", "io_filesystem_used", promMetric).([]metric.IOFilesystemBytesMetric)
+	if !ok || len(filesystems) != 1 {
+		t.Fatalf("Filesystem metric incorrect. Got %#v", filesystems)
+	}
+	if filesystems[0].MountPoint != "/" || filesystems[0].Bytes != 1024 || filesystems[0].Agent != "agent" {
+		t.Errorf("Filesystem metric incorrect. Got %+v", filesystems[0])
+	}
+	memory := parseMetric("agent", "memory_edge_total", promMetric).(metric.MemoryEdgeMetric)
+	if memory.Usage != 1024 {
+		t.Errorf("Memory metric incorrect. Got %d, Want 1024", memory.Usage)
+	}
+	empty := metric.PromMetric{Type: metric.VectorType, Data: []metric.Vector{}}
+	utilization := parseMetric("agent", "cpu_utilization", empty).(metric.CpuEdgeOverallUsageMetric)
+	if utilization.Usage != 0 {
+		t.Errorf("CPU metric without data incorrect. Got %f, Want 0", utilization.Usage)
+	}
+	if _, err := decodeMetric(map[string]interface{}{"type": "unknown"}); err == nil {
+		t.Errorf("Unknown metric types should fail")
+	}
+}
+
+func TestParseSeries(t *testing.T) {
+	//Result of a range query as sent by the agent
+	const message = `{"type": "matrix", "data": [{"key": {"cpu": "cpu01", "id": "/docker/abc"}, "values": [{"time": "2021-01-01T00:00:00Z", "value": 0.5, "undefined": false}, {"time": "2021-01-01T00:00:15Z", "value": 0, "undefined": true}]}]}`
+	var raw interface{}
+	if err := json.Unmarshal([]byte(message), &raw); err != nil {
+		t.Fatal(err)
+	}
+	promMetric, err := decodeMetric(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, ok := parseSeries("agent", "cpu_container_avg", promMetric).([]metric.CpuContainerSeries)
+	if !ok || len(series) != 1 {
+		t.Fatalf("CPU series incorrect. Got %#v", series)
+	}
+	if series[0].Container != "abc" || series[0].Core != 1 || series[0].Agent != "agent" {
+		t.Errorf("CPU series incorrect. Got %+v", series[0])
+	}
+	if len(series[0].Values) != 2 || series[0].Values[0].Value != 0.5 || !series[0].Values[1].Undefined {
+		t.Errorf("CPU series values incorrect. Got %+v", series[0].Values)
+	}
+	memory, ok := parseSeries("agent", "memory_edge", promMetric).([]metric.EdgeSeries)
+	if !ok || len(memory) != 1 || len(memory[0].Values) != 2 {
+		t.Errorf("Memory series incorrect. Got %#v", memory)
+	}
+}
@@ -3,15 +3,17 @@ package callback
 import (
 	"osmoticframework/controller/alert"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
 	"osmoticframework/controller/types"
 	"osmoticframework/controller/vars"
 	"time"
 )
 
-func ProcessPing(message map[string]interface{}) {
+//signer is the agent that signed the message
+func ProcessPing(message map[string]interface{}, signer string) {
 	agentId, ok := message["agentId"].(string)
-	if !ok {
-		// Broken ping message?
+	if !ok || agentId != signer {
+		// Broken or forged ping message?
 		return
 	}
 	agent, ok := vars.Agents.Load(agentId)
@@ -19,33 +21,38 @@ func ProcessPing(message map[string]interface{}) {
 		// Disconnected agent still sending ping?
 		return
 	}
-	seq, ok := message["seq"].(int64)
+	// JSON numbers are always decoded as float64
+	_seq, ok := message["seq"].(float64)
 	if !ok {
 		// Broken ping message?
 		return
 	}
+	seq := int64(_seq)
 	// Newer ping arrived before older one. Ignore.
 	if seq < agent.(types.Agent).PingSeq {
 		return
 	}
 	// Pong contains the timestamp when the agent received the ping. Currently unused
-	_, ok = message["pong"].(int64)
+	_, ok = message["pong"].(float64)
 	if !ok {
 		return
 	}
-	latency, ok := message["latency"].(int64)
+	_latency, ok := message["latency"].(float64)
 	if !ok {
 		// Broken ping message?
 		return
 	}
+	latency := int64(_latency)
 	// Latency longer than 3 seconds
 	if latency > 3000 {
 		// Possible long latency
-		alert.PerformanceIssues <- types.PerformanceReport{
+		metrics.Alerts.Inc(string(types.PerformanceAlertTypeNetworkLatency))
+		alert.PerformanceIssues.Publish(types.PerformanceReport{
 			AgentId:   agentId,
 			CloudSide: false,
 			AlertType: types.PerformanceAlertTypeNetworkLatency,
-		}
+			Value:     float64(latency),
+		})
 		log.Warn.Printf("Agent %s has a high latency of %d ms\n", agentId, latency)
 	}
 	//Update the last alive in memory
@@ -57,5 +64,6 @@ func ProcessPing(message map[string]interface{}) {
 		Containers:    agent.(types.Agent).Containers,
 		LastAlive:     time.Now().Unix(),
 		PingSeq:       seq + 1,
+		Latency:       latency,
 	})
 }
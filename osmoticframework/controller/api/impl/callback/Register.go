@@ -3,12 +3,17 @@ package callback
 import (
 	"encoding/json"
 	"errors"
-	"github.com/streadway/amqp"
+	"osmoticframework/controller/alert"
 	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/auth"
 	"osmoticframework/controller/auto"
 	"osmoticframework/controller/database"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
 	"osmoticframework/controller/queue"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"time"
 )
 
 //Registration queue. All unprocessed registration requests are stored here.
@@ -16,6 +21,10 @@ var RegisterQueue = make(chan request.RegisterRequest)
 
 type RegisterMessageDirection string
 
+//Registration is rejected with these errors. Agents check for errRevoked, so the message must not change
+var errInvalidToken = errors.New("invalid registration token")
+var errRevoked = errors.New("agent revoked")
+
 var (
 	CONTROLLER RegisterMessageDirection = "controller"
 	AGENT      RegisterMessageDirection = "agent"
@@ -27,68 +36,204 @@ var (
 //If enabled -> send agent ID to agent
 //Due to the need to deploy Prometheus on ONLY ONE agent for each LAN. Registration must be done via a queue (in this case the requests are built up inside a channel)
 //Otherwise there will be concurrency problems where there are multiple agents deploying Prometheus, wasting resources.
+//Rejoining agents report the ID of their previous session. They keep their containers, except the ones the controller has no record of
+//If registration authentication is enabled, new agents must present a bootstrap token and rejoining agents must sign the request with their secret
+//Replies go to the reply queue of the agent, never to the shared register queue, as they may carry a secret
 
 func RegisterThread() {
 	//If there are no registration request in the queue, the thread simply goes to sleep
 	for regRequest := range RegisterQueue {
-		log.Info.Printf("(reg: %s) >> Registering agent\n", regRequest.ID)
-		//Generate ID
-		agentId := database.GenerateAgentID()
-		if agentId == "" {
-			log.Error.Printf("(reg: %s) >> Failed to generate agent ID\n", regRequest.ID)
-			rejectRegistration(regRequest.ID, errors.New("failed to generate agent ID"))
+		if regRequest.ReplyTo == "" {
+			log.Warn.Printf("(reg: %s) >> Registration request without a reply queue. Ignoring\n", regRequest.ID)
 			continue
 		}
-		//Register to database
-		err := database.Register(agentId, regRequest.InternalIP, regRequest.DeviceSupport, regRequest.SensorSupport)
-		if err != nil {
-			rejectRegistration(regRequest.ID, err)
+		if regRequest.AgentId != "" {
+			rejoin(regRequest)
 			continue
 		}
-		//Send response back to agent
-		response, _ := json.Marshal(map[string]string{
-			"requestId": regRequest.ID,
-			"direction": string(CONTROLLER),
-			"agentId":   agentId,
-			"status":    "success",
-		})
-		err = queue.Ch.Publish(
-			"",
-			"register",
-			false,
-			false,
-			amqp.Publishing{
-				ContentType: "application/json",
-				Body:        response,
-			},
-		)
+		register(regRequest, make([]string, 0))
+	}
+	log.Fatal.Fatalln("Registration thread ended. This should not happen!")
+}
+
+//Registers a new agent. The agent removes the given containers
+func register(regRequest request.RegisterRequest, remove []string) {
+	log.Info.Printf("(reg: %s) >> Registering agent\n", regRequest.ID)
+	if vars.IsRegistrationAuthEnable() && !auth.ValidateToken(regRequest.Token) {
+		log.Warn.Printf("(reg: %s) >> Agent presented an invalid registration token\n", regRequest.ID)
+		rejectRegistration(regRequest, errInvalidToken)
+		return
+	}
+	//Generate ID
+	agentId := database.GenerateAgentID()
+	if agentId == "" {
+		log.Error.Printf("(reg: %s) >> Failed to generate agent ID\n", regRequest.ID)
+		rejectRegistration(regRequest, errors.New("failed to generate agent ID"))
+		return
+	}
+	//Register to database
+	err := database.Register(agentId, regRequest.InternalIP, regRequest.DeviceSupport, regRequest.SensorSupport)
+	if err != nil {
+		rejectRegistration(regRequest, err)
+		return
+	}
+	secret, err := auth.NewSecret(agentId)
+	if err != nil {
+		database.Unregister(agentId)
+		rejectRegistration(regRequest, errors.New("failed to generate agent secret"))
+		return
+	}
+	//Send response back to agent
+	err = acceptRegistration(regRequest, agentId, secret, remove)
+	if err != nil {
+		log.Error.Println("Failed sending registration info")
+		log.Error.Println(err)
+		database.Unregister(agentId)
+		return
+	}
+	log.Info.Printf("%s (reg: %s) << Registered agent\n", agentId, regRequest.ID)
+	alert.AgentConnect.Publish(agentId)
+	auto.AgentJoin <- agentId
+}
+
+//Lets an agent continue under the ID of its previous session
+//If the agent is still registered, its record is kept. If it was marked as disconnected, it is registered again under the same ID
+func rejoin(regRequest request.RegisterRequest) {
+	agentId := regRequest.AgentId
+	//Agent IDs are generated by shortuuid, which are always 22 characters long
+	if len(agentId) != 22 {
+		log.Warn.Printf("(reg: %s) >> Agent rejoined with invalid ID %s. Registering as a new agent\n", regRequest.ID, agentId)
+		register(regRequest, regRequest.Containers)
+		return
+	}
+	if vars.IsRevoked(agentId) {
+		log.Warn.Printf("%s (reg: %s) >> Revoked agent tried to rejoin\n", agentId, regRequest.ID)
+		rejectRegistration(regRequest, errRevoked)
+		return
+	}
+	//An agent that cannot prove its identity must register as a new agent with a bootstrap token
+	if vars.IsRegistrationAuthEnable() && !regRequest.Authenticated {
+		log.Warn.Printf("%s (reg: %s) >> Agent rejoined without a valid signature. Registering as a new agent\n", agentId, regRequest.ID)
+		register(regRequest, regRequest.Containers)
+		return
+	}
+	//The stored secret is never sent. Agents that did not prove their identity receive a new one
+	secret := ""
+	if !regRequest.Authenticated {
+		var err error
+		secret, err = auth.NewSecret(agentId)
+		if err != nil {
+			rejectRegistration(regRequest, errors.New("failed to generate agent secret"))
+			return
+		}
+	}
+	log.Info.Printf("%s (reg: %s) >> Agent rejoining with %d containers\n", agentId, regRequest.ID, len(regRequest.Containers))
+	expected := make([]string, 0)
+	if _agent, ok := vars.Agents.Load(agentId); ok {
+		agent := _agent.(types.Agent)
+		expected = agent.Containers
+		agent.LastAlive = time.Now().Unix()
+		vars.Agents.Store(agentId, agent)
+		//The agent's queues expire when it is disconnected for too long
+		queue.SetupAgentQueue(agentId)
+	} else {
+		err := database.Register(agentId, regRequest.InternalIP, regRequest.DeviceSupport, regRequest.SensorSupport)
 		if err != nil {
-			log.Error.Println("Failed sending registration info")
-			log.Error.Println(err)
-			database.Unregister(agentId)
+			rejectRegistration(regRequest, err)
+			return
+		}
+	}
+	//Containers of disconnected agents are still in memory until they are moved to other agents
+	specs := make(map[string]types.ContainerSpec)
+	vars.ContainerSpecs.Range(func(containerId, spec interface{}) bool {
+		if spec.(types.ContainerSpec).AgentId == agentId {
+			specs[containerId.(string)] = spec.(types.ContainerSpec)
+		}
+		return true
+	})
+	adopt, remove, lost := planRejoin(expected, specs, regRequest.Containers)
+	for _, containerId := range adopt {
+		spec := specs[containerId]
+		database.AddContainer(agentId, containerId, &spec)
+	}
+	for _, containerId := range lost {
+		log.Warn.Printf("%s (reg: %s) >> Container %s is no longer on the agent\n", agentId, regRequest.ID, containerId)
+		database.RemoveContainer(agentId, containerId)
+		vars.ContainerSpecs.Delete(containerId)
+	}
+	err := acceptRegistration(regRequest, agentId, secret, remove)
+	if err != nil {
+		log.Error.Println("Failed sending registration info")
+		log.Error.Println(err)
+		return
+	}
+	log.Info.Printf("%s (reg: %s) << Agent rejoined. %d containers kept, %d removed\n", agentId, regRequest.ID, len(regRequest.Containers)-len(remove), len(remove))
+	alert.AgentConnect.Publish(agentId)
+}
+
+//Decides what happens to the containers of a rejoining agent
+//	adopt: not in the agent's record, but the controller still has the spec. This happens if the agent was marked as disconnected
+//	remove: unknown to the controller, e.g. the container has been moved to another agent. The agent removes these
+//	lost: in the agent's record, but no longer on the agent
+func planRejoin(expected []string, specs map[string]types.ContainerSpec, reported []string) (adopt, remove, lost []string) {
+	adopt = make([]string, 0)
+	remove = make([]string, 0)
+	lost = make([]string, 0)
+	known := make(map[string]bool)
+	for _, containerId := range expected {
+		known[containerId] = true
+	}
+	onAgent := make(map[string]bool)
+	for _, containerId := range reported {
+		onAgent[containerId] = true
+		if known[containerId] {
 			continue
 		}
-		log.Info.Printf("%s (reg: %s) << Registered agent\n", agentId, regRequest.ID)
-		auto.AgentJoin <- agentId
+		if _, ok := specs[containerId]; ok {
+			adopt = append(adopt, containerId)
+		} else {
+			remove = append(remove, containerId)
+		}
 	}
-	log.Fatal.Fatalln("Registration thread ended. This should not happen!")
+	for _, containerId := range expected {
+		if !onAgent[containerId] {
+			lost = append(lost, containerId)
+		}
+	}
+	return adopt, remove, lost
 }
 
-func rejectRegistration(requestId string, err error) {
-	log.Error.Printf("(reg: %s) << Registration failure", requestId)
+//Sends the agent ID to the agent, along with the containers it should remove
+//The secret is left out if it is empty, which means the agent keeps its current one
+func acceptRegistration(regRequest request.RegisterRequest, agentId, secret string, remove []string) error {
+	reply := map[string]interface{}{
+		"requestId": regRequest.ID,
+		"direction": string(CONTROLLER),
+		"agentId":   agentId,
+		"status":    "success",
+		"remove":    remove,
+	}
+	if secret != "" {
+		reply["secret"] = secret
+	}
+	response, _ := json.Marshal(reply)
+	return queue.Publish(regRequest.ReplyTo, response)
+}
+
+func rejectRegistration(regRequest request.RegisterRequest, err error) {
+	log.Error.Printf("(reg: %s) << Registration failure", regRequest.ID)
+	switch err {
+	case errInvalidToken:
+		metrics.RegistrationRejections.Inc("invalid-token")
+	case errRevoked:
+		metrics.RegistrationRejections.Inc("revoked")
+	default:
+		metrics.RegistrationRejections.Inc("error")
+	}
 	response, _ := json.Marshal(map[string]string{
-		"requestId": requestId,
+		"requestId": regRequest.ID,
 		"status":    "error",
 		"error":     err.Error(),
 	})
-	_ = queue.Ch.Publish(
-		"",
-		"register",
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        response,
-		},
-	)
+	_ = queue.Publish(regRequest.ReplyTo, response)
 }
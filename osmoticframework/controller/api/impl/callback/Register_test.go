@@ -0,0 +1,59 @@
+package callback
+
+import (
+	"osmoticframework/controller/types"
+	"reflect"
+	"testing"
+)
+
+func TestPlanRejoin(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Expected []string
+		Specs    map[string]types.ContainerSpec
+		Reported []string
+		Adopt    []string
+		Remove   []string
+		Lost     []string
+	}{
+		{
+			Name:     "reconnected",
+			Expected: []string{"a", "b"},
+			Specs:    map[string]types.ContainerSpec{"a": {}, "b": {}},
+			Reported: []string{"a", "b"},
+			Adopt:    []string{},
+			Remove:   []string{},
+			Lost:     []string{},
+		},
+		{
+			Name:     "marked as disconnected",
+			Expected: []string{},
+			Specs:    map[string]types.ContainerSpec{"a": {}},
+			Reported: []string{"a", "b"},
+			Adopt:    []string{"a"},
+			Remove:   []string{"b"},
+			Lost:     []string{},
+		},
+		{
+			Name:     "container gone",
+			Expected: []string{"a", "b"},
+			Specs:    map[string]types.ContainerSpec{},
+			Reported: []string{"b"},
+			Adopt:    []string{},
+			Remove:   []string{},
+			Lost:     []string{"a"},
+		},
+	}
+	for _, test := range tests {
+		adopt, remove, lost := planRejoin(test.Expected, test.Specs, test.Reported)
+		if !reflect.DeepEqual(adopt, test.Adopt) {
+			t.Errorf("%s: Adopted containers incorrect. Got %v, Want %v", test.Name, adopt, test.Adopt)
+		}
+		if !reflect.DeepEqual(remove, test.Remove) {
+			t.Errorf("%s: Removed containers incorrect. Got %v, Want %v", test.Name, remove, test.Remove)
+		}
+		if !reflect.DeepEqual(lost, test.Lost) {
+			t.Errorf("%s: Lost containers incorrect. Got %v, Want %v", test.Name, lost, test.Lost)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package callback
+
+import (
+	"errors"
+	"osmoticframework/controller/api/impl/request"
+	"time"
+)
+
+//Reads a message pushed by an agent to the queue of a subscription
+//Samples have the same message structure as the responses of the monitor API, plus the subscription ID, command, container ID and time of the sample
+func ParseSample(sub *request.Subscription, message map[string]interface{}) {
+	status, ok := message["status"].(string)
+	if !ok {
+		//No status. Ignore
+		return
+	}
+	//The subscription ran out on the agent
+	if status == "ended" {
+		sub.End()
+		return
+	}
+	command, _ := message["command"].(string)
+	containerId, _ := message["containerId"].(string)
+	timestamp, _ := message["time"].(float64)
+	sample := request.Sample{
+		SubscriptionId: sub.ID,
+		AgentId:        sub.AgentId,
+		Command:        command,
+		ContainerId:    containerId,
+		Time:           time.Unix(int64(timestamp), 0),
+	}
+	switch status {
+	case "ok":
+		promMetric, err := decodeMetric(message["metric"])
+		if err != nil {
+			sample.Err = err
+			break
+		}
+		sample.Metric = parseMetric(sub.AgentId, command, promMetric)
+	case "failed":
+		errStr, ok := message["error"].(string)
+		if !ok {
+			errStr = "unknown error"
+		}
+		sample.Err = errors.New(errStr)
+	default:
+		return
+	}
+	sub.Deliver(sample)
+}
@@ -0,0 +1,50 @@
+package callback
+
+import (
+	"encoding/json"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/types/metric"
+	"testing"
+)
+
+func TestParseSample(t *testing.T) {
+	tests := []struct {
+		Message string
+		Valid   bool
+	}{
+		{`{"subscriptionId": "a", "command": "cpu_utilization", "time": 1000, "status": "ok", "metric": {"type": "vector", "data": [{"key": {}, "scalar": {"time": "2021-01-01T00:00:00Z", "value": 0.5, "undefined": false}}]}}`, true},
+		{`{"subscriptionId": "a", "command": "cpu_utilization", "time": 1000, "status": "failed", "error": "prometheus unavailable"}`, false},
+		//No metric
+		{`{"subscriptionId": "a", "command": "cpu_utilization", "time": 1000, "status": "ok"}`, false},
+	}
+	sub := &request.Subscription{ID: "a", AgentId: "agent", Samples: make(chan request.Sample, len(tests))}
+	for _, test := range tests {
+		var message map[string]interface{}
+		if err := json.Unmarshal([]byte(test.Message), &message); err != nil {
+			t.Fatal(err)
+		}
+		ParseSample(sub, message)
+		select {
+		case sample := <-sub.Samples:
+			if test.Valid != (sample.Err == nil) {
+				t.Errorf("Sample error incorrect. Got %v, Want error: %t", sample.Err, !test.Valid)
+			}
+			if sample.Time.Unix() != 1000 || sample.Command != "cpu_utilization" || sample.AgentId != "agent" {
+				t.Errorf("Sample incorrect. Got %+v", sample)
+			}
+			if test.Valid {
+				usage, ok := sample.Metric.(metric.CpuEdgeOverallUsageMetric)
+				if !ok || usage.Usage != 0.5 {
+					t.Errorf("Sample metric incorrect. Got %#v, Want a CPU utilization of 0.5", sample.Metric)
+				}
+			}
+		default:
+			t.Errorf("Message %s not delivered", test.Message)
+		}
+	}
+	//Messages without a status are ignored
+	ParseSample(sub, map[string]interface{}{"subscriptionId": "a"})
+	if len(sub.Samples) != 0 {
+		t.Errorf("Message without a status delivered")
+	}
+}
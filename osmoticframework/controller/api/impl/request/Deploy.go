@@ -3,7 +3,6 @@ package request
 import (
 	"encoding/json"
 	"github.com/lithammer/shortuuid"
-	"github.com/streadway/amqp"
 	"osmoticframework/controller/log"
 	"osmoticframework/controller/queue"
 	"osmoticframework/controller/types"
@@ -17,6 +16,11 @@ An internal request ID will be automatically generated upon calling the function
 Each api request has their own timeout duration. If you think it needs more time, please change the value.
 */
 func RunRequest(agentId string, deployArgs types.DeployArgs, authInfo types.AuthInfo, timeout float64) *RequestTask {
+	return RunRequestWithOrigin(agentId, deployArgs, authInfo, types.OriginAPI, timeout)
+}
+
+//Same as RunRequest. The origin is recorded with the container in the database
+func RunRequestWithOrigin(agentId string, deployArgs types.DeployArgs, authInfo types.AuthInfo, origin types.ContainerOrigin, timeout float64) *RequestTask {
 	id := shortuuid.New()
 	for {
 		id = shortuuid.New()
@@ -33,16 +37,7 @@ func RunRequest(agentId string, deployArgs types.DeployArgs, authInfo types.Auth
 		},
 	})
 	log.Info.Printf("%s << Deploy request with image %s\n", agentId, deployArgs.Image)
-	err := queue.Ch.Publish(
-		"",
-		"deploy-"+agentId,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        request,
-		},
-	)
+	err := queue.PublishRequest(id, agentId, "deploy-"+agentId, request)
 	if err != nil {
 		log.Error.Println("Failed sending request")
 		log.Error.Println(err)
@@ -56,6 +51,7 @@ func RunRequest(agentId string, deployArgs types.DeployArgs, authInfo types.Auth
 		Args: map[string]interface{}{
 			"deployArgs": deployArgs,
 			"authInfo":   authInfo,
+			"origin":     origin,
 		},
 		Timeout: timeout,
 		Result:  make(chan Result, 1),
@@ -75,7 +71,7 @@ func RunRequest(agentId string, deployArgs types.DeployArgs, authInfo types.Auth
 func StopRequest(agentId, containerId string, timeout float64) *RequestTask {
 	var id string
 	for {
-		id := shortuuid.New()
+		id = shortuuid.New()
 		if _, ok := DeployRequests.Load(id); !ok {
 			break
 		}
@@ -88,16 +84,7 @@ func StopRequest(agentId, containerId string, timeout float64) *RequestTask {
 		},
 	})
 	log.Info.Printf("%s << Stop request on container %s\n", agentId, containerId)
-	err := queue.Ch.Publish(
-		"",
-		"deploy-"+agentId,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        request,
-		},
-	)
+	err := queue.PublishRequest(id, agentId, "deploy-"+agentId, request)
 	if err != nil {
 		log.Error.Println("Failed sending request")
 		log.Error.Println(err)
@@ -132,7 +119,7 @@ func StopRequest(agentId, containerId string, timeout float64) *RequestTask {
 func DeleteRequest(agentId, containerId string, deleteImage bool, timeout float64) *RequestTask {
 	var id string
 	for {
-		id := shortuuid.New()
+		id = shortuuid.New()
 		if _, ok := DeployRequests.Load(id); !ok {
 			break
 		}
@@ -146,16 +133,7 @@ func DeleteRequest(agentId, containerId string, deleteImage bool, timeout float6
 		},
 	})
 	log.Info.Printf("%s << Delete request on container %s\n", agentId, containerId)
-	err := queue.Ch.Publish(
-		"",
-		"deploy-"+agentId,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        request,
-		},
-	)
+	err := queue.PublishRequest(id, agentId, "deploy-"+agentId, request)
 	if err != nil {
 		log.Error.Println("Failed sending request")
 		log.Error.Println(err)
@@ -191,7 +169,7 @@ func DeleteRequest(agentId, containerId string, deleteImage bool, timeout float6
 func UpdateRequest(agentId, containerId string, deployArgs types.DeployArgs, authInfo types.AuthInfo, timeout float64) *RequestTask {
 	var id string
 	for {
-		id := shortuuid.New()
+		id = shortuuid.New()
 		if _, ok := DeployRequests.Load(id); !ok {
 			break
 		}
@@ -206,16 +184,7 @@ func UpdateRequest(agentId, containerId string, deployArgs types.DeployArgs, aut
 		},
 	})
 	log.Info.Printf("%s << Update request on container %s\n", agentId, containerId)
-	err := queue.Ch.Publish(
-		"",
-		"deploy-"+agentId,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        request,
-		},
-	)
+	err := queue.PublishRequest(id, agentId, "deploy-"+agentId, request)
 	if err != nil {
 		log.Error.Println("Failed sending request")
 		log.Error.Println(err)
@@ -250,10 +219,25 @@ func UpdateRequest(agentId, containerId string, deployArgs types.DeployArgs, aut
 	return &task
 }
 
+//Checks if an update request on a container is in flight
+//Agents update containers by removing the old one and running a new one. The old container is replaced, not gone
+func Updating(agentId, containerId string) bool {
+	updating := false
+	DeployRequests.Range(func(_, value interface{}) bool {
+		task := value.(ImplRequestTask)
+		if task.Command == "update" && task.AgentId == agentId {
+			args, ok := task.Args.(map[string]string)
+			updating = ok && args["oldContainerId"] == containerId
+		}
+		return !updating
+	})
+	return updating
+}
+
 func ListRequest(agentId string, timeout float64) *RequestTask {
 	var id string
 	for {
-		id := shortuuid.New()
+		id = shortuuid.New()
 		if _, ok := DeployRequests.Load(id); !ok {
 			break
 		}
@@ -264,16 +248,7 @@ func ListRequest(agentId string, timeout float64) *RequestTask {
 		Args:      map[string]interface{}{},
 	})
 	log.Info.Printf("%s << List request\n", agentId)
-	err := queue.Ch.Publish(
-		"",
-		"deploy-"+agentId,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        request,
-		},
-	)
+	err := queue.PublishRequest(id, agentId, "deploy-"+agentId, request)
 	if err != nil {
 		log.Error.Println("Failed sending request")
 		log.Error.Println(err)
@@ -303,7 +278,7 @@ func ListRequest(agentId string, timeout float64) *RequestTask {
 func InspectRequest(agentId, containerId string, timeout float64) *RequestTask {
 	var id string
 	for {
-		id := shortuuid.New()
+		id = shortuuid.New()
 		if _, ok := DeployRequests.Load(id); !ok {
 			break
 		}
@@ -316,16 +291,7 @@ func InspectRequest(agentId, containerId string, timeout float64) *RequestTask {
 		},
 	})
 	log.Info.Printf("%s << Inspect container on container %s\n", agentId, containerId)
-	err := queue.Ch.Publish(
-		"",
-		"deploy-"+agentId,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        request,
-		},
-	)
+	err := queue.PublishRequest(id, agentId, "deploy-"+agentId, request)
 	if err != nil {
 		log.Error.Println("Failed sending request")
 		log.Error.Println(err)
@@ -351,7 +317,72 @@ func InspectRequest(agentId, containerId string, timeout float64) *RequestTask {
 			"containerId": containerId,
 		},
 		Timeout: timeout,
-		Result:  nil,
+		Result:  make(chan Result, 1),
+	}
+	DeployTaskList.Store(id, task)
+	return &task
+}
+
+//Extra time the agent gets to reply after an exec command times out
+const execGrace = 10
+
+/*
+	Runs a command inside a container. The command is an array of the program and its arguments, and is not run in a shell
+	The agent abandons the command after the timeout in seconds. The result is types.ExecResult
+	Exec must be enabled on the agent. See enable_exec in the properties file of the agent
+*/
+func ExecRequest(agentId, containerId string, command []string, timeout float64) *RequestTask {
+	log.Info.Printf("%s << Exec request in container %s\n", agentId, containerId)
+	args := map[string]interface{}{
+		"containerId": containerId,
+		"command":     command,
+		"timeout":     timeout,
+	}
+	return deployRequest("exec", agentId, args, timeout+execGrace)
+}
+
+//Sends a deploy API request. Used by the commands that do not change the containers
+func deployRequest(command, agentId string, args map[string]interface{}, timeout float64) *RequestTask {
+	var id string
+	for true {
+		id = shortuuid.New()
+		if _, ok := DeployRequests.Load(id); !ok {
+			break
+		}
+	}
+	request, err := json.Marshal(Request{
+		RequestID: id,
+		Command:   command,
+		Args:      args,
+	})
+	if err != nil {
+		log.Error.Println("Failed constructing deploy API command")
+		log.Error.Println(err)
+		return nil
+	}
+	err = queue.PublishRequest(id, agentId, "deploy-"+agentId, request)
+	if err != nil {
+		log.Error.Println("Failed sending request")
+		log.Error.Println(err)
+		return nil
+	}
+	DeployRequests.Store(id, ImplRequestTask{
+		AgentId: agentId,
+		API:     "deploy",
+		Command: command,
+		Ack:     false,
+		Time:    time.Now(),
+		Args:    args,
+		Timeout: timeout,
+	})
+	task := RequestTask{
+		AgentId: agentId,
+		API:     "deploy",
+		Command: command,
+		Time:    time.Now(),
+		Args:    args,
+		Timeout: timeout,
+		Result:  make(chan Result, 1),
 	}
 	DeployTaskList.Store(id, task)
 	return &task
@@ -15,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"osmoticframework/controller/log"
 	"osmoticframework/controller/types"
@@ -194,6 +195,27 @@ func KUpdateDeployment(deployArgs types.KDeployArgs, secrets []string) error {
 	return err
 }
 
+//Changes the number of replicas of a deployment, without changing the rest of the deployment
+func KScaleDeployment(deploymentName string, replicas int32) error {
+	kuber := getKuber()
+	if replicas <= 0 {
+		return errors.New("replicas cannot be lower than 1")
+	}
+	scale, err := kuber.AppsV1().Deployments("default").GetScale(context.Background(), deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	scale.Spec.Replicas = replicas
+	_, err = kuber.AppsV1().Deployments("default").UpdateScale(context.Background(), deploymentName, scale, metav1.UpdateOptions{})
+	if err != nil {
+		log.Error.Println("Scale deployment " + deploymentName + " failed")
+		log.Error.Println(err)
+		return err
+	}
+	log.Info.Printf("Deployment %s scaled to %d replicas\n", deploymentName, replicas)
+	return nil
+}
+
 //Creates a service in Kubernetes
 //In order for external IPs to connect to deployments, you'll need services.
 //Simply exposing the ports from the pods are not enough. It only allows other pods to communicate with each other
@@ -1025,6 +1047,9 @@ func servicePortK8sToLocal(servicePort []corev1.ServicePort) []types.KServicePor
 var configMutex sync.Once
 var kClient *kubernetes.Clientset
 
+//Kept for the requests that are not covered by the clientset, such as exec. See KPods.go
+var kConfig *rest.Config
+
 //Get Kubernetes configurations
 //This includes credentials, the address the client should connect to, etc.
 func getKuber() *kubernetes.Clientset {
@@ -1032,12 +1057,13 @@ func getKuber() *kubernetes.Clientset {
 		if vars.GetKuberConfigPath() == "" {
 			log.Fatal.Panicln("Kubernetes config filepath not defined!")
 		}
-		config, err := clientcmd.BuildConfigFromFlags("", vars.GetKuberConfigPath())
+		var err error
+		kConfig, err = clientcmd.BuildConfigFromFlags("", vars.GetKuberConfigPath())
 		if err != nil {
 			log.Fatal.Println("Cannot read Kubernetes config")
 			log.Fatal.Panicln(err)
 		}
-		kClient, err = kubernetes.NewForConfig(config)
+		kClient, err = kubernetes.NewForConfig(kConfig)
 		if err != nil {
 			log.Fatal.Println("Error occurred generating client for Kubernetes")
 			log.Fatal.Panicln(err)
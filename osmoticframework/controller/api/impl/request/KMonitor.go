@@ -589,6 +589,11 @@ func KEndpointInfo() (*metric.PromMetric, error) {
 	return promMetric, nil
 }
 
+//Runs a PromQL query on the cloud Prometheus
+func KQuery(promQL string, time time.Time) (*metric.PromMetric, error) {
+	return restQuery(promQL, time)
+}
+
 //Queries in a specific point in time.
 func restQuery(query string, time time.Time) (*metric.PromMetric, error) {
 	if vars.GetPrometheusAddress() == "" {
@@ -625,9 +630,9 @@ func restQueryRange(query string, from, to time.Time, step time.Duration) (*metr
 	response, err := client.R().
 		SetQueryParams(map[string]string{
 			"query": query,
-			"from":  strconv.FormatInt(from.Unix(), 10),
-			"to":    strconv.FormatInt(to.Unix(), 10),
-			"step":  strconv.Itoa(int(step.Seconds())),
+			"start": strconv.FormatInt(from.Unix(), 10),
+			"end":   strconv.FormatInt(to.Unix(), 10),
+			"step":  strconv.FormatInt(int64(step.Seconds()), 10),
 		}).
 		Get(vars.GetPrometheusAddress() + "/query_range")
 	if err != nil {
@@ -684,7 +689,7 @@ func parsePromResponse(response []byte) (*metric.PromMetric, error) {
 		promMetric.Type = metric.MatrixType
 		var matrix = make([]metric.Matrix, 0)
 		for _, dev := range result {
-			devInfoDecoded, ok := dev["promMetric"].(map[string]interface{})
+			devInfoDecoded, ok := dev["metric"].(map[string]interface{})
 			//mapstructure has decoded some of the fields in promMetric to int64 or float64 instead of string.
 			//To minimize the unnecessary type assertions, we'll convert all of them to string
 			devInfo := make(map[string]string)
@@ -706,7 +711,7 @@ func parsePromResponse(response []byte) (*metric.PromMetric, error) {
 			/*
 				"result": [
 					{
-						"promMetric": {
+						"metric": {
 							"cpu": "0"
 						},
 						"values": [
@@ -749,7 +754,7 @@ func parsePromResponse(response []byte) (*metric.PromMetric, error) {
 		promMetric.Type = metric.VectorType
 		var vectors = make([]metric.Vector, 0)
 		for _, dev := range result {
-			devInfoDecoded, ok := dev["promMetric"].(map[string]interface{})
+			devInfoDecoded, ok := dev["metric"].(map[string]interface{})
 			//mapstructure has decoded some of the fields in promMetric to int64 or float64 instead of string.
 			//To minimize the unnecessary type assertions, we'll convert all of them to string
 			devInfo := make(map[string]string)
@@ -771,7 +776,7 @@ func parsePromResponse(response []byte) (*metric.PromMetric, error) {
 			/*
 				"result": [
 					{
-						"promMetric": {
+						"metric": {
 							"cpu": "4"
 						},
 						"value": [
@@ -0,0 +1,279 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"osmoticframework/controller/api/impl/request/monitor/query"
+	"osmoticframework/controller/types/metric"
+	"strconv"
+	"time"
+)
+
+/*
+Range variants of the Kubernetes monitoring API functions. See KMonitor.go
+They return a point every step from "from" to "to", so resource usage can be plotted without querying every point in time.
+*/
+
+//Node (Individual server host) average usage per core from the last 10 seconds (Separated by core ID)
+func KCPUCoreAvgRange(nodeName string, from, to time.Time, step time.Duration) ([]metric.KCPUCoreSeries, error) {
+	return kCPUCoreSeries(fmt.Sprintf(query.KCPUNodeAvg, nodeName), from, to, step)
+}
+
+//Pod average usage (includes all replicas) from the last 10 seconds
+func KCPUPodAvgRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodSeries, error) {
+	return kPodSeries(fmt.Sprintf(query.KCPUPodAvg, podName), from, to, step)
+}
+
+//Cumulative CPU time by core
+func KCPUTimeRange(nodeName string, from, to time.Time, step time.Duration) ([]metric.KCPUCoreSeries, error) {
+	return kCPUCoreSeries(fmt.Sprintf(query.KCPUTime, nodeName), from, to, step)
+}
+
+//Overall node CPU utilization
+func KCPUUtilizationRange(nodeName string, from, to time.Time, step time.Duration) ([]metric.KNodeSeries, error) {
+	return kNodeSeries(fmt.Sprintf(query.KCPUUtil, nodeName), from, to, step)
+}
+
+//Memory usage in bytes in a pod
+func KMemoryPodRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodSeries, error) {
+	return kPodSeries(fmt.Sprintf(query.KMemoryPod, podName), from, to, step)
+}
+
+//Memory usage in bytes in the whole cluster
+func KMemoryNodeRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeSeries, error) {
+	return kNodeSeries(fmt.Sprintf(query.KMemoryNode, nodeIP), from, to, step)
+}
+
+//Highest memory usage in bytes over the past 5 minutes in a pod
+func KMemoryPodPeakRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodSeries, error) {
+	return kPodSeries(fmt.Sprintf(query.KMemoryPodPeak, podName), from, to, step)
+}
+
+//Highest memory usage in bytes over the past 5 minutes in a node
+func KMemoryNodePeakRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeSeries, error) {
+	return kNodeSeries(fmt.Sprintf(query.KMemoryNodePeak, nodeIP), from, to, step)
+}
+
+//Cumulative number of seconds spent doing IO operations on a node
+func KIONodeTimeRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KIONodeTime, nodeIP), from, to, step)
+}
+
+//Cumulative number of seconds spent doing IO operations on a pod
+func KIOPodTimeRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KIOPodTime, podName), from, to, step)
+}
+
+//Cumulative amount of read bytes on the node
+func KIOReadNodeBytesRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KIOReadNodeBytes, nodeIP), from, to, step)
+}
+
+//Cumulative amount of read bytes on a pod
+func KIOReadPodBytesRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KIOReadPodBytes, podName), from, to, step)
+}
+
+//Cumulative amount of write bytes on the node
+func KIOWriteNodeBytesRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KIOWriteNodeBytes, nodeIP), from, to, step)
+}
+
+//Cumulative amount of write bytes on a pod
+func KIOWritePodBytesRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KIOWritePodBytes, podName), from, to, step)
+}
+
+//Total disk space used in bytes
+func KIOFilesystemUsedBytesRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KFilesystemSeries, error) {
+	return kFilesystemSeries(fmt.Sprintf(query.KIOFilesystemUsedBytes, nodeIP), from, to, step)
+}
+
+//Filesystem size in bytes
+func KIOFilesystemSizeBytesRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KFilesystemSeries, error) {
+	return kFilesystemSeries(fmt.Sprintf(query.KIOFilesystemSizeBytes, nodeIP), from, to, step)
+}
+
+//Network receive bytes on the node
+func KNetworkNodeRxBytesRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KNetworkNodeRxBytes, nodeIP), from, to, step)
+}
+
+//Network receive bytes on a pod
+func KNetworkPodRxBytesRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KNetworkPodRxBytes, podName), from, to, step)
+}
+
+//Network transmit bytes on the node
+func KNetworkNodeTxBytesRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KNetworkNodeTxBytes, nodeIP), from, to, step)
+}
+
+//Network transmit bytes on a pod
+func KNetworkPodTxBytesRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KNetworkPodTxBytes, podName), from, to, step)
+}
+
+//Network received packets on the node
+func KNetworkNodeRxPacketsRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KNetworkNodeRxPackets, nodeIP), from, to, step)
+}
+
+//Network received packets on a pod
+func KNetworkPodRxPacketsRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KNetworkPodRxPackets, podName), from, to, step)
+}
+
+//Network transmit packets on the node
+func KNetworkNodeTxPacketsRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KNetworkNodeTxPackets, nodeIP), from, to, step)
+}
+
+//Network transmit packets on a pod
+func KNetworkPodTxPacketsRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KNetworkPodTxPackets, podName), from, to, step)
+}
+
+//Network dropped received packets on the node
+func KNetworkNodeRxDroppedRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KNetworkNodeRxPacketDropped, nodeIP), from, to, step)
+}
+
+//Network dropped received packets on a pod
+func KNetworkPodRxDroppedRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KNetworkPodRxPacketDropped, podName), from, to, step)
+}
+
+//Network dropped transmit packets on the node
+func KNetworkNodeTxDroppedRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KNetworkNodeTxPacketDropped, nodeIP), from, to, step)
+}
+
+//Network dropped transmit packets on a pod
+func KNetworkPodTxDroppedRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KNetworkPodTxPacketDropped, podName), from, to, step)
+}
+
+//Network receive errors on the node
+func KNetworkNodeRxErrorRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KNetworkNodeRxError, nodeIP), from, to, step)
+}
+
+//Network receive errors on a pod
+func KNetworkPodRxErrorRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KNetworkPodRxError, podName), from, to, step)
+}
+
+//Network transmit errors on the node
+func KNetworkNodeTxErrorRange(nodeIP string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	return kNodeDeviceSeries(fmt.Sprintf(query.KNetworkNodeTxError, nodeIP), from, to, step)
+}
+
+//Network transmit errors on a pod
+func KNetworkPodTxErrorRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	return kPodDeviceSeries(fmt.Sprintf(query.KNetworkPodTxError, podName), from, to, step)
+}
+
+//The time in seconds a pod reaches the memory soft limit over the past 5 minutes (It cannot exceed 300 seconds)
+//The soft limit is checked at the end of the range
+func KMemoryPodReachLimitSecondsRange(podName string, from, to time.Time, step time.Duration) ([]metric.KPodSeries, error) {
+	promMetric, err := restQuery(fmt.Sprintf(query.KSoftLimitQuery, podName), to)
+	if err != nil {
+		return nil, err
+	}
+	//Check if promMetric is empty
+	if len(promMetric.Data.([]metric.Vector)) == 0 {
+		return nil, nil
+	}
+	//Check if soft limit is enabled in the pod. If not set, return error
+	if promMetric.Data.([]metric.Vector)[0].Scalar.Value == 0 {
+		return nil, errors.New("soft limit for container is not set")
+	}
+	return kPodSeries(fmt.Sprintf(query.KMemoryPodReachLimitSeconds, podName), from, to, step)
+}
+
+//Series are grouped the same way as the results of the instant queries
+func kCPUCoreSeries(query string, from, to time.Time, step time.Duration) ([]metric.KCPUCoreSeries, error) {
+	matrices, err := rangeMatrices(query, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+	var ret = make([]metric.KCPUCoreSeries, 0)
+	for _, m := range matrices {
+		core, _ := strconv.Atoi(m.Key["cpu"])
+		ret = append(ret, metric.KCPUCoreSeries{Core: core, Node: m.Key["node"], Values: m.Values})
+	}
+	return ret, nil
+}
+
+func kNodeSeries(query string, from, to time.Time, step time.Duration) ([]metric.KNodeSeries, error) {
+	matrices, err := rangeMatrices(query, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+	var ret = make([]metric.KNodeSeries, 0)
+	for _, m := range matrices {
+		ret = append(ret, metric.KNodeSeries{Node: m.Key["node"], Values: m.Values})
+	}
+	return ret, nil
+}
+
+func kPodSeries(query string, from, to time.Time, step time.Duration) ([]metric.KPodSeries, error) {
+	matrices, err := rangeMatrices(query, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+	var ret = make([]metric.KPodSeries, 0)
+	for _, m := range matrices {
+		ret = append(ret, metric.KPodSeries{Pod: m.Key["pod"], Values: m.Values})
+	}
+	return ret, nil
+}
+
+func kNodeDeviceSeries(query string, from, to time.Time, step time.Duration) ([]metric.KNodeDeviceSeries, error) {
+	matrices, err := rangeMatrices(query, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+	var ret = make([]metric.KNodeDeviceSeries, 0)
+	for _, m := range matrices {
+		ret = append(ret, metric.KNodeDeviceSeries{Node: m.Key["node"], Device: m.Key["device"], Values: m.Values})
+	}
+	return ret, nil
+}
+
+func kPodDeviceSeries(query string, from, to time.Time, step time.Duration) ([]metric.KPodDeviceSeries, error) {
+	matrices, err := rangeMatrices(query, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+	var ret = make([]metric.KPodDeviceSeries, 0)
+	for _, m := range matrices {
+		ret = append(ret, metric.KPodDeviceSeries{Pod: m.Key["pod"], Device: m.Key["device"], Values: m.Values})
+	}
+	return ret, nil
+}
+
+func kFilesystemSeries(query string, from, to time.Time, step time.Duration) ([]metric.KFilesystemSeries, error) {
+	matrices, err := rangeMatrices(query, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+	var ret = make([]metric.KFilesystemSeries, 0)
+	for _, m := range matrices {
+		ret = append(ret, metric.KFilesystemSeries{Node: m.Key["node"], Device: m.Key["device"], Mountpoint: m.Key["mountpoint"], Values: m.Values})
+	}
+	return ret, nil
+}
+
+func rangeMatrices(query string, from, to time.Time, step time.Duration) ([]metric.Matrix, error) {
+	promMetric, err := restQueryRange(query, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+	matrices, ok := promMetric.Data.([]metric.Matrix)
+	if !ok {
+		return nil, errors.New("range query did not return a matrix")
+	}
+	return matrices, nil
+}
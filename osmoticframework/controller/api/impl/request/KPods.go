@@ -0,0 +1,386 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/types"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Output of and commands in Kubernetes pods
+	KGetPodLogs reads the last lines of output of the pods of a deployment or a job. KFollowPodLogs streams new lines until the pods stop or the stream is cancelled.
+	Only the first container of each pod is read. Pods created by the framework have a single container.
+	Kubernetes merges stdout and stderr, so LogLine.Stream is empty. LogLine.Pod tells the pods apart.
+	KExecPod runs a command inside a pod, like `kubectl exec`.
+*/
+
+const (
+	defaultKLogTail = 100
+	maxKLogTail     = 10000
+	//Size limit of the output read from each pod
+	maxKLogBytes = 1 << 20
+	//Time limit of reading the output without following
+	kLogReadTimeout = 30 * time.Second
+	//Lines of output attached to crash reports
+	kCrashOutputLines = 20
+	//Size limit of each output stream of a command
+	maxKExecOutput = 64 << 10
+)
+
+type KLogStream struct {
+	Workload types.KWorkload
+	Name     string
+	Lines    chan types.LogLine
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+//Reads the last lines of output of the pods of a workload, oldest first. A zero tail reads the last 100 lines of each pod. A zero since time reads from the start
+//Pods that are not running yet are skipped
+func KGetPodLogs(workload types.KWorkload, name string, tail int, since time.Time) ([]types.LogLine, error) {
+	pods, err := kWorkloadPods(workload, name)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kLogReadTimeout)
+	defer cancel()
+	lines := make([]types.LogLine, 0)
+	var lastErr error
+	read := 0
+	for _, pod := range pods {
+		podLines, err := kReadPodLogs(ctx, pod, kLogOptions(pod, tail, since, false, false))
+		if err != nil {
+			log.Warn.Printf("Failed reading output of pod %s of %s %s\n", pod.Name, workload, name)
+			log.Warn.Println(err)
+			lastErr = err
+			continue
+		}
+		read++
+		lines = append(lines, podLines...)
+	}
+	//Only fail if no pod could be read
+	if read == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	//Pods are read one after another. Interleave their lines
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Time.Before(lines[j].Time)
+	})
+	return lines, nil
+}
+
+/*
+	Follows the output of the pods of a workload. The last tail lines of each pod are sent first, or the lines since the given time
+	Only the pods that exist when the stream starts are followed. Receive the lines like so.
+		for {
+			select {
+			case line := <-stream.Lines:
+				...
+			case <-stream.Done():
+				return
+			}
+		}
+	Lines are dropped if they are not received in time. Cancel the streams that are no longer needed.
+*/
+func KFollowPodLogs(workload types.KWorkload, name string, tail int, since time.Time) (*KLogStream, error) {
+	pods, err := kWorkloadPods(workload, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("%s %s has no running pods", workload, name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &KLogStream{
+		Workload: workload,
+		Name:     name,
+		Lines:    make(chan types.LogLine, logBuffer),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		wg.Add(1)
+		go func(pod corev1.Pod) {
+			defer wg.Done()
+			err := stream.follow(ctx, pod, tail, since)
+			if err != nil && ctx.Err() == nil {
+				log.Warn.Printf("Failed following output of pod %s of %s %s\n", pod.Name, workload, name)
+				log.Warn.Println(err)
+			}
+		}(pod)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(stream.done)
+		log.Info.Printf("Log stream of %s %s ended\n", workload, name)
+	}()
+	log.Info.Printf("Following output of %d pods of %s %s\n", len(pods), workload, name)
+	return stream, nil
+}
+
+//Stops following the pods. Done is closed once all pods are closed
+func (s *KLogStream) Cancel() {
+	s.cancel()
+}
+
+//Closed when the output of all pods ended
+func (s *KLogStream) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *KLogStream) follow(ctx context.Context, pod corev1.Pod, tail int, since time.Time) error {
+	output, err := getKuber().CoreV1().Pods("default").GetLogs(pod.Name, kLogOptions(pod, tail, since, true, false)).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64<<10), maxKLogBytes)
+	for scanner.Scan() {
+		select {
+		case s.Lines <- parseKLogLine(pod.Name, scanner.Text()):
+		case <-ctx.Done():
+			return nil
+		default:
+			log.Warn.Printf("Log stream of %s %s is full. Dropping output\n", s.Workload, s.Name)
+		}
+	}
+	return scanner.Err()
+}
+
+//The last lines of output of a pod, without timestamps. Empty if they cannot be read
+//If previous is set, the output of the last terminated container is read. Used for pods that already restarted
+func KPodOutput(podName string, previous bool) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pod, err := getKuber().CoreV1().Pods("default").Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		log.Warn.Printf("Failed reading output of pod %s\n", podName)
+		log.Warn.Println(err)
+		return nil
+	}
+	lines, err := kReadPodLogs(ctx, *pod, kLogOptions(*pod, kCrashOutputLines, time.Time{}, false, previous))
+	if err != nil {
+		log.Warn.Printf("Failed reading output of pod %s\n", podName)
+		log.Warn.Println(err)
+		return nil
+	}
+	output := make([]string, 0, len(lines))
+	for _, line := range lines {
+		output = append(output, line.Text)
+	}
+	return output
+}
+
+/*
+	Runs a command inside a pod. The command is an array of the program and its arguments, and is not run in a shell
+	An empty container name runs the command in the first container. An error is returned if the command does not end within the timeout in seconds
+	Stdout and stderr are each cut at 64KiB
+*/
+func KExecPod(podName, container string, command []string, timeout float64) (*types.ExecResult, error) {
+	if len(command) == 0 {
+		return nil, errors.New("command is required")
+	}
+	kuber := getKuber()
+	if container == "" {
+		pod, err := kuber.CoreV1().Pods("default").Get(context.Background(), podName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		container = pod.Spec.Containers[0].Name
+	}
+	execRequest := kuber.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace("default").
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(kConfig, "POST", execRequest.URL())
+	if err != nil {
+		return nil, err
+	}
+	log.Info.Printf("Running command in pod %s\n", podName)
+	stdout, stderr := &kCappedBuffer{}, &kCappedBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- executor.Stream(remotecommand.StreamOptions{
+			Stdout: stdout,
+			Stderr: stderr,
+		})
+	}()
+	result := &types.ExecResult{}
+	select {
+	case err = <-done:
+		var exitErr exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitStatus()
+		} else if err != nil {
+			return nil, err
+		}
+	case <-time.After(time.Duration(timeout * float64(time.Second))):
+		//The client-go version in use cannot cancel the stream. It closes when the command ends
+		return nil, fmt.Errorf("command in pod %s timed out", podName)
+	}
+	result.Stdout, result.Truncated = stdout.result()
+	var truncated bool
+	result.Stderr, truncated = stderr.result()
+	result.Truncated = result.Truncated || truncated
+	return result, nil
+}
+
+//Names of the pods of a workload that are not pending
+func KListPods(workload types.KWorkload, name string) ([]string, error) {
+	pods, err := kWorkloadPods(workload, name)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names, nil
+}
+
+//Lists the pods of a workload by its label selector, in name order. Pending pods are skipped
+func kWorkloadPods(workload types.KWorkload, name string) ([]corev1.Pod, error) {
+	kuber := getKuber()
+	var selector *metav1.LabelSelector
+	switch workload {
+	case types.KWorkloadDeployment:
+		deployment, err := kuber.AppsV1().Deployments("default").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = deployment.Spec.Selector
+	case types.KWorkloadJob:
+		job, err := kuber.BatchV1().Jobs("default").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = job.Spec.Selector
+	default:
+		return nil, fmt.Errorf("unknown workload %q", workload)
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := kuber.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{
+		LabelSelector: labelSelector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]corev1.Pod, 0, len(list.Items))
+	for _, pod := range list.Items {
+		if pod.Status.Phase == corev1.PodPending || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	return pods, nil
+}
+
+func kReadPodLogs(ctx context.Context, pod corev1.Pod, options *corev1.PodLogOptions) ([]types.LogLine, error) {
+	raw, err := getKuber().CoreV1().Pods("default").GetLogs(pod.Name, options).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]types.LogLine, 0)
+	for _, text := range strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n") {
+		if text == "" {
+			continue
+		}
+		lines = append(lines, parseKLogLine(pod.Name, text))
+	}
+	return lines, nil
+}
+
+//A zero tail reads the default number of lines. Tails above the maximum are cut
+func kLogOptions(pod corev1.Pod, tail int, since time.Time, follow, previous bool) *corev1.PodLogOptions {
+	if tail <= 0 {
+		tail = defaultKLogTail
+	} else if tail > maxKLogTail {
+		tail = maxKLogTail
+	}
+	limit := int64(maxKLogBytes)
+	tailLines := int64(tail)
+	options := &corev1.PodLogOptions{
+		Container:  pod.Spec.Containers[0].Name,
+		Follow:     follow,
+		Previous:   previous,
+		Timestamps: true,
+		TailLines:  &tailLines,
+	}
+	//The limit would end the stream
+	if !follow {
+		options.LimitBytes = &limit
+	}
+	if !since.IsZero() {
+		sinceTime := metav1.NewTime(since)
+		options.SinceTime = &sinceTime
+	}
+	return options
+}
+
+//Kubernetes starts each line with its timestamp, e.g. "2021-03-04T05:06:07.123456789Z hello"
+func parseKLogLine(pod, raw string) types.LogLine {
+	raw = strings.TrimSuffix(raw, "\r")
+	line := types.LogLine{Pod: pod, Text: raw}
+	parts := strings.SplitN(raw, " ", 2)
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return line
+	}
+	line.Time = timestamp
+	line.Text = ""
+	if len(parts) == 2 {
+		line.Text = parts[1]
+	}
+	return line
+}
+
+//Keeps the first bytes written to it
+type kCappedBuffer struct {
+	buffer    bytes.Buffer
+	truncated bool
+}
+
+func (b *kCappedBuffer) Write(p []byte) (int, error) {
+	space := maxKExecOutput - b.buffer.Len()
+	if len(p) > space {
+		b.truncated = true
+		b.buffer.Write(p[:space])
+	} else {
+		b.buffer.Write(p)
+	}
+	//The command must not fail because its output is not kept
+	return len(p), nil
+}
+
+func (b *kCappedBuffer) result() (string, bool) {
+	return b.buffer.String(), b.truncated
+}
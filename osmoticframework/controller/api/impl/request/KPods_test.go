@@ -0,0 +1,54 @@
+package request
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"osmoticframework/controller/types"
+	"testing"
+	"time"
+)
+
+func TestParseKLogLine(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want types.LogLine
+	}{
+		{"2021-03-04T05:06:07.123456789Z hello world", types.LogLine{Pod: "pod", Time: time.Date(2021, 3, 4, 5, 6, 7, 123456789, time.UTC), Text: "hello world"}},
+		{"2021-03-04T05:06:07Z", types.LogLine{Pod: "pod", Time: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}},
+		{"no timestamp\r", types.LogLine{Pod: "pod", Text: "no timestamp"}},
+	}
+	for _, test := range tests {
+		line := parseKLogLine("pod", test.raw)
+		if line.Pod != test.want.Pod || line.Text != test.want.Text || !line.Time.Equal(test.want.Time) {
+			t.Errorf("Line %q parsed incorrectly. Got %+v, Want %+v", test.raw, line, test.want)
+		}
+	}
+}
+
+func TestKLogOptions(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "first"}, {Name: "second"}}}}
+	tests := []struct {
+		tail      int
+		follow    bool
+		wantTail  int64
+		wantLimit bool
+	}{
+		{0, false, defaultKLogTail, true},
+		{50, true, 50, false},
+		{maxKLogTail + 1, false, maxKLogTail, true},
+	}
+	for _, test := range tests {
+		options := kLogOptions(pod, test.tail, time.Time{}, test.follow, false)
+		if options.Container != "first" {
+			t.Errorf("Container incorrect. Got %s, Want first", options.Container)
+		}
+		if *options.TailLines != test.wantTail {
+			t.Errorf("Tail of %d incorrect. Got %d, Want %d", test.tail, *options.TailLines, test.wantTail)
+		}
+		if (options.LimitBytes != nil) != test.wantLimit {
+			t.Errorf("Byte limit with follow %v incorrect. Got %v, Want %v", test.follow, options.LimitBytes != nil, test.wantLimit)
+		}
+		if options.SinceTime != nil || !options.Timestamps {
+			t.Errorf("Options incorrect. Got %+v", options)
+		}
+	}
+}
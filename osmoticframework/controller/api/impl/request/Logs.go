@@ -0,0 +1,232 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/lithammer/shortuuid"
+	"github.com/streadway/amqp"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/queue"
+	"osmoticframework/controller/types"
+	"sync"
+	"time"
+)
+
+/*
+	Container logs
+	LogsRequest reads the last lines of output of a container on an agent. The result is []types.LogLine
+	FollowLogs streams new lines of output from the agent to the queue of the stream, until the container stops or the stream is cancelled.
+	Receive the lines from LogStream.Lines like so.
+		for {
+			select {
+			case line := <-stream.Lines:
+				...
+			case <-stream.Done():
+				return
+			}
+		}
+	Lines are dropped if they are not received in time. Cancel the streams that are no longer needed.
+*/
+
+//Number of lines held for the receiver
+const logBuffer = 1000
+
+type LogStream struct {
+	ID          string
+	AgentId     string
+	ContainerId string
+	Lines       chan types.LogLine
+	//Set if the stream ended because the agent failed reading the output
+	Err  error
+	done chan struct{}
+	once sync.Once
+}
+
+//A message pushed by the agent to the queue of a stream
+type logMessage struct {
+	Status string          `json:"status"`
+	Lines  []types.LogLine `json:"lines"`
+	Error  string          `json:"error"`
+}
+
+//Stream ID -> *LogStream
+var LogStreams sync.Map
+
+//Reads the last lines of output of a container. A zero tail uses the default of the agent (100 lines). A zero since time reads from the start
+func LogsRequest(agentId, containerId string, tail int, since time.Time, timeout float64) *RequestTask {
+	log.Info.Printf("%s << Logs request of container %s\n", agentId, containerId)
+	return deployRequest("logs", agentId, logsArgs(containerId, tail, since), timeout)
+}
+
+/*
+	Follows the output of a container. The last tail lines are sent first, or the lines since the given time
+	This waits for the agent to accept the stream. The timeout is the timeout of the request to the agent.
+*/
+func FollowLogs(agentId, containerId string, tail int, since time.Time, timeout float64) (*LogStream, error) {
+	var id string
+	for true {
+		id = shortuuid.New()
+		if _, ok := LogStreams.Load(id); !ok {
+			break
+		}
+	}
+	stream := &LogStream{
+		ID:          id,
+		AgentId:     agentId,
+		ContainerId: containerId,
+		Lines:       make(chan types.LogLine, logBuffer),
+		done:        make(chan struct{}),
+	}
+	//The queue must exist before the agent pushes lines
+	err := stream.consume()
+	if err != nil {
+		log.Error.Println("Failed declaring queue " + stream.Queue())
+		log.Error.Println(err)
+		return nil, err
+	}
+	LogStreams.Store(id, stream)
+	args := logsArgs(containerId, tail, since)
+	args["follow"] = true
+	args["streamId"] = id
+	log.Info.Printf("%s << Following output of container %s\n", agentId, containerId)
+	result := Await(deployRequest("logs", agentId, args, timeout))
+	if result.ResultType == Error {
+		stream.End()
+		return nil, result.Content.(error)
+	}
+	return stream, nil
+}
+
+//Asks the agent to stop the stream. The stream ends even if the agent cannot be reached
+func (s *LogStream) Cancel(timeout float64) error {
+	defer s.End()
+	result := Await(deployRequest("unfollow", s.AgentId, map[string]interface{}{"streamId": s.ID}, timeout))
+	if result.ResultType == Error {
+		return result.Content.(error)
+	}
+	return nil
+}
+
+//Ends the stream on the controller only, and removes its queue. Use Cancel to stop the stream on the agent
+func (s *LogStream) End() {
+	s.once.Do(func() {
+		LogStreams.Delete(s.ID)
+		close(s.done)
+		err := queue.DeleteQueue(s.Queue())
+		if err != nil {
+			log.Warn.Println("Failed deleting queue " + s.Queue())
+			log.Warn.Println(err)
+		}
+		log.Info.Printf("%s >> Log stream %s ended\n", s.AgentId, s.ID)
+	})
+}
+
+//Closed when the stream ends
+func (s *LogStream) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *LogStream) Queue() string {
+	return "logs-" + s.ID
+}
+
+//Declares the queue of the stream and listens to it
+func (s *LogStream) consume() error {
+	_, err := queue.DeclareExpireQueue(s.Queue(), subscriptionQueueExpiry)
+	if err != nil {
+		return err
+	}
+	stream, err := queue.NewConsumer(s.Queue())
+	if err != nil {
+		return err
+	}
+	go func() {
+		for message := range stream {
+			s.receive(message)
+		}
+	}()
+	return nil
+}
+
+//Only messages signed by the agent of the stream are accepted
+func (s *LogStream) receive(message amqp.Delivery) {
+	_ = message.Ack(false)
+	agentId, err := queue.Verify(s.Queue(), message)
+	if err != nil {
+		log.Warn.Printf("Dropped message on queue %s from agent %q: %s\n", s.Queue(), agentId, err)
+		return
+	}
+	if agentId != s.AgentId {
+		log.Warn.Printf("%s >> Agent pushed output to log stream %s of agent %s. Ignoring\n", agentId, s.ID, s.AgentId)
+		return
+	}
+	s.parse(message.Body)
+}
+
+func (s *LogStream) parse(body []byte) {
+	var content logMessage
+	err := json.Unmarshal(body, &content)
+	if err != nil {
+		log.Warn.Printf("%s >> Cannot decode output of log stream %s\n", s.AgentId, s.ID)
+		log.Warn.Println(err)
+		return
+	}
+	switch content.Status {
+	case "ok":
+		for _, line := range content.Lines {
+			select {
+			case <-s.done:
+				return
+			case s.Lines <- line:
+			default:
+				log.Warn.Printf("%s >> Log stream %s is full. Dropping output\n", s.AgentId, s.ID)
+				return
+			}
+		}
+	case "ended":
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		if content.Error != "" {
+			s.Err = errors.New(content.Error)
+		}
+		s.End()
+	}
+}
+
+//Declares the queues of all log streams again and listens to them. Used after reconnecting to RabbitMQ
+func ResumeLogStreams() {
+	LogStreams.Range(func(_, stream interface{}) bool {
+		err := stream.(*LogStream).consume()
+		if err != nil {
+			log.Error.Println("Failed resuming log stream " + stream.(*LogStream).ID)
+			log.Error.Println(err)
+		}
+		return true
+	})
+}
+
+//Ends all log streams of an agent. Used when the agent disconnects
+func EndLogStreams(agentId string) {
+	LogStreams.Range(func(_, stream interface{}) bool {
+		if stream.(*LogStream).AgentId == agentId {
+			stream.(*LogStream).End()
+		}
+		return true
+	})
+}
+
+func logsArgs(containerId string, tail int, since time.Time) map[string]interface{} {
+	args := map[string]interface{}{
+		"containerId": containerId,
+	}
+	if tail > 0 {
+		args["tail"] = tail
+	}
+	if !since.IsZero() {
+		args["since"] = since.Unix()
+	}
+	return args
+}
@@ -0,0 +1,40 @@
+package request
+
+import (
+	"osmoticframework/controller/types"
+	"testing"
+	"time"
+)
+
+func TestLogStreamParse(t *testing.T) {
+	stream := &LogStream{
+		ID:      "stream",
+		AgentId: "agent",
+		Lines:   make(chan types.LogLine, 2),
+		done:    make(chan struct{}),
+	}
+	stream.parse([]byte(`{"streamId": "stream", "status": "ok", "lines": [
+		{"stream": "stdout", "time": "2021-03-04T05:06:07.5Z", "text": "round 1"},
+		{"stream": "stderr", "time": "2021-03-04T05:06:08Z", "text": "warning"},
+		{"stream": "stdout", "time": "2021-03-04T05:06:09Z", "text": "dropped"}
+	]}`))
+	want := []types.LogLine{
+		{Stream: "stdout", Time: time.Date(2021, 3, 4, 5, 6, 7, 5e8, time.UTC), Text: "round 1"},
+		{Stream: "stderr", Time: time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC), Text: "warning"},
+	}
+	//The buffer holds two lines. The rest is dropped
+	if len(stream.Lines) != len(want) {
+		t.Fatalf("Number of lines incorrect. Got %d, Want %d", len(stream.Lines), len(want))
+	}
+	for i := range want {
+		line := <-stream.Lines
+		if line.Stream != want[i].Stream || line.Text != want[i].Text || !line.Time.Equal(want[i].Time) {
+			t.Errorf("Line %d incorrect. Got %+v, Want %+v", i, line, want[i])
+		}
+	}
+	select {
+	case <-stream.Done():
+		t.Errorf("Stream ended before the agent ended it")
+	default:
+	}
+}
@@ -3,7 +3,6 @@ package request
 import (
 	"encoding/json"
 	"github.com/lithammer/shortuuid"
-	"github.com/streadway/amqp"
 	"osmoticframework/controller/log"
 	"osmoticframework/controller/queue"
 	"time"
@@ -39,6 +38,11 @@ func MemoryEdgeRequest(agentId string, timestamp time.Time, timeout float64) *Re
 	return edgeMonitorRequest(command, agentId, timestamp, timeout)
 }
 
+func MemoryEdgeTotalRequest(agentId string, timestamp time.Time, timeout float64) *RequestTask {
+	const command = "memory_edge_total"
+	return edgeMonitorRequest(command, agentId, timestamp, timeout)
+}
+
 func MemoryContainerRequest(agentId, containerId string, timestamp time.Time, timeout float64) *RequestTask {
 	const command = "memory_container"
 	return containerMonitorRequest(command, agentId, containerId, timestamp, timeout)
@@ -184,72 +188,241 @@ func ThermalRequest(agentId string, timestamp time.Time, timeout float64) *Reque
 	return edgeMonitorRequest(command, agentId, timestamp, timeout)
 }
 
+//Runs a PromQL query on the agent's Prometheus. The result is a metric.PromMetric
+//The agent must enable PromQL queries, and may only allow some metrics
+func PromQLEdgeRequest(agentId, query string, timestamp time.Time, timeout float64) *RequestTask {
+	const command = "promql"
+	return monitorRequest(command, agentId, map[string]interface{}{
+		"edge":  agentId,
+		"time":  timestamp.Unix(),
+		"query": query,
+	}, timeout)
+}
+
+//Range variants of the requests above. The agent returns a point every step from "from" to "to"
+//The results are series instead of single values. See types/metric/SeriesMetric.go
+
+func CPUEdgeAvgRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "cpu_edge_avg"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func CPUContainerAvgRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "cpu_container_avg"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func CPUTimeRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "cpu_time"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func CPUUtilizationRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "cpu_utilization"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func MemoryEdgeRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "memory_edge"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func MemoryEdgeTotalRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "memory_edge_total"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func MemoryContainerRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "memory_container"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func MemoryEdgePeakRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "memory_edge_peak"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func MemoryContainerPeakRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "memory_container_peak"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func MemoryContainerLimitSecondsRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "memory_container_limit_seconds"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func IOEdgeTimeRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "io_edge_time"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func IOContainerTimeRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "io_container_time"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func IOEdgeReadRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "io_edge_read"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func IOContainerReadRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "io_container_read"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func IOEdgeWriteRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "io_edge_write"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func IOContainerWriteRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "io_container_write"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func IOFilesystemUsedRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "io_filesystem_used"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func IOFilesystemSizeRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "io_filesystem_size"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetEdgeRxBytesRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_edge_rx_bytes"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetEdgeRxPacketsRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_edge_rx_packets"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetEdgeRxDroppedRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_edge_rx_dropped"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetEdgeRxErrorRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_edge_rx_error"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetEdgeTxBytesRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_edge_tx_bytes"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetEdgeTxPacketsRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_edge_tx_packets"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetEdgeTxDroppedRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_edge_tx_dropped"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetEdgeTxErrorRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_edge_tx_error"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func NetContainerRxBytesRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_container_rx_bytes"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func NetContainerRxPacketsRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_container_rx_packets"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func NetContainerRxDroppedRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_container_rx_dropped"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func NetContainerRxErrorRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_container_rx_error"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func NetContainerTxBytesRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_container_tx_bytes"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func NetContainerTxPacketsRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_container_tx_packets"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func NetContainerTxDroppedRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_container_tx_dropped"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func NetContainerTxErrorRangeRequest(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "net_container_tx_error"
+	return containerMonitorRangeRequest(command, agentId, containerId, from, to, step, timeout)
+}
+
+func ThermalRangeRequest(agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "thermal"
+	return edgeMonitorRangeRequest(command, agentId, from, to, step, timeout)
+}
+
+func PromQLEdgeRangeRequest(agentId, query string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	const command = "promql"
+	args := rangeArgs(agentId, from, to, step)
+	args["query"] = query
+	return monitorRequest(command, agentId, args, timeout)
+}
+
 func containerMonitorRequest(command, agentId, containerId string, timestamp time.Time, timeout float64) *RequestTask {
-	var id string
-	for true {
-		id := shortuuid.New()
-		if _, ok := MonitorRequests.Load(id); !ok {
-			break
-		}
-	}
-	request, err := json.Marshal(Request{
-		RequestID: id,
-		Command:   command,
-		Args: map[string]interface{}{
-			"edge":        agentId,
-			"time":        timestamp.Unix(),
-			"containerId": containerId,
-		},
-	})
-	if err != nil {
-		log.Error.Println("Failed constructing monitor API command")
-		log.Error.Println(err)
-		return nil
-	}
-	err = queue.Ch.Publish(
-		"",
-		"monitor-"+agentId,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        request,
-		},
-	)
-	if err != nil {
-		log.Error.Println("Failed sending monitor API request")
-		log.Error.Println(err)
-		return nil
-	}
-	MonitorRequests.Store(id, ImplRequestTask{
-		AgentId: agentId,
-		API:     "monitor",
-		Command: command,
-		Ack:     false,
-		Time:    time.Now(),
-		Timeout: timeout,
-	})
-	task := RequestTask{
-		AgentId: agentId,
-		API:     "monitor",
-		Command: command,
-		Time:    time.Now(),
-		Args: map[string]interface{}{
-			"edge":        agentId,
-			"time":        timestamp.Unix(),
-			"containerId": containerId,
-		},
-		Timeout: timeout,
-		Result:  make(chan Result, 1),
-	}
-	MonitorTaskList.Store(id, task)
-	return &task
+	return monitorRequest(command, agentId, map[string]interface{}{
+		"edge":        agentId,
+		"time":        timestamp.Unix(),
+		"containerId": containerId,
+	}, timeout)
 }
 
 func edgeMonitorRequest(command, agentId string, timestamp time.Time, timeout float64) *RequestTask {
+	return monitorRequest(command, agentId, map[string]interface{}{
+		"edge": agentId,
+		"time": timestamp.Unix(),
+	}, timeout)
+}
+
+func containerMonitorRangeRequest(command, agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	args := rangeArgs(agentId, from, to, step)
+	args["containerId"] = containerId
+	return monitorRequest(command, agentId, args, timeout)
+}
+
+func edgeMonitorRangeRequest(command, agentId string, from, to time.Time, step time.Duration, timeout float64) *RequestTask {
+	return monitorRequest(command, agentId, rangeArgs(agentId, from, to, step), timeout)
+}
+
+//Prometheus smallest scale in time is second. The step is sent in seconds
+func rangeArgs(agentId string, from, to time.Time, step time.Duration) map[string]interface{} {
+	return map[string]interface{}{
+		"edge": agentId,
+		"from": from.Unix(),
+		"to":   to.Unix(),
+		"step": int64(step.Seconds()),
+	}
+}
+
+func monitorRequest(command, agentId string, args map[string]interface{}, timeout float64) *RequestTask {
 	var id string
 	for true {
-		id := shortuuid.New()
+		id = shortuuid.New()
 		if _, ok := MonitorRequests.Load(id); !ok {
 			break
 		}
@@ -258,26 +431,14 @@ func edgeMonitorRequest(command, agentId string, timestamp time.Time, timeout fl
 	request, err := json.Marshal(Request{
 		RequestID: id,
 		Command:   command,
-		Args: map[string]interface{}{
-			"edge": agentId,
-			"time": timestamp.Unix(),
-		},
+		Args:      args,
 	})
 	if err != nil {
 		log.Error.Println("Failed constructing monitor API command")
 		log.Error.Println(err)
 		return nil
 	}
-	err = queue.Ch.Publish(
-		"",
-		"monitor-"+agentId,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        request,
-		},
-	)
+	err = queue.PublishRequest(id, agentId, "monitor-"+agentId, request)
 	if err != nil {
 		log.Error.Println("Failed sending monitor API request")
 		log.Error.Println(err)
@@ -289,6 +450,7 @@ func edgeMonitorRequest(command, agentId string, timestamp time.Time, timeout fl
 		Command: command,
 		Ack:     false,
 		Time:    time.Now(),
+		Args:    args,
 		Timeout: timeout,
 	})
 	task := RequestTask{
@@ -296,10 +458,7 @@ func edgeMonitorRequest(command, agentId string, timestamp time.Time, timeout fl
 		API:     "monitor",
 		Command: command,
 		Time:    time.Now(),
-		Args: map[string]interface{}{
-			"edge": agentId,
-			"time": timestamp.Unix(),
-		},
+		Args:    args,
 		Timeout: timeout,
 		Result:  make(chan Result, 1),
 	}
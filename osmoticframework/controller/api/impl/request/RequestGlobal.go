@@ -1,6 +1,7 @@
 package request
 
 import (
+	"errors"
 	"sync"
 	"time"
 )
@@ -58,6 +59,9 @@ type Result struct {
 
 type ResultType string
 
+//Extra time given to a request on top of its timeout before Await gives up
+const awaitGrace = 5 * time.Second
+
 const (
 	Error ResultType = "error"
 	Ok    ResultType = "ok"
@@ -70,6 +74,16 @@ type RegisterRequest struct {
 	InternalIP    string   `json:"internalIP"`
 	DeviceSupport []string `json:"devSupport"`
 	SensorSupport []string `json:"sensorSupport"`
+	//Set when an agent rejoins with the ID of a previous session
+	AgentId string `json:"agentId"`
+	//Containers running on a rejoining agent
+	Containers []string `json:"containers"`
+	//Bootstrap token of a new agent. Required if registration authentication is enabled
+	Token string `json:"token"`
+	//Queue of the reply. Only the agent can read it, as the reply may carry a new secret
+	ReplyTo string `json:"replyTo"`
+	//Set if a rejoining agent signed the request with the secret of its agent ID
+	Authenticated bool `json:"-"`
 }
 
 /*
@@ -95,3 +109,21 @@ var MonitorRequests sync.Map
 //This stores all request tasks objects.
 var DeployTaskList sync.Map
 var MonitorTaskList sync.Map
+
+/*
+	Waits for the result of a request.
+	The timeout cleanup in the API only removes requests that are not acknowledged. An agent that disconnects after acknowledging a request never replies,
+	so this gives up once the request timeout has passed, instead of blocking forever.
+	A nil task means the request could not be sent.
+*/
+func Await(task *RequestTask) Result {
+	if task == nil {
+		return Result{ResultType: Error, Content: errors.New("failed sending request")}
+	}
+	select {
+	case result := <-task.Result:
+		return result
+	case <-time.After(time.Duration(task.Timeout*float64(time.Second)) + awaitGrace):
+		return Result{ResultType: Error, Content: errors.New("timeout")}
+	}
+}
@@ -0,0 +1,234 @@
+package request
+
+import (
+	"errors"
+	"github.com/lithammer/shortuuid"
+	"github.com/streadway/amqp"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/queue"
+	"sync"
+	"time"
+)
+
+/*
+	Metric subscriptions
+	Instead of polling an agent with monitor requests, the controller subscribes to a set of edge metrics at an interval.
+	The agent pushes samples to the queue of the subscription until the subscription is cancelled, runs out or the agent disconnects.
+	Receive the samples from Subscription.Samples like so.
+		for {
+			select {
+			case sample := <-sub.Samples:
+				...
+			case <-sub.Done():
+				return
+			}
+		}
+	Samples are dropped if they are not received in time. Cancel the subscriptions that are no longer needed.
+*/
+
+//Queues of subscriptions are removed by RabbitMQ if the controller stops consuming them, e.g. after a crash
+const subscriptionQueueExpiry = 60000
+
+//Number of samples held for the receiver
+const sampleBuffer = 100
+
+//A metric to subscribe to. The container ID is required for container metrics
+type SubscribedMetric struct {
+	Command     string
+	ContainerId string
+}
+
+//A value of a subscribed metric
+type Sample struct {
+	SubscriptionId string
+	AgentId        string
+	Command        string
+	ContainerId    string
+	Time           time.Time
+	//Same type as the result of the monitor request of the command. Nil if the agent failed querying the metric
+	Metric interface{}
+	Err    error
+}
+
+type Subscription struct {
+	ID       string
+	AgentId  string
+	Metrics  []SubscribedMetric
+	Interval time.Duration
+	//Zero if the subscription runs until cancelled
+	Duration time.Duration
+	Samples  chan Sample
+	done     chan struct{}
+	once     sync.Once
+}
+
+//A message received on the queue of a subscription. The message is already verified
+type SampleMessage struct {
+	Subscription *Subscription
+	Body         []byte
+}
+
+//Subscription ID -> *Subscription
+var Subscriptions sync.Map
+
+//Messages from the subscription queues. These are parsed by the API listener
+var SampleQueue = make(chan SampleMessage)
+
+/*
+	Subscribes to metrics of an agent. The agent queries the metrics every interval, starting right away.
+	The subscription ends by itself after the duration. A zero duration runs the subscription until it is cancelled.
+	This waits for the agent to accept the subscription. The timeout is the timeout of the request to the agent.
+*/
+func Subscribe(agentId string, metrics []SubscribedMetric, interval, duration time.Duration, timeout float64) (*Subscription, error) {
+	if len(metrics) == 0 {
+		return nil, errors.New("no metrics to subscribe to")
+	}
+	//Prometheus smallest scale in time is second
+	if interval < time.Second {
+		return nil, errors.New("interval must be at least 1 second")
+	}
+	var id string
+	for true {
+		id = shortuuid.New()
+		if _, ok := Subscriptions.Load(id); !ok {
+			break
+		}
+	}
+	sub := &Subscription{
+		ID:       id,
+		AgentId:  agentId,
+		Metrics:  metrics,
+		Interval: interval,
+		Duration: duration,
+		Samples:  make(chan Sample, sampleBuffer),
+		done:     make(chan struct{}),
+	}
+	//The queue must exist before the agent pushes samples
+	err := sub.consume()
+	if err != nil {
+		log.Error.Println("Failed declaring queue " + sub.Queue())
+		log.Error.Println(err)
+		return nil, err
+	}
+	Subscriptions.Store(id, sub)
+	metricArgs := make([]map[string]interface{}, 0)
+	for _, metric := range metrics {
+		args := map[string]interface{}{"command": metric.Command}
+		if metric.ContainerId != "" {
+			args["containerId"] = metric.ContainerId
+		}
+		metricArgs = append(metricArgs, args)
+	}
+	args := map[string]interface{}{
+		"subscriptionId": id,
+		"metrics":        metricArgs,
+		"interval":       interval.Seconds(),
+	}
+	if duration > 0 {
+		args["duration"] = duration.Seconds()
+	}
+	result := Await(monitorRequest("subscribe", agentId, args, timeout))
+	if result.ResultType == Error {
+		sub.End()
+		return nil, result.Content.(error)
+	}
+	return sub, nil
+}
+
+//Asks the agent to stop the subscription. The subscription ends even if the agent cannot be reached
+func (s *Subscription) Cancel(timeout float64) error {
+	defer s.End()
+	result := Await(monitorRequest("unsubscribe", s.AgentId, map[string]interface{}{"subscriptionId": s.ID}, timeout))
+	if result.ResultType == Error {
+		return result.Content.(error)
+	}
+	return nil
+}
+
+//Ends the subscription on the controller only, and removes its queue. Use Cancel to stop the subscription on the agent
+func (s *Subscription) End() {
+	s.once.Do(func() {
+		Subscriptions.Delete(s.ID)
+		close(s.done)
+		err := queue.DeleteQueue(s.Queue())
+		if err != nil {
+			log.Warn.Println("Failed deleting queue " + s.Queue())
+			log.Warn.Println(err)
+		}
+		log.Info.Printf("%s >> Subscription %s ended\n", s.AgentId, s.ID)
+	})
+}
+
+//Closed when the subscription ends
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+//Passes a sample to the receiver. The sample is dropped if the receiver is falling behind
+func (s *Subscription) Deliver(sample Sample) {
+	select {
+	case <-s.done:
+	case s.Samples <- sample:
+	default:
+		log.Warn.Printf("%s >> Subscription %s is full. Dropping sample\n", s.AgentId, s.ID)
+	}
+}
+
+func (s *Subscription) Queue() string {
+	return "subscription-" + s.ID
+}
+
+//Declares the queue of the subscription and listens to it
+//Only messages signed by the subscribed agent are passed to the SampleQueue
+func (s *Subscription) consume() error {
+	_, err := queue.DeclareExpireQueue(s.Queue(), subscriptionQueueExpiry)
+	if err != nil {
+		return err
+	}
+	stream, err := queue.NewConsumer(s.Queue())
+	if err != nil {
+		return err
+	}
+	go func() {
+		for message := range stream {
+			s.receive(message)
+		}
+	}()
+	return nil
+}
+
+func (s *Subscription) receive(message amqp.Delivery) {
+	_ = message.Ack(false)
+	agentId, err := queue.Verify(s.Queue(), message)
+	if err != nil {
+		log.Warn.Printf("Dropped message on queue %s from agent %q: %s\n", s.Queue(), agentId, err)
+		return
+	}
+	if agentId != s.AgentId {
+		log.Warn.Printf("%s >> Agent pushed a sample to subscription %s of agent %s. Ignoring\n", agentId, s.ID, s.AgentId)
+		return
+	}
+	SampleQueue <- SampleMessage{Subscription: s, Body: message.Body}
+}
+
+//Declares the queues of all subscriptions again and listens to them. Used after reconnecting to RabbitMQ
+func ResumeSubscriptions() {
+	Subscriptions.Range(func(_, sub interface{}) bool {
+		err := sub.(*Subscription).consume()
+		if err != nil {
+			log.Error.Println("Failed resuming subscription " + sub.(*Subscription).ID)
+			log.Error.Println(err)
+		}
+		return true
+	})
+}
+
+//Ends all subscriptions to an agent. Used when the agent disconnects
+func EndSubscriptions(agentId string) {
+	Subscriptions.Range(func(_, sub interface{}) bool {
+		if sub.(*Subscription).AgentId == agentId {
+			sub.(*Subscription).End()
+		}
+		return true
+	})
+}
@@ -9,7 +9,9 @@ import (
 	"osmoticframework/controller/alert"
 	"osmoticframework/controller/api/impl/request"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
 	"osmoticframework/controller/types"
+	"strings"
 	"sync"
 	"time"
 )
@@ -174,41 +176,41 @@ func JobWatch() {
 				if event.Status.Succeeded == 1 {
 					if ok {
 						log.Info.Printf("Job %s of cronjob %s has finished execution\n", event.Name, cronjob)
-						alert.ContainerCrash <- types.ContainerCrashReport{
+						crashed(types.ContainerCrashReport{
 							AgentId:  "cloud-cronjob",
 							ID:       cronjob,
 							Name:     event.Name,
 							Status:   "exited",
 							ExitCode: 0,
-						}
+						})
 					} else {
 						log.Info.Printf("Job %s has finished execution\n", event.Name)
-						alert.ContainerCrash <- types.ContainerCrashReport{
+						crashed(types.ContainerCrashReport{
 							AgentId:  "cloud-job",
 							ID:       event.Name,
 							Status:   "exited",
 							ExitCode: 0,
-						}
+						})
 					}
 				} else if event.Status.Failed == 1 {
 					if ok {
 						log.Warn.Printf("Job %s of cronjob %s has failed. Maximum back off limit reached\n", event.Name, cronjob)
 						log.Warn.Printf("Cronjob %s has failed scheduled execution\n", cronjob)
-						alert.ContainerCrash <- types.ContainerCrashReport{
+						crashed(types.ContainerCrashReport{
 							AgentId:  "cloud-cronjob",
 							ID:       cronjob,
 							Name:     event.Name,
 							Status:   "error",
 							ExitCode: -1,
-						}
+						})
 					} else {
 						log.Warn.Printf("Job %s has failed execution. Maximum back off limit reached\n", event.Name)
-						alert.ContainerCrash <- types.ContainerCrashReport{
+						crashed(types.ContainerCrashReport{
 							AgentId:  "cloud-job",
 							ID:       event.Name,
 							Status:   "error",
 							ExitCode: -1,
-						}
+						})
 					}
 				} else if event.Status.Active == 1 {
 					if ok {
@@ -278,6 +280,8 @@ func (pw *PodWatcher) Monitor(deploymentName, deploymentId string) {
 				continue
 			}
 			log.Info.Printf("Started Kubernetes pod watcher for deployment %s\n", deploymentName)
+			//Last reported phase and status of each pod. Pods are modified far more often than their state changes
+			states := make(map[string]string)
 			for _event := range pw.watcher.ResultChan() {
 				eventType := _event.Type
 				event := _event.Object.(*corev1.Pod)
@@ -292,6 +296,23 @@ func (pw *PodWatcher) Monitor(deploymentName, deploymentId string) {
 						status = state.Waiting.Reason
 					}
 				}
+				if eventType == watch.Added || eventType == watch.Modified || eventType == watch.Deleted {
+					state := string(event.Status.Phase) + "/" + status
+					if eventType == watch.Deleted || states[event.Name] != state {
+						alert.PodStatus.Publish(types.PodStatusReport{
+							Deployment: deploymentName,
+							Pod:        event.Name,
+							Event:      strings.ToLower(string(eventType)),
+							Phase:      string(event.Status.Phase),
+							Status:     status,
+						})
+					}
+					if eventType == watch.Deleted {
+						delete(states, event.Name)
+					} else {
+						states[event.Name] = state
+					}
+				}
 				switch eventType {
 				case watch.Added:
 					//Usually refers to new pod scheduled, or the pods from the start of the watch
@@ -311,24 +332,24 @@ func (pw *PodWatcher) Monitor(deploymentName, deploymentId string) {
 						} else if status == "CrashLoopBackOff" {
 							log.Info.Printf("Pod %s of deployment %s has entered backoff state\n", event.Name, deploymentName)
 						} else if status == "Error" {
-							alert.ContainerCrash <- types.ContainerCrashReport{
+							crashedPod(types.ContainerCrashReport{
 								AgentId:  "cloud-deployment",
 								ID:       deploymentName,
 								Name:     event.Name,
 								Image:    event.Spec.Containers[0].Image,
 								Status:   "error",
 								ExitCode: int(event.Status.ContainerStatuses[0].State.Terminated.ExitCode),
-							}
+							}, event)
 							log.Warn.Printf("Pod %s of deployment %s has crashed\n", event.Name, deploymentName)
 						} else if status == "Completed" {
-							alert.ContainerCrash <- types.ContainerCrashReport{
+							crashedPod(types.ContainerCrashReport{
 								AgentId:  "cloud-deployment",
 								ID:       deploymentName,
 								Name:     event.Name,
 								Image:    event.Spec.Containers[0].Image,
 								Status:   "exited",
 								ExitCode: 0,
-							}
+							}, event)
 							log.Warn.Printf("Pod %s of deployment %s has exited with code 0. You should use Jobs for one time executed applications", event.Name, deploymentName)
 						} else {
 							log.Info.Printf("Pod %s of deployment %s status unknown. Attempt dumping all info\n", event.Name, deploymentName)
@@ -343,26 +364,26 @@ func (pw *PodWatcher) Monitor(deploymentName, deploymentId string) {
 							log.Info.Printf("Pod %s of deployment %s has entered back off state from image pulling\n", event.Name, deploymentName)
 						}
 					} else if event.Status.Phase == corev1.PodSucceeded {
-						alert.ContainerCrash <- types.ContainerCrashReport{
+						crashedPod(types.ContainerCrashReport{
 							AgentId:  "cloud-deployment",
 							ID:       deploymentName,
 							Name:     event.Name,
 							Image:    event.Spec.Containers[0].Image,
 							Status:   "exited",
 							ExitCode: 0,
-						}
+						}, event)
 						log.Warn.Printf("Pod %s of deployment %s has exited with code 0. You should use Jobs for one time executed applications\n", event.Name, deploymentName)
 					} else if event.Status.Phase == corev1.PodFailed {
 						if status == "Evicted" {
 							log.Error.Printf("Pod %s of deployment %s has been evicted. Cluster is dangerously low on resources!\n", event.Name, deploymentName)
-							alert.ContainerCrash <- types.ContainerCrashReport{
+							crashed(types.ContainerCrashReport{
 								AgentId:  "cloud-deployment",
 								ID:       deploymentName,
 								Name:     event.Name,
 								Image:    event.Spec.Containers[0].Image,
 								Status:   "evicted",
 								ExitCode: -1,
-							}
+							})
 						} else {
 							log.Info.Printf("Pod %s of deployment %s status unknown. Attempt dumping all info\n", event.Name, deploymentName)
 							log.Info.Printf("%#v", event.Status)
@@ -386,3 +407,20 @@ func (pw *PodWatcher) Monitor(deploymentName, deploymentId string) {
 		}
 	}()
 }
+
+//Raises a container crash alert
+func crashed(report types.ContainerCrashReport) {
+	metrics.Alerts.Inc("container-crash")
+	alert.ContainerCrash.Publish(report)
+}
+
+//Raises a container crash alert of a deployment pod, with the last lines of output of the pod
+//The output is read in the background, so that the watch is not held up
+func crashedPod(report types.ContainerCrashReport, pod *corev1.Pod) {
+	//Once Kubernetes restarted the container, the output of the crash is in the previous instance
+	previous := len(pod.Status.ContainerStatuses) != 0 && pod.Status.ContainerStatuses[0].RestartCount > 0
+	go func() {
+		report.Output = request.KPodOutput(report.Name, previous)
+		crashed(report)
+	}()
+}
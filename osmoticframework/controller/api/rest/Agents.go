@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/database"
+	"osmoticframework/controller/scheduler"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//Edge endpoints. These wrap the deploy and monitor API of the agents
+//	GET    /agents
+//	GET    /agents/{agentId}
+//	GET    /agents/{agentId}/containers
+//	POST   /agents/{agentId}/containers
+//	GET    /agents/{agentId}/containers/{containerId}
+//	PUT    /agents/{agentId}/containers/{containerId}
+//	DELETE /agents/{agentId}/containers/{containerId}?deleteImage=true
+//	POST   /agents/{agentId}/containers/{containerId}/stop
+//	GET    /agents/{agentId}/containers/{containerId}/spec
+//	GET    /agents/{agentId}/containers/{containerId}/logs?tail=&since=
+//	POST   /agents/{agentId}/containers/{containerId}/exec
+//	GET    /agents/{agentId}/metrics/{command}?time=&from=&to=&step=&containerId=
+//	GET    /agents/{agentId}/metrics/promql?query=&time=&from=&to=&step=
+//	POST   /agents/{agentId}/revoke
+
+//An agent as shown by the REST API
+type agentView struct {
+	ID string `json:"ID"`
+	types.Agent
+}
+
+//Request body for running and updating containers
+type deployBody struct {
+	DeployArgs types.DeployArgs `json:"deployArgs"`
+	AuthInfo   types.AuthInfo   `json:"authInfo"`
+	//Timeout in seconds. Pulling images can take a long time, so the default is longer than other requests
+	Timeout float64 `json:"timeout"`
+}
+
+//Request body for running a command inside a container
+type execBody struct {
+	//The program and its arguments. This is not run in a shell
+	Command []string `json:"command"`
+	//Timeout of the command in seconds
+	Timeout float64 `json:"timeout"`
+}
+
+func agentsHandler(w http.ResponseWriter, r *http.Request) {
+	segments := pathSegments(r.URL.Path, apiPrefix+"/agents")
+	if len(segments) == 0 {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w)
+			return
+		}
+		writeOk(w, listAgents())
+		return
+	}
+	agentId := segments[0]
+	if len(segments) == 2 && segments[1] == "revoke" {
+		revokeHandler(w, r, agentId)
+		return
+	}
+	_agent, ok := vars.Agents.Load(agentId)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("agent not found"))
+		return
+	}
+	if len(segments) == 1 {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w)
+			return
+		}
+		writeOk(w, agentView{ID: agentId, Agent: _agent.(types.Agent)})
+		return
+	}
+	switch segments[1] {
+	case "containers":
+		containersHandler(w, r, agentId, segments[2:])
+	case "metrics":
+		edgeMetricsHandler(w, r, agentId, segments[2:])
+	default:
+		notFound(w)
+	}
+}
+
+//Lists all registered agents, sorted by agent ID
+func listAgents() []agentView {
+	agents := make([]agentView, 0)
+	vars.Agents.Range(func(agentId, agent interface{}) bool {
+		agents = append(agents, agentView{ID: agentId.(string), Agent: agent.(types.Agent)})
+		return true
+	})
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].ID < agents[j].ID
+	})
+	return agents
+}
+
+func containersHandler(w http.ResponseWriter, r *http.Request, agentId string, segments []string) {
+	var task *request.RequestTask
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		task = request.ListRequest(agentId, parseTimeout(r, defaultTimeout))
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		var body deployBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if body.DeployArgs.Image == "" {
+			writeError(w, http.StatusBadRequest, errors.New("image is required"))
+			return
+		}
+		if body.Timeout <= 0 {
+			body.Timeout = scheduler.DefaultDeployTimeout
+		}
+		task = request.RunRequest(agentId, body.DeployArgs, body.AuthInfo, body.Timeout)
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		task = request.InspectRequest(agentId, segments[0], parseTimeout(r, defaultTimeout))
+	case len(segments) == 1 && r.Method == http.MethodPut:
+		var body deployBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if body.DeployArgs.Image == "" {
+			writeError(w, http.StatusBadRequest, errors.New("image is required"))
+			return
+		}
+		if body.Timeout <= 0 {
+			body.Timeout = scheduler.DefaultDeployTimeout
+		}
+		task = request.UpdateRequest(agentId, segments[0], body.DeployArgs, body.AuthInfo, body.Timeout)
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		deleteImage, _ := strconv.ParseBool(r.URL.Query().Get("deleteImage"))
+		task = request.DeleteRequest(agentId, segments[0], deleteImage, parseTimeout(r, defaultTimeout))
+	case len(segments) == 2 && segments[1] == "stop" && r.Method == http.MethodPost:
+		//Stopping a container waits up to 60 seconds before it is killed
+		task = request.StopRequest(agentId, segments[0], parseTimeout(r, 90))
+	case len(segments) == 2 && segments[1] == "spec" && r.Method == http.MethodGet:
+		//The controller's own record. This does not contact the agent
+		container, err := database.GetContainer(agentId, segments[0])
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeOk(w, container)
+		return
+	case len(segments) == 2 && segments[1] == "logs" && r.Method == http.MethodGet:
+		tail, since, err := parseLogsQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		task = request.LogsRequest(agentId, segments[0], tail, since, parseTimeout(r, defaultTimeout))
+	case len(segments) == 2 && segments[1] == "exec" && r.Method == http.MethodPost:
+		var body execBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if len(body.Command) == 0 {
+			writeError(w, http.StatusBadRequest, errors.New("command is required"))
+			return
+		}
+		if body.Timeout <= 0 {
+			body.Timeout = defaultTimeout
+		}
+		task = request.ExecRequest(agentId, segments[0], body.Command, body.Timeout)
+	case len(segments) > 2 || (len(segments) == 2 && segments[1] != "stop" && segments[1] != "spec" && segments[1] != "logs" && segments[1] != "exec"):
+		notFound(w)
+		return
+	default:
+		methodNotAllowed(w)
+		return
+	}
+	result, err := awaitTask(task)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeOk(w, result)
+}
+
+//Reads the tail (number of lines) and since (UNIX seconds) query parameters of container logs. Both are optional
+func parseLogsQuery(r *http.Request) (int, time.Time, error) {
+	query := r.URL.Query()
+	tail := 0
+	if query.Get("tail") != "" {
+		var err error
+		tail, err = strconv.Atoi(query.Get("tail"))
+		if err != nil || tail < 1 {
+			return 0, time.Time{}, errors.New("invalid tail")
+		}
+	}
+	var since time.Time
+	if query.Get("since") != "" {
+		unix, err := strconv.ParseInt(query.Get("since"), 10, 64)
+		if err != nil {
+			return 0, time.Time{}, errors.New("invalid since")
+		}
+		since = time.Unix(unix, 0)
+	}
+	return tail, since, nil
+}
@@ -0,0 +1,280 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+)
+
+//Cloud endpoints. These wrap the Kubernetes API in KDeploy.go and KMonitor.go
+//	GET    /cloud/deployments
+//	POST   /cloud/deployments
+//	GET    /cloud/deployments/{name}
+//	PUT    /cloud/deployments/{name}
+//	DELETE /cloud/deployments/{name}
+//	GET    /cloud/deployments/{name}/logs?tail=&since=
+//	GET    /cloud/services
+//	POST   /cloud/services
+//	GET    /cloud/services/{name}
+//	PUT    /cloud/services/{name}
+//	DELETE /cloud/services/{name}
+//	GET    /cloud/jobs
+//	POST   /cloud/jobs
+//	GET    /cloud/jobs/{name}
+//	DELETE /cloud/jobs/{name}
+//	GET    /cloud/jobs/{name}/logs?tail=&since=
+//	GET    /cloud/cronjobs
+//	POST   /cloud/cronjobs
+//	GET    /cloud/cronjobs/{name}
+//	DELETE /cloud/cronjobs/{name}
+//	GET    /cloud/configmaps
+//	POST   /cloud/configmaps
+//	DELETE /cloud/configmaps/{name}
+//	POST   /cloud/pods/{name}/exec
+//	GET    /cloud/metrics/{command}?target=&time=&from=&to=&step=
+
+type kDeployBody struct {
+	DeployArgs types.KDeployArgs `json:"deployArgs"`
+	Secrets    []string          `json:"secrets"`
+}
+
+type kJobBody struct {
+	JobArgs types.KJobArgs `json:"jobArgs"`
+	Secrets []string       `json:"secrets"`
+}
+
+//Request body for running a command inside a pod
+type kExecBody struct {
+	//The program and its arguments. This is not run in a shell
+	Command []string `json:"command"`
+	//Empty runs the command in the first container of the pod
+	Container string `json:"container"`
+	//Timeout of the command in seconds
+	Timeout float64 `json:"timeout"`
+}
+
+type kCronjobBody struct {
+	CronjobArgs types.KCronjobArgs `json:"cronjobArgs"`
+	Secrets     []string           `json:"secrets"`
+}
+
+func cloudHandler(w http.ResponseWriter, r *http.Request) {
+	segments := pathSegments(r.URL.Path, apiPrefix+"/cloud")
+	if len(segments) == 0 {
+		notFound(w)
+		return
+	}
+	//Calling the Kubernetes API without a config file panics. See getKuber() in KDeploy.go
+	if vars.GetKuberConfigPath() == "" {
+		writeError(w, http.StatusServiceUnavailable, errors.New("kubernetes is not configured"))
+		return
+	}
+	switch segments[0] {
+	case "deployments":
+		deploymentsHandler(w, r, segments[1:])
+	case "services":
+		servicesHandler(w, r, segments[1:])
+	case "jobs":
+		jobsHandler(w, r, segments[1:])
+	case "cronjobs":
+		cronjobsHandler(w, r, segments[1:])
+	case "configmaps":
+		configMapsHandler(w, r, segments[1:])
+	case "pods":
+		podsHandler(w, r, segments[1:])
+	case "metrics":
+		if vars.GetPrometheusAddress() == "" {
+			writeError(w, http.StatusServiceUnavailable, errors.New("prometheus is not configured"))
+			return
+		}
+		cloudMetricsHandler(w, r, segments[1:])
+	default:
+		notFound(w)
+	}
+}
+
+func deploymentsHandler(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		result, err := request.KListDeployment()
+		writeResult(w, result, err)
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		var body kDeployBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeResult(w, nil, request.KRunDeployment(body.DeployArgs, body.Secrets))
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		result, err := request.KGetDeployment(segments[0])
+		writeResult(w, result, err)
+	case len(segments) == 1 && r.Method == http.MethodPut:
+		var body kDeployBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		//The deployment name is taken from the path
+		body.DeployArgs.DeploymentName = segments[0]
+		writeResult(w, nil, request.KUpdateDeployment(body.DeployArgs, body.Secrets))
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		writeResult(w, nil, request.KDeleteDeployment(segments[0]))
+	case len(segments) == 2 && segments[1] == "logs" && r.Method == http.MethodGet:
+		podLogs(w, r, types.KWorkloadDeployment, segments[0])
+	case len(segments) > 2 || (len(segments) == 2 && segments[1] != "logs"):
+		notFound(w)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func servicesHandler(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		result, err := request.KListService()
+		writeResult(w, result, err)
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		var body types.KServiceArgs
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		result, err := request.KCreateService(body)
+		writeResult(w, result, err)
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		result, err := request.KGetService(segments[0])
+		writeResult(w, result, err)
+	case len(segments) == 1 && r.Method == http.MethodPut:
+		var body types.KServiceArgs
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		body.Name = segments[0]
+		result, err := request.KUpdateService(body)
+		writeResult(w, result, err)
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		writeResult(w, nil, request.KDeleteService(segments[0]))
+	case len(segments) > 1:
+		notFound(w)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func jobsHandler(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		result, err := request.KListJob()
+		writeResult(w, result, err)
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		var body kJobBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeResult(w, nil, request.KRunJob(body.JobArgs, body.Secrets))
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		result, err := request.KGetJob(segments[0])
+		writeResult(w, result, err)
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		writeResult(w, nil, request.KDeleteJob(segments[0]))
+	case len(segments) == 2 && segments[1] == "logs" && r.Method == http.MethodGet:
+		podLogs(w, r, types.KWorkloadJob, segments[0])
+	case len(segments) > 2 || (len(segments) == 2 && segments[1] != "logs"):
+		notFound(w)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func cronjobsHandler(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		result, err := request.KListCronjob()
+		writeResult(w, result, err)
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		var body kCronjobBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeResult(w, nil, request.KRunCronjob(body.CronjobArgs, body.Secrets))
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		result, err := request.KGetCronjob(segments[0])
+		writeResult(w, result, err)
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		writeResult(w, nil, request.KDeleteCronJob(segments[0]))
+	case len(segments) > 1:
+		notFound(w)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func configMapsHandler(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		result, err := request.KListConfigMap()
+		writeResult(w, result, err)
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		var body types.KConfigMap
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeResult(w, nil, request.KCreateConfigMap(body))
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		writeResult(w, nil, request.KDeleteConfigMap(segments[0]))
+	case len(segments) > 1:
+		notFound(w)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func podsHandler(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch {
+	case len(segments) == 2 && segments[1] == "exec" && r.Method == http.MethodPost:
+		var body kExecBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if len(body.Command) == 0 {
+			writeError(w, http.StatusBadRequest, errors.New("command is required"))
+			return
+		}
+		if body.Timeout <= 0 {
+			body.Timeout = defaultTimeout
+		}
+		result, err := request.KExecPod(segments[0], body.Container, body.Command, body.Timeout)
+		writeResult(w, result, err)
+	case len(segments) != 2 || segments[1] != "exec":
+		notFound(w)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+//The output of the pods of a deployment or a job. Takes the same query parameters as the logs of containers
+func podLogs(w http.ResponseWriter, r *http.Request, workload types.KWorkload, name string) {
+	tail, since, err := parseLogsQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := request.KGetPodLogs(workload, name, tail, since)
+	writeResult(w, result, err)
+}
+
+//Writes the return values of a Kubernetes API call
+func writeResult(w http.ResponseWriter, content interface{}, err error) {
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeOk(w, content)
+}
@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"osmoticframework/controller/database"
+	"osmoticframework/controller/types"
+	"strconv"
+	"time"
+)
+
+//Event history endpoint. See controller/history
+//	GET /events?agent=&type=&from=&to=&limit=
+//from and to are UNIX seconds. Events are returned newest first
+
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	events, err := database.QueryEvents(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeOk(w, events)
+}
+
+func parseEventFilter(r *http.Request) (types.EventFilter, error) {
+	query := r.URL.Query()
+	filter := types.EventFilter{
+		AgentId: query.Get("agent"),
+		Type:    query.Get("type"),
+	}
+	if query.Get("from") != "" {
+		from, err := strconv.ParseInt(query.Get("from"), 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid from")
+		}
+		filter.From = time.Unix(from, 0)
+	}
+	if query.Get("to") != "" {
+		to, err := strconv.ParseInt(query.Get("to"), 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid to")
+		}
+		filter.To = time.Unix(to, 0)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return filter, errors.New("range ends before it starts")
+	}
+	if query.Get("limit") != "" {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 {
+			return filter, errors.New("invalid limit")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
@@ -0,0 +1,362 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"osmoticframework/controller/api/impl/request"
+	"time"
+)
+
+//Metric endpoints. The command names are the same as the ones used in the monitor queue protocol
+//	GET /agents/{agentId}/metrics/{command}?time=&containerId=
+//	GET /agents/{agentId}/metrics/promql?query=&time=
+//	GET /cloud/metrics/{command}?target=&time=
+//Range queries replace time with from, to and step, and return series instead. See parseRange
+
+type edgeRequest func(agentId string, timestamp time.Time, timeout float64) *request.RequestTask
+type containerRequest func(agentId, containerId string, timestamp time.Time, timeout float64) *request.RequestTask
+type cloudRequest func(target string, timestamp time.Time) (interface{}, error)
+type edgeRangeRequest func(agentId string, from, to time.Time, step time.Duration, timeout float64) *request.RequestTask
+type containerRangeRequest func(agentId, containerId string, from, to time.Time, step time.Duration, timeout float64) *request.RequestTask
+type cloudRangeRequest func(target string, window timeRange) (interface{}, error)
+
+var edgeMetrics = map[string]edgeRequest{
+	"cpu_edge_avg":        request.CPUEdgeAvgRequest,
+	"cpu_time":            request.CPUTimeRequest,
+	"cpu_utilization":     request.CPUUtilizationRequest,
+	"memory_edge":         request.MemoryEdgeRequest,
+	"memory_edge_peak":    request.MemoryEdgePeakRequest,
+	"memory_edge_total":   request.MemoryEdgeTotalRequest,
+	"io_edge_time":        request.IOEdgeTimeRequest,
+	"io_edge_read":        request.IOEdgeReadRequest,
+	"io_edge_write":       request.IOEdgeWriteRequest,
+	"io_filesystem_used":  request.IOFilesystemUsedRequest,
+	"io_filesystem_size":  request.IOFilesystemSizeRequest,
+	"net_edge_rx_bytes":   request.NetEdgeRxBytesRequest,
+	"net_edge_rx_packets": request.NetEdgeRxPacketsRequest,
+	"net_edge_rx_dropped": request.NetEdgeRxDroppedRequest,
+	"net_edge_rx_error":   request.NetEdgeRxErrorRequest,
+	"net_edge_tx_bytes":   request.NetEdgeTxBytesRequest,
+	"net_edge_tx_packets": request.NetEdgeTxPacketsRequest,
+	"net_edge_tx_dropped": request.NetEdgeTxDroppedRequest,
+	"net_edge_tx_error":   request.NetEdgeTxErrorRequest,
+	"thermal":             request.ThermalRequest,
+}
+
+var containerMetrics = map[string]containerRequest{
+	"cpu_container_avg":              request.CPUContainerAvgRequest,
+	"memory_container":               request.MemoryContainerRequest,
+	"memory_container_peak":          request.MemoryContainerPeakRequest,
+	"memory_container_limit_seconds": request.MemoryContainerLimitSecondsRequest,
+	"io_container_time":              request.IOContainerTimeRequest,
+	"io_container_read":              request.IOContainerReadRequest,
+	"io_container_write":             request.IOContainerWriteRequest,
+	"net_container_rx_bytes":         request.NetContainerRxBytesRequest,
+	"net_container_rx_packets":       request.NetContainerRxPacketsRequest,
+	"net_container_rx_dropped":       request.NetContainerRxDroppedRequest,
+	"net_container_rx_error":         request.NetContainerRxErrorRequest,
+	"net_container_tx_bytes":         request.NetContainerTxBytesRequest,
+	"net_container_tx_packets":       request.NetContainerTxPacketsRequest,
+	"net_container_tx_dropped":       request.NetContainerTxDroppedRequest,
+	"net_container_tx_error":         request.NetContainerTxErrorRequest,
+}
+
+//Cloud metrics take either a node name, node IP or pod name as the target. See KMonitor.go
+var cloudMetrics = map[string]cloudRequest{
+	"cpu_node_avg":             func(t string, ts time.Time) (interface{}, error) { return request.KCPUCoreAvg(t, ts) },
+	"cpu_pod_avg":              func(t string, ts time.Time) (interface{}, error) { return request.KCPUPodAvg(t, ts) },
+	"cpu_time":                 func(t string, ts time.Time) (interface{}, error) { return request.KCPUTime(t, ts) },
+	"cpu_utilization":          func(t string, ts time.Time) (interface{}, error) { return request.KCPUUtilization(t, ts) },
+	"memory_pod":               func(t string, ts time.Time) (interface{}, error) { return request.KMemoryPod(t, ts) },
+	"memory_node":              func(t string, ts time.Time) (interface{}, error) { return request.KMemoryNode(t, ts) },
+	"memory_pod_peak":          func(t string, ts time.Time) (interface{}, error) { return request.KMemoryPodPeak(t, ts) },
+	"memory_node_peak":         func(t string, ts time.Time) (interface{}, error) { return request.KMemoryNodePeak(t, ts) },
+	"memory_pod_limit_seconds": func(t string, ts time.Time) (interface{}, error) { return request.KMemoryPodReachLimitSeconds(t, ts) },
+	"io_node_time":             func(t string, ts time.Time) (interface{}, error) { return request.KIONodeTime(t, ts) },
+	"io_pod_time":              func(t string, ts time.Time) (interface{}, error) { return request.KIOPodTime(t, ts) },
+	"io_node_read":             func(t string, ts time.Time) (interface{}, error) { return request.KIOReadNodeBytes(t, ts) },
+	"io_pod_read":              func(t string, ts time.Time) (interface{}, error) { return request.KIOReadPodBytes(t, ts) },
+	"io_node_write":            func(t string, ts time.Time) (interface{}, error) { return request.KIOWriteNodeBytes(t, ts) },
+	"io_pod_write":             func(t string, ts time.Time) (interface{}, error) { return request.KIOWritePodBytes(t, ts) },
+	"io_filesystem_used":       func(t string, ts time.Time) (interface{}, error) { return request.KIOFilesystemUsedBytes(t, ts) },
+	"io_filesystem_size":       func(t string, ts time.Time) (interface{}, error) { return request.KIOFilesystemSizeBytes(t, ts) },
+	"net_node_rx_bytes":        func(t string, ts time.Time) (interface{}, error) { return request.KNetworkNodeRxBytes(t, ts) },
+	"net_pod_rx_bytes":         func(t string, ts time.Time) (interface{}, error) { return request.KNetworkPodRxBytes(t, ts) },
+	"net_node_tx_bytes":        func(t string, ts time.Time) (interface{}, error) { return request.KNetworkNodeTxBytes(t, ts) },
+	"net_pod_tx_bytes":         func(t string, ts time.Time) (interface{}, error) { return request.KNetworkPodTxBytes(t, ts) },
+	"net_node_rx_packets":      func(t string, ts time.Time) (interface{}, error) { return request.KNetworkNodeRxPackets(t, ts) },
+	"net_pod_rx_packets":       func(t string, ts time.Time) (interface{}, error) { return request.KNetworkPodRxPackets(t, ts) },
+	"net_node_tx_packets":      func(t string, ts time.Time) (interface{}, error) { return request.KNetworkNodeTxPackets(t, ts) },
+	"net_pod_tx_packets":       func(t string, ts time.Time) (interface{}, error) { return request.KNetworkPodTxPackets(t, ts) },
+	"net_node_rx_dropped":      func(t string, ts time.Time) (interface{}, error) { return request.KNetworkNodeRxDropped(t, ts) },
+	"net_pod_rx_dropped":       func(t string, ts time.Time) (interface{}, error) { return request.KNetworkPodRxDropped(t, ts) },
+	"net_node_tx_dropped":      func(t string, ts time.Time) (interface{}, error) { return request.KNetworkNodeTxDropped(t, ts) },
+	"net_pod_tx_dropped":       func(t string, ts time.Time) (interface{}, error) { return request.KNetworkPodTxDropped(t, ts) },
+	"net_node_rx_error":        func(t string, ts time.Time) (interface{}, error) { return request.KNetworkNodeRxError(t, ts) },
+	"net_pod_rx_error":         func(t string, ts time.Time) (interface{}, error) { return request.KNetworkPodRxError(t, ts) },
+	"net_node_tx_error":        func(t string, ts time.Time) (interface{}, error) { return request.KNetworkNodeTxError(t, ts) },
+	"net_pod_tx_error":         func(t string, ts time.Time) (interface{}, error) { return request.KNetworkPodTxError(t, ts) },
+	"endpoint_info":            func(_ string, _ time.Time) (interface{}, error) { return request.KEndpointInfo() },
+}
+
+var edgeRangeMetrics = map[string]edgeRangeRequest{
+	"cpu_edge_avg":        request.CPUEdgeAvgRangeRequest,
+	"cpu_time":            request.CPUTimeRangeRequest,
+	"cpu_utilization":     request.CPUUtilizationRangeRequest,
+	"memory_edge":         request.MemoryEdgeRangeRequest,
+	"memory_edge_peak":    request.MemoryEdgePeakRangeRequest,
+	"memory_edge_total":   request.MemoryEdgeTotalRangeRequest,
+	"io_edge_time":        request.IOEdgeTimeRangeRequest,
+	"io_edge_read":        request.IOEdgeReadRangeRequest,
+	"io_edge_write":       request.IOEdgeWriteRangeRequest,
+	"io_filesystem_used":  request.IOFilesystemUsedRangeRequest,
+	"io_filesystem_size":  request.IOFilesystemSizeRangeRequest,
+	"net_edge_rx_bytes":   request.NetEdgeRxBytesRangeRequest,
+	"net_edge_rx_packets": request.NetEdgeRxPacketsRangeRequest,
+	"net_edge_rx_dropped": request.NetEdgeRxDroppedRangeRequest,
+	"net_edge_rx_error":   request.NetEdgeRxErrorRangeRequest,
+	"net_edge_tx_bytes":   request.NetEdgeTxBytesRangeRequest,
+	"net_edge_tx_packets": request.NetEdgeTxPacketsRangeRequest,
+	"net_edge_tx_dropped": request.NetEdgeTxDroppedRangeRequest,
+	"net_edge_tx_error":   request.NetEdgeTxErrorRangeRequest,
+	"thermal":             request.ThermalRangeRequest,
+}
+
+var containerRangeMetrics = map[string]containerRangeRequest{
+	"cpu_container_avg":              request.CPUContainerAvgRangeRequest,
+	"memory_container":               request.MemoryContainerRangeRequest,
+	"memory_container_peak":          request.MemoryContainerPeakRangeRequest,
+	"memory_container_limit_seconds": request.MemoryContainerLimitSecondsRangeRequest,
+	"io_container_time":              request.IOContainerTimeRangeRequest,
+	"io_container_read":              request.IOContainerReadRangeRequest,
+	"io_container_write":             request.IOContainerWriteRangeRequest,
+	"net_container_rx_bytes":         request.NetContainerRxBytesRangeRequest,
+	"net_container_rx_packets":       request.NetContainerRxPacketsRangeRequest,
+	"net_container_rx_dropped":       request.NetContainerRxDroppedRangeRequest,
+	"net_container_rx_error":         request.NetContainerRxErrorRangeRequest,
+	"net_container_tx_bytes":         request.NetContainerTxBytesRangeRequest,
+	"net_container_tx_packets":       request.NetContainerTxPacketsRangeRequest,
+	"net_container_tx_dropped":       request.NetContainerTxDroppedRangeRequest,
+	"net_container_tx_error":         request.NetContainerTxErrorRangeRequest,
+}
+
+//The endpoint info has no range variant
+var cloudRangeMetrics = map[string]cloudRangeRequest{
+	"cpu_node_avg": func(t string, w timeRange) (interface{}, error) {
+		return request.KCPUCoreAvgRange(t, w.from, w.to, w.step)
+	},
+	"cpu_pod_avg": func(t string, w timeRange) (interface{}, error) {
+		return request.KCPUPodAvgRange(t, w.from, w.to, w.step)
+	},
+	"cpu_time": func(t string, w timeRange) (interface{}, error) {
+		return request.KCPUTimeRange(t, w.from, w.to, w.step)
+	},
+	"cpu_utilization": func(t string, w timeRange) (interface{}, error) {
+		return request.KCPUUtilizationRange(t, w.from, w.to, w.step)
+	},
+	"memory_pod": func(t string, w timeRange) (interface{}, error) {
+		return request.KMemoryPodRange(t, w.from, w.to, w.step)
+	},
+	"memory_node": func(t string, w timeRange) (interface{}, error) {
+		return request.KMemoryNodeRange(t, w.from, w.to, w.step)
+	},
+	"memory_pod_peak": func(t string, w timeRange) (interface{}, error) {
+		return request.KMemoryPodPeakRange(t, w.from, w.to, w.step)
+	},
+	"memory_node_peak": func(t string, w timeRange) (interface{}, error) {
+		return request.KMemoryNodePeakRange(t, w.from, w.to, w.step)
+	},
+	"memory_pod_limit_seconds": func(t string, w timeRange) (interface{}, error) {
+		return request.KMemoryPodReachLimitSecondsRange(t, w.from, w.to, w.step)
+	},
+	"io_node_time": func(t string, w timeRange) (interface{}, error) {
+		return request.KIONodeTimeRange(t, w.from, w.to, w.step)
+	},
+	"io_pod_time": func(t string, w timeRange) (interface{}, error) {
+		return request.KIOPodTimeRange(t, w.from, w.to, w.step)
+	},
+	"io_node_read": func(t string, w timeRange) (interface{}, error) {
+		return request.KIOReadNodeBytesRange(t, w.from, w.to, w.step)
+	},
+	"io_pod_read": func(t string, w timeRange) (interface{}, error) {
+		return request.KIOReadPodBytesRange(t, w.from, w.to, w.step)
+	},
+	"io_node_write": func(t string, w timeRange) (interface{}, error) {
+		return request.KIOWriteNodeBytesRange(t, w.from, w.to, w.step)
+	},
+	"io_pod_write": func(t string, w timeRange) (interface{}, error) {
+		return request.KIOWritePodBytesRange(t, w.from, w.to, w.step)
+	},
+	"io_filesystem_used": func(t string, w timeRange) (interface{}, error) {
+		return request.KIOFilesystemUsedBytesRange(t, w.from, w.to, w.step)
+	},
+	"io_filesystem_size": func(t string, w timeRange) (interface{}, error) {
+		return request.KIOFilesystemSizeBytesRange(t, w.from, w.to, w.step)
+	},
+	"net_node_rx_bytes": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkNodeRxBytesRange(t, w.from, w.to, w.step)
+	},
+	"net_pod_rx_bytes": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkPodRxBytesRange(t, w.from, w.to, w.step)
+	},
+	"net_node_tx_bytes": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkNodeTxBytesRange(t, w.from, w.to, w.step)
+	},
+	"net_pod_tx_bytes": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkPodTxBytesRange(t, w.from, w.to, w.step)
+	},
+	"net_node_rx_packets": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkNodeRxPacketsRange(t, w.from, w.to, w.step)
+	},
+	"net_pod_rx_packets": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkPodRxPacketsRange(t, w.from, w.to, w.step)
+	},
+	"net_node_tx_packets": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkNodeTxPacketsRange(t, w.from, w.to, w.step)
+	},
+	"net_pod_tx_packets": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkPodTxPacketsRange(t, w.from, w.to, w.step)
+	},
+	"net_node_rx_dropped": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkNodeRxDroppedRange(t, w.from, w.to, w.step)
+	},
+	"net_pod_rx_dropped": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkPodRxDroppedRange(t, w.from, w.to, w.step)
+	},
+	"net_node_tx_dropped": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkNodeTxDroppedRange(t, w.from, w.to, w.step)
+	},
+	"net_pod_tx_dropped": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkPodTxDroppedRange(t, w.from, w.to, w.step)
+	},
+	"net_node_rx_error": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkNodeRxErrorRange(t, w.from, w.to, w.step)
+	},
+	"net_pod_rx_error": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkPodRxErrorRange(t, w.from, w.to, w.step)
+	},
+	"net_node_tx_error": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkNodeTxErrorRange(t, w.from, w.to, w.step)
+	},
+	"net_pod_tx_error": func(t string, w timeRange) (interface{}, error) {
+		return request.KNetworkPodTxErrorRange(t, w.from, w.to, w.step)
+	},
+}
+
+func edgeMetricsHandler(w http.ResponseWriter, r *http.Request, agentId string, segments []string) {
+	if len(segments) != 1 {
+		notFound(w)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	timestamp, err := parseTime(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	window, err := parseRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	timeout := parseTimeout(r, defaultTimeout)
+	command := segments[0]
+	if command == "promql" {
+		promQLHandler(w, r, agentId, timestamp, window, timeout)
+		return
+	}
+	containerId := r.URL.Query().Get("containerId")
+	_, isEdge := edgeMetrics[command]
+	_, isContainer := containerMetrics[command]
+	if !isEdge && !isContainer {
+		writeError(w, http.StatusNotFound, errors.New("unknown metric "+command))
+		return
+	}
+	if isContainer && containerId == "" {
+		writeError(w, http.StatusBadRequest, errors.New("containerId is required"))
+		return
+	}
+	var task *request.RequestTask
+	switch {
+	case isContainer && window != nil:
+		task = containerRangeMetrics[command](agentId, containerId, window.from, window.to, window.step, timeout)
+	case isContainer:
+		task = containerMetrics[command](agentId, containerId, timestamp, timeout)
+	case window != nil:
+		task = edgeRangeMetrics[command](agentId, window.from, window.to, window.step, timeout)
+	default:
+		task = edgeMetrics[command](agentId, timestamp, timeout)
+	}
+	result, err := awaitTask(task)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeOk(w, result)
+}
+
+//Runs the PromQL query in the query parameter on the agent
+func promQLHandler(w http.ResponseWriter, r *http.Request, agentId string, timestamp time.Time, window *timeRange, timeout float64) {
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("query is required"))
+		return
+	}
+	var task *request.RequestTask
+	if window != nil {
+		task = request.PromQLEdgeRangeRequest(agentId, query, window.from, window.to, window.step, timeout)
+	} else {
+		task = request.PromQLEdgeRequest(agentId, query, timestamp, timeout)
+	}
+	result, err := awaitTask(task)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeOk(w, result)
+}
+
+func cloudMetricsHandler(w http.ResponseWriter, r *http.Request, segments []string) {
+	if len(segments) != 1 {
+		notFound(w)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	cloudMetric, ok := cloudMetrics[segments[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown metric "+segments[0]))
+		return
+	}
+	timestamp, err := parseTime(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	window, err := parseRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var result interface{}
+	if window != nil {
+		cloudRangeMetric, ok := cloudRangeMetrics[segments[0]]
+		if !ok {
+			writeError(w, http.StatusBadRequest, errors.New("metric "+segments[0]+" has no range query"))
+			return
+		}
+		result, err = cloudRangeMetric(r.URL.Query().Get("target"), *window)
+	} else {
+		result, err = cloudMetric(r.URL.Query().Get("target"), timestamp)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeOk(w, result)
+}
@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"osmoticframework/controller/replicaset"
+	"osmoticframework/controller/types"
+)
+
+//Edge replica set endpoints. Containers are deployed and removed in the background after a change
+//	GET    /replicasets
+//	POST   /replicasets
+//	GET    /replicasets/{name}
+//	DELETE /replicasets/{name}
+//	POST   /replicasets/{name}/scale
+//Posting a set with the name of an existing set replaces it
+
+type replicaSetBody struct {
+	ReplicaSet types.ReplicaSet `json:"replicaSet"`
+	AuthInfo   types.AuthInfo   `json:"authInfo"`
+}
+
+type scaleBody struct {
+	Replicas *int `json:"replicas"`
+}
+
+func replicaSetsHandler(w http.ResponseWriter, r *http.Request) {
+	segments := pathSegments(r.URL.Path, apiPrefix+"/replicasets")
+	switch len(segments) {
+	case 0:
+		switch r.Method {
+		case http.MethodGet:
+			writeOk(w, replicaset.List())
+		case http.MethodPost:
+			var body replicaSetBody
+			if err := decodeBody(r, &body); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			body.ReplicaSet.AuthInfo = body.AuthInfo
+			if err := replicaset.Validate(body.ReplicaSet); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if err := replicaset.Apply(body.ReplicaSet); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			replicaSetStatus(w, body.ReplicaSet.Name)
+		default:
+			methodNotAllowed(w)
+		}
+	case 1:
+		switch r.Method {
+		case http.MethodGet:
+			replicaSetStatus(w, segments[0])
+		case http.MethodDelete:
+			err := replicaset.Delete(segments[0])
+			if err == replicaset.ErrNotFound {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeOk(w, nil)
+		default:
+			methodNotAllowed(w)
+		}
+	case 2:
+		if segments[1] != "scale" {
+			notFound(w)
+			return
+		}
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w)
+			return
+		}
+		var body scaleBody
+		if err := decodeBody(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if body.Replicas == nil || *body.Replicas < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid replicas"))
+			return
+		}
+		err := replicaset.Scale(segments[0], *body.Replicas)
+		if err == replicaset.ErrNotFound {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		replicaSetStatus(w, segments[0])
+	default:
+		notFound(w)
+	}
+}
+
+func replicaSetStatus(w http.ResponseWriter, name string) {
+	status, err := replicaset.Get(name)
+	if err == replicaset.ErrNotFound {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeOk(w, status)
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"osmoticframework/controller/scheduler"
+	"osmoticframework/controller/types"
+)
+
+//Scheduler endpoint. The controller chooses the agent to run the container on
+//	POST /schedule
+
+type scheduleBody struct {
+	DeployArgs  types.DeployArgs  `json:"deployArgs"`
+	AuthInfo    types.AuthInfo    `json:"authInfo"`
+	Constraints types.Constraints `json:"constraints"`
+	Timeout     float64           `json:"timeout"`
+}
+
+func scheduleHandler(w http.ResponseWriter, r *http.Request) {
+	if len(pathSegments(r.URL.Path, apiPrefix+"/schedule")) != 0 {
+		notFound(w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	var body scheduleBody
+	if err := decodeBody(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.DeployArgs.Image == "" {
+		writeError(w, http.StatusBadRequest, errors.New("image is required"))
+		return
+	}
+	if body.Timeout <= 0 {
+		body.Timeout = scheduler.DefaultDeployTimeout
+	}
+	agentId, task, err := scheduler.Schedule(body.DeployArgs, body.AuthInfo, body.Constraints, types.OriginScheduler, body.Timeout)
+	if err == scheduler.ErrNoCandidate {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	containerId, err := awaitTask(task)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeOk(w, map[string]interface{}{
+		"agentId":     agentId,
+		"containerId": containerId,
+	})
+}
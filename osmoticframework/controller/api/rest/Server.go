@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/vars"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+The REST management API
+This wraps the request package in HTTP/JSON so that the controller can be operated from scripts and dashboards without recompiling.
+All routes are versioned under /api/v1. Every response is a JSON object in the same shape as agent responses:
+	{"status": "ok", "result": ...}
+	{"status": "failed", "error": "..."}
+*/
+
+const apiPrefix = "/api/v1"
+
+//Default timeout in seconds for requests that are sent to agents
+const defaultTimeout = 30
+
+//Starts the HTTP server in a separate goroutine
+func Init() {
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiPrefix+"/agents", agentsHandler)
+	mux.HandleFunc(apiPrefix+"/agents/", agentsHandler)
+	mux.HandleFunc(apiPrefix+"/cloud/", cloudHandler)
+	mux.HandleFunc(apiPrefix+"/events", eventsHandler)
+	mux.HandleFunc(apiPrefix+"/replicasets", replicaSetsHandler)
+	mux.HandleFunc(apiPrefix+"/replicasets/", replicaSetsHandler)
+	mux.HandleFunc(apiPrefix+"/schedule", scheduleHandler)
+	mux.HandleFunc(apiPrefix+"/tokens", tokensHandler)
+	mux.HandleFunc(apiPrefix+"/tokens/", tokensHandler)
+	address := ":" + strconv.Itoa(vars.GetRestApiPort())
+	if vars.GetRestApiToken() == "" {
+		log.Warn.Println("REST API token not set. Anyone who can reach the controller can deploy containers")
+	}
+	go func() {
+		log.Info.Println("Serving REST API at " + address + apiPrefix)
+		err := http.ListenAndServe(address, authenticate(mux))
+		if err != nil {
+			log.Error.Println("REST API stopped")
+			log.Error.Println(err)
+		}
+	}()
+}
+
+//Rejects any request without the bearer token, if one is configured
+func authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := vars.GetRestApiToken()
+		//Constant time, so that the token cannot be guessed from the response time
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//Splits the path after the given prefix into segments. Empty segments are removed
+//e.g. /api/v1/agents/abc/containers -> [abc containers]
+func pathSegments(path, prefix string) []string {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(strings.TrimPrefix(path, prefix), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+//Waits for the result of a request sent to an agent
+func awaitTask(task *request.RequestTask) (interface{}, error) {
+	result := request.Await(task)
+	if result.ResultType == request.Error {
+		return nil, result.Content.(error)
+	}
+	return result.Content, nil
+}
+
+//Reads the timeout query parameter, or returns the fallback value
+func parseTimeout(r *http.Request, fallback float64) float64 {
+	timeout, err := strconv.ParseFloat(r.URL.Query().Get("timeout"), 64)
+	if err != nil || timeout <= 0 {
+		return fallback
+	}
+	return timeout
+}
+
+//Reads the time query parameter (UNIX seconds), or returns the current time
+func parseTime(r *http.Request) (time.Time, error) {
+	raw := r.URL.Query().Get("time")
+	if raw == "" {
+		return time.Now(), nil
+	}
+	timestamp, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("invalid time")
+	}
+	return time.Unix(timestamp, 0), nil
+}
+
+//A range in time of a metric query
+type timeRange struct {
+	from time.Time
+	to   time.Time
+	step time.Duration
+}
+
+//Reads the range query parameters from, to (UNIX seconds) and step (seconds)
+//Returns nil if there is no step, which makes the request an instant query. The range ends now if to is not given
+func parseRange(r *http.Request) (*timeRange, error) {
+	query := r.URL.Query()
+	if query.Get("step") == "" {
+		return nil, nil
+	}
+	step, err := strconv.ParseInt(query.Get("step"), 10, 64)
+	//Prometheus smallest scale in time is second
+	if err != nil || step < 1 {
+		return nil, errors.New("invalid step")
+	}
+	from, err := strconv.ParseInt(query.Get("from"), 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid from")
+	}
+	to := time.Now()
+	if query.Get("to") != "" {
+		unix, err := strconv.ParseInt(query.Get("to"), 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid to")
+		}
+		to = time.Unix(unix, 0)
+	}
+	if to.Before(time.Unix(from, 0)) {
+		return nil, errors.New("range ends before it starts")
+	}
+	return &timeRange{from: time.Unix(from, 0), to: to, step: time.Duration(step) * time.Second}, nil
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func writeOk(w http.ResponseWriter, content interface{}) {
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"result": content,
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJson(w, code, map[string]interface{}{
+		"status": "failed",
+		"error":  err.Error(),
+	})
+}
+
+func writeJson(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.Error.Println("Failed writing REST response")
+		log.Error.Println(err)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+func notFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, errors.New("not found"))
+}
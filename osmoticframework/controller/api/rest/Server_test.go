@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPathSegments(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{"root", "/api/v1/agents", []string{}},
+		{"trailing slash", "/api/v1/agents/", []string{}},
+		{"agent", "/api/v1/agents/abc", []string{"abc"}},
+		{"containers", "/api/v1/agents/abc/containers/", []string{"abc", "containers"}},
+		{"double slash", "/api/v1/agents//abc//containers", []string{"abc", "containers"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pathSegments(tt.path, apiPrefix+"/agents"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pathSegments() Got %v, Want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *timeRange
+		wantErr bool
+	}{
+		{"instant", "time=1000", nil, false},
+		{"range", "from=1000&to=1600&step=15", &timeRange{time.Unix(1000, 0), time.Unix(1600, 0), 15 * time.Second}, false},
+		{"no from", "to=1600&step=15", nil, true},
+		{"zero step", "from=1000&to=1600&step=0", nil, true},
+		{"invalid step", "from=1000&to=1600&step=15s", nil, true},
+		{"reversed", "from=1600&to=1000&step=15", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			got, err := parseRange(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseRange() error Got %v, Want error %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRange() Got %v, Want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEventFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    types.EventFilter
+		wantErr bool
+	}{
+		{"empty", "", types.EventFilter{}, false},
+		{"all", "agent=abc&type=container-crash&from=1000&to=1600&limit=10", types.EventFilter{AgentId: "abc", Type: "container-crash", From: time.Unix(1000, 0), To: time.Unix(1600, 0), Limit: 10}, false},
+		{"invalid from", "from=yesterday", types.EventFilter{}, true},
+		{"reversed", "from=1600&to=1000", types.EventFilter{}, true},
+		{"zero limit", "limit=0", types.EventFilter{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			got, err := parseEventFilter(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseEventFilter() error Got %v, Want error %t", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEventFilter() Got %v, Want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgentsEndpoint(t *testing.T) {
+	vars.LoadConfig([]byte(`{"rest_api_token": "secret"}`))
+	vars.Agents.Store("agent-b", types.Agent{InternalIP: "10.0.0.2"})
+	vars.Agents.Store("agent-a", types.Agent{InternalIP: "10.0.0.1"})
+	defer vars.Agents.Delete("agent-a")
+	defer vars.Agents.Delete("agent-b")
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiPrefix+"/agents", agentsHandler)
+	mux.HandleFunc(apiPrefix+"/agents/", agentsHandler)
+	handler := authenticate(mux)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"no token", http.MethodGet, "/api/v1/agents", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/api/v1/agents", "wrong", http.StatusUnauthorized},
+		{"list", http.MethodGet, "/api/v1/agents", "secret", http.StatusOK},
+		{"list post", http.MethodPost, "/api/v1/agents", "secret", http.StatusMethodNotAllowed},
+		{"get", http.MethodGet, "/api/v1/agents/agent-a", "secret", http.StatusOK},
+		{"missing agent", http.MethodGet, "/api/v1/agents/agent-c", "secret", http.StatusNotFound},
+		{"unknown route", http.MethodGet, "/api/v1/agents/agent-a/unknown", "secret", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("Status code incorrect. Got %d, Want %d", rec.Code, tt.want)
+			}
+		})
+	}
+	//Agents are sorted by ID
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var body struct {
+		Status string      `json:"status"`
+		Result []agentView `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Result) != 2 || body.Result[0].ID != "agent-a" || body.Result[1].ID != "agent-b" {
+		t.Errorf("Agent list incorrect. Got %v", body.Result)
+	}
+}
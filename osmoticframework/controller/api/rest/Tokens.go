@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"osmoticframework/controller/auth"
+	"osmoticframework/controller/database"
+	"time"
+)
+
+//Bootstrap token endpoints. Agents present a token to register if registration authentication is enabled
+//	GET    /tokens
+//	POST   /tokens
+//	DELETE /tokens/{tokenId}
+//The token itself is only returned when it is created. Tokens are identified by their SHA-256 hash afterwards
+
+type tokenBody struct {
+	Description string `json:"description"`
+	//Time to live in seconds. 0 means the token does not expire
+	TTL int64 `json:"ttl"`
+}
+
+func tokensHandler(w http.ResponseWriter, r *http.Request) {
+	segments := pathSegments(r.URL.Path, apiPrefix+"/tokens")
+	switch len(segments) {
+	case 0:
+		switch r.Method {
+		case http.MethodGet:
+			tokens, err := database.ListBootstrapTokens()
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeOk(w, tokens)
+		case http.MethodPost:
+			var body tokenBody
+			if err := decodeBody(r, &body); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if len(body.Description) > 64 {
+				writeError(w, http.StatusBadRequest, errors.New("description is longer than 64 characters"))
+				return
+			}
+			if body.TTL < 0 {
+				writeError(w, http.StatusBadRequest, errors.New("invalid ttl"))
+				return
+			}
+			token, bootstrapToken, err := auth.IssueToken(body.Description, time.Duration(body.TTL)*time.Second)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeOk(w, map[string]interface{}{
+				"token":   token,
+				"details": bootstrapToken,
+			})
+		default:
+			methodNotAllowed(w)
+		}
+	case 1:
+		if r.Method != http.MethodDelete {
+			methodNotAllowed(w)
+			return
+		}
+		err := auth.DeleteToken(segments[0])
+		if err == auth.ErrNotFound {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeOk(w, nil)
+	default:
+		notFound(w)
+	}
+}
+
+//Revokes an agent. This works for disconnected agents too, so that they cannot rejoin
+func revokeHandler(w http.ResponseWriter, r *http.Request, agentId string) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	err := auth.Revoke(agentId)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeOk(w, nil)
+}
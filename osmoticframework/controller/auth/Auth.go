@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"osmoticframework/controller/database"
+	"osmoticframework/controller/failover"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/queue"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"time"
+)
+
+/*
+	Registration authentication
+	New agents present a bootstrap token when registering. A token is either pre-shared in the controller config, which can be used any number of times,
+	or a one-time token issued through the REST API. Only the SHA-256 hash of one-time tokens is stored.
+	A registered agent receives a secret, and signs its request with it when rejoining under its previous ID. The secret is only sent to the reply queue of the agent.
+	Revoked agents cannot rejoin, and their messages are ignored.
+	Secrets are also kept in memory, as every message between the controller and agents is signed with them. See queue/Signing.go
+*/
+
+var ErrNotFound = errors.New("token not found")
+
+//Creates a one-time bootstrap token. The token is returned only once. A ttl of 0 means the token does not expire
+func IssueToken(description string, ttl time.Duration) (string, types.BootstrapToken, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", types.BootstrapToken{}, err
+	}
+	bootstrapToken := types.BootstrapToken{
+		ID:          HashToken(token),
+		Description: description,
+		Created:     time.Now().UTC(),
+	}
+	if ttl > 0 {
+		expires := bootstrapToken.Created.Add(ttl)
+		bootstrapToken.Expires = &expires
+	}
+	err = database.AddBootstrapToken(bootstrapToken.ID, description, bootstrapToken.Expires)
+	if err != nil {
+		return "", types.BootstrapToken{}, err
+	}
+	return token, bootstrapToken, nil
+}
+
+//Checks a bootstrap token presented by a registering agent. One-time tokens cannot be used again afterwards
+func ValidateToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	if isPreShared(token, vars.GetRegistrationTokens()) {
+		return true
+	}
+	ok, err := database.ConsumeBootstrapToken(HashToken(token))
+	if err != nil {
+		log.Error.Println("Failed validating bootstrap token")
+		return false
+	}
+	return ok
+}
+
+//Deletes a one-time bootstrap token by its ID
+func DeleteToken(tokenId string) error {
+	ok, err := database.DeleteBootstrapToken(tokenId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+//The ID of a token
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func isPreShared(token string, tokens []string) bool {
+	match := false
+	//Compare against every token so that the time taken does not depend on which token matches
+	for _, preShared := range tokens {
+		if preShared != "" && subtle.ConstantTimeCompare([]byte(token), []byte(preShared)) == 1 {
+			match = true
+		}
+	}
+	return match
+}
+
+//Generates a secret for an agent and stores it
+func NewSecret(agentId string) (string, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	err = database.SetAgentKey(agentId, secret)
+	if err != nil {
+		return "", err
+	}
+	vars.AgentKeys.Store(agentId, secret)
+	return secret, nil
+}
+
+//Revokes an agent. It is disconnected, and cannot rejoin
+//The containers of a connected agent are moved to other agents if failover is enabled
+func Revoke(agentId string) error {
+	err := database.RevokeAgent(agentId)
+	if err != nil {
+		return err
+	}
+	vars.RevokedAgents.Store(agentId, time.Now())
+	vars.AgentKeys.Delete(agentId)
+	log.Warn.Printf("Agent %s has been revoked\n", agentId)
+	if _, ok := vars.Agents.Load(agentId); ok {
+		failover.AgentLost(agentId)
+		database.Unregister(agentId)
+	}
+	//Deleting the queues ends the agent's consumers. The agent is rejected when it tries to rejoin
+	for _, name := range []string{"deploy-" + agentId, "monitor-" + agentId} {
+		err := queue.DeleteQueue(name)
+		if err != nil {
+			log.Warn.Printf("Failed deleting queue %s\n", name)
+			log.Warn.Println(err)
+		}
+	}
+	return nil
+}
+
+//Loads the revoked agents from the database
+func LoadRevoked() error {
+	agentIds, err := database.ListRevokedAgents()
+	if err != nil {
+		return err
+	}
+	for _, agentId := range agentIds {
+		vars.RevokedAgents.Store(agentId, time.Time{})
+	}
+	return nil
+}
+
+//Loads the secrets of all agents from the database
+func LoadKeys() error {
+	keys, err := database.ListAgentKeys()
+	if err != nil {
+		return err
+	}
+	for agentId, secret := range keys {
+		vars.AgentKeys.Store(agentId, secret)
+	}
+	return nil
+}
+
+func randomHex(length int) (string, error) {
+	buffer := make([]byte, length)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
@@ -0,0 +1,31 @@
+package auth
+
+import "testing"
+
+func TestIsPreShared(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Token    string
+		Tokens   []string
+		Expected bool
+	}{
+		{Name: "match", Token: "abc", Tokens: []string{"xyz", "abc"}, Expected: true},
+		{Name: "no match", Token: "abc", Tokens: []string{"xyz"}, Expected: false},
+		{Name: "prefix", Token: "ab", Tokens: []string{"abc"}, Expected: false},
+		{Name: "no tokens", Token: "abc", Tokens: nil, Expected: false},
+		{Name: "empty token", Token: "", Tokens: []string{""}, Expected: false},
+	}
+	for _, test := range tests {
+		if result := isPreShared(test.Token, test.Tokens); result != test.Expected {
+			t.Errorf("%s: Pre-shared token check incorrect. Got %v, Want %v", test.Name, result, test.Expected)
+		}
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	//SHA-256 of "token"
+	expected := "3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0"
+	if hash := HashToken("token"); hash != expected {
+		t.Errorf("Token hash incorrect. Got %s, Want %s", hash, expected)
+	}
+}
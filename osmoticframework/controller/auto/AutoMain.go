@@ -9,6 +9,8 @@ import (
 	"osmoticframework/controller/alert"
 	"osmoticframework/controller/api/impl/request"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/reconcile"
+	"osmoticframework/controller/replicaset"
 	"osmoticframework/controller/types"
 	"osmoticframework/controller/util"
 	"osmoticframework/controller/vars"
@@ -17,7 +19,6 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -27,12 +28,22 @@ var startLoop = make(chan bool)
 
 var repoDirectories sync.Map
 
-var executorCount int64
-var executorCountMax int64
+//The executors run as a replica set, so that exactly the required number is kept running across the agents
+//The set is deleted when the FL job ends, so that finished executors are not deployed again
+const executorReplicaSet = "executor"
+
+//Executors that finished the current FL job. The job ends once all of them have
+var finishedExecutors = make(map[string]bool)
+var finishedLock sync.Mutex
+
 var tmpDir string
 
 // AutoMain Auto deploy logic main
 func AutoMain() {
+	//Subscribe before anything else, so that no alert is missed
+	crashes, _ := alert.ContainerCrash.Subscribe("auto", alert.DefaultBuffer)
+	disconnects, _ := alert.AgentDisconnect.Subscribe("auto", alert.DefaultBuffer)
+	performanceIssues, _ := alert.PerformanceIssues.Subscribe("auto", alert.DefaultBuffer)
 	//Wait for controller API to get ready
 	for vars.GetApiReady() {
 	}
@@ -58,16 +69,21 @@ func AutoMain() {
 	}()
 	go func() {
 		//Crash handler
-		for crash := range alert.ContainerCrash {
+		for _crash := range crashes {
+			crash := _crash.(types.ContainerCrashReport)
 			crashHandle(crash)
 		}
 	}()
 	//Disconnected agents
-	for agent := range alert.AgentDisconnect {
-		disconnectedAgent(agent)
-	}
+	go func() {
+		for _agent := range disconnects {
+			agent := _agent.(types.OfflineAgent)
+			disconnectedAgent(agent)
+		}
+	}()
 	//Performance issues
-	for agent := range alert.PerformanceIssues {
+	for _agent := range performanceIssues {
+		agent := _agent.(types.PerformanceReport)
 		performanceAlert(agent)
 	}
 }
@@ -210,8 +226,22 @@ func loop() {
 					log.Error.Println("Error on copy nas_201_pth: " + err.Error())
 				}
 			}
-			atomic.StoreInt64(&executorCountMax, int64(config["device"].(map[string]interface{})["num_of_clients"].(float64)))
-			log.Info.Println("Number of required executors is now " + strconv.FormatInt(atomic.LoadInt64(&executorCountMax), 10))
+			executorCount := int(config["device"].(map[string]interface{})["num_of_clients"].(float64))
+			err = replicaset.Apply(types.ReplicaSet{
+				Name:       executorReplicaSet,
+				DeployArgs: executorContainer,
+				Replicas:   executorCount,
+				Spread:     types.SpreadOnePerAgent,
+			})
+			if err != nil {
+				log.Error.Println("Error on applying executor replica set: " + err.Error())
+			} else {
+				log.Info.Println("Number of required executors is now " + strconv.Itoa(executorCount))
+				//A new job starts
+				finishedLock.Lock()
+				finishedExecutors = make(map[string]bool)
+				finishedLock.Unlock()
+			}
 			flConfig.Close()
 			// Begin build image
 			log.Info.Println("Building image for " + repoName)
@@ -308,29 +338,25 @@ func customIn(eventMessage string) {
 }
 
 //Agent join event
+//Executors are placed on new agents by their replica set
 func agentJoin(agentId string) {
 	//Insert logic when new agent joined
-	if executorCount == atomic.LoadInt64(&executorCountMax) {
-		return
-	}
-	log.Info.Println("Deploying executor to agent: " + agentId)
-	response := request.RunRequest(agentId, executorContainer, types.AuthInfo{}, 500)
-	result := <-response.Result
-	if result.ResultType == request.Error {
-		log.Error.Println("Error on deploy executor to agent: " + agentId + " - " + result.Content.(error).Error())
-	}
 	log.Info.Println("Deploying influxdb to agent: " + agentId)
-	response = request.RunRequest(agentId, influxdbContainer, types.AuthInfo{}, 180)
-	result = <-response.Result
+	response := request.RunRequest(agentId, influxdbContainer, types.AuthInfo{}, 180)
+	result := <-response.Result
 	if result.ResultType == request.Error {
 		log.Error.Println("Error on deploy influxdb to agent: " + agentId + " - " + result.Content.(error).Error())
 	}
-	executorCount++
 }
 
 //Container crash handler. This include s succeeded containers
 //Crashes are automatically logged. You do not need to log again
 func crashHandle(crash types.ContainerCrashReport) {
+	//Executors exit with code 0 once the FL job is done. Finished executors are not crashes, so there is no grace period
+	if crash.Status == "exited" && crash.ExitCode == 0 && reconcile.SameImage(crash.Image, executorContainer.Image) {
+		executorFinished(crash.ID)
+		return
+	}
 	//Ignore any crashes that occurred during the first few seconds of controller startup
 	//As containers might require other dependencies containers to start, if the dependency hasn't started yet. They will just crash
 	//This gives a grace period before the controller really treat container crashes as real issues
@@ -339,10 +365,33 @@ func crashHandle(crash types.ContainerCrashReport) {
 	}
 }
 
+//Deletes the executor replica set once all executors have finished, so that they are not deployed again
+func executorFinished(containerId string) {
+	set, err := replicaset.Get(executorReplicaSet)
+	if err != nil {
+		//Already deleted
+		return
+	}
+	finishedLock.Lock()
+	defer finishedLock.Unlock()
+	finishedExecutors[containerId] = true
+	if len(finishedExecutors) < set.Replicas {
+		log.Info.Printf("Executor %s finished. %d of %d executors done\n", containerId, len(finishedExecutors), set.Replicas)
+		return
+	}
+	log.Info.Println("All executors finished. Deleting the executor replica set")
+	err = replicaset.Delete(executorReplicaSet)
+	if err != nil && err != replicaset.ErrNotFound {
+		log.Error.Println("Error on deleting executor replica set: " + err.Error())
+		return
+	}
+	finishedExecutors = make(map[string]bool)
+}
+
 func disconnectedAgent(agent types.OfflineAgent) {
 	//Handle agents that have disconnected.
 	//They usually have containers running while it disconnects, we must handle any workflow that might have been interrupted by this event
-	executorCount--
+	//Lost executors are deployed again by their replica set
 	log.Error.Printf("Affected container IDs: %#v", agent.Agent.Containers)
 }
 
@@ -0,0 +1,211 @@
+package autoscale
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"osmoticframework/controller/alert"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/types/metric"
+	"osmoticframework/controller/vars"
+	"time"
+)
+
+/*
+Horizontal autoscaling of cloud deployments
+Every interval, each policy measures its deployment and sets the replicas to ceil(value / target), within the bounds of the policy. See types.ScalingPolicy
+Only deployments created by KRunDeployment are scaled. They carry the deploymentId label.
+Values within 10% of the capacity of the current replicas do not change the replicas, so that noisy metrics do not resize the deployment every interval.
+Scaling down waits for the cooldown after the last scaling. Scaling up is never delayed, so that load spikes are handled right away.
+Changes are pushed to alert.DeploymentScaling
+*/
+
+//Fraction around the current capacity where the replicas are kept
+const tolerance = 0.1
+
+//Seconds after a scaling before scaling down, if the policy does not set one
+const defaultCooldown = 300
+
+//Only accessed by the evaluation loop. Deployment name -> time of the last scaling
+var lastScaled = make(map[string]time.Time)
+
+//Runs the evaluation loop. This blocks until the controller terminates
+func Start() {
+	if vars.GetKuberConfigPath() == "" || vars.GetPrometheusAddress() == "" {
+		log.Warn.Println("Autoscaling requires Kubernetes and Prometheus. Ignoring autoscaling policies")
+		return
+	}
+	policies := make([]types.ScalingPolicy, 0)
+	seen := make(map[string]bool)
+	for _, policy := range vars.GetScalingPolicies() {
+		err := validate(policy)
+		if err == nil && seen[policy.Deployment] {
+			err = errors.New("deployment already has a policy")
+		}
+		if err != nil {
+			log.Warn.Printf("Ignoring autoscaling policy of deployment %s: %s\n", policy.Deployment, err)
+			continue
+		}
+		seen[policy.Deployment] = true
+		policies = append(policies, policy)
+	}
+	if len(policies) == 0 {
+		return
+	}
+	interval := time.Duration(vars.GetAutoscaleInterval()) * time.Second
+	log.Info.Printf("Autoscaling %d deployments every %s\n", len(policies), interval)
+	for !vars.IsTerminate() {
+		time.Sleep(interval)
+		for _, policy := range policies {
+			evaluate(policy, time.Now())
+		}
+	}
+}
+
+func validate(policy types.ScalingPolicy) error {
+	if policy.Deployment == "" {
+		return errors.New("deployment is required")
+	}
+	if policy.MinReplicas < 1 {
+		return errors.New("min_replicas must be at least 1")
+	}
+	if policy.MaxReplicas < policy.MinReplicas {
+		return errors.New("max_replicas cannot be lower than min_replicas")
+	}
+	if (policy.TargetCPU > 0) == (policy.Query != "") {
+		return errors.New("set either target_cpu or query")
+	}
+	if policy.Query != "" && policy.TargetValue <= 0 {
+		return errors.New("target_value must be above 0")
+	}
+	if policy.Cooldown < 0 {
+		return errors.New("cooldown cannot be negative")
+	}
+	return nil
+}
+
+//Measures a policy and scales its deployment if needed
+func evaluate(policy types.ScalingPolicy, now time.Time) {
+	deployment, err := request.KGetDeployment(policy.Deployment)
+	if err != nil {
+		log.Warn.Printf("Failed reading deployment %s for autoscaling\n", policy.Deployment)
+		log.Warn.Println(err)
+		return
+	}
+	if deployment.PodArgs.Label["deploymentId"] == "" {
+		log.Warn.Printf("Deployment %s was not created by the controller. Not autoscaling\n", policy.Deployment)
+		return
+	}
+	value, err := measure(policy, now)
+	if err != nil {
+		log.Warn.Printf("Failed measuring deployment %s for autoscaling\n", policy.Deployment)
+		log.Warn.Println(err)
+		return
+	}
+	replicas := desired(policy, deployment.Replicas, value)
+	if replicas == deployment.Replicas {
+		return
+	}
+	if replicas < deployment.Replicas && now.Sub(lastScaled[policy.Deployment]) < cooldown(policy) {
+		return
+	}
+	err = request.KScaleDeployment(policy.Deployment, replicas)
+	if err != nil {
+		return
+	}
+	lastScaled[policy.Deployment] = now
+	direction := "up"
+	if replicas < deployment.Replicas {
+		direction = "down"
+	}
+	log.Info.Printf("Scaled deployment %s %s from %d to %d replicas. Value %g with target %g per replica\n",
+		policy.Deployment, direction, deployment.Replicas, replicas, value, target(policy))
+	metrics.DeploymentScalings.Inc(policy.Deployment, direction)
+	alert.DeploymentScaling.Publish(types.ScalingReport{
+		Deployment: policy.Deployment,
+		From:       deployment.Replicas,
+		To:         replicas,
+		Value:      value,
+		Target:     target(policy),
+	})
+}
+
+//The replicas that handle the value at the target of the policy, within its bounds
+func desired(policy types.ScalingPolicy, current int32, value float64) int32 {
+	replicas := float64(current)
+	capacity := target(policy) * float64(current)
+	if current <= 0 || math.Abs(value/capacity-1) > tolerance {
+		replicas = math.Ceil(value / target(policy))
+	}
+	if replicas < float64(policy.MinReplicas) {
+		return policy.MinReplicas
+	}
+	if replicas > float64(policy.MaxReplicas) {
+		return policy.MaxReplicas
+	}
+	return int32(replicas)
+}
+
+func target(policy types.ScalingPolicy) float64 {
+	if policy.Query != "" {
+		return policy.TargetValue
+	}
+	return policy.TargetCPU
+}
+
+func cooldown(policy types.ScalingPolicy) time.Duration {
+	if policy.Cooldown == 0 {
+		return defaultCooldown * time.Second
+	}
+	return time.Duration(policy.Cooldown) * time.Second
+}
+
+//The result of the query of the policy, or the cores used by all running pods of the deployment
+func measure(policy types.ScalingPolicy, now time.Time) (float64, error) {
+	if policy.Query != "" {
+		result, err := request.KQuery(policy.Query, now)
+		if err != nil {
+			return 0, err
+		}
+		return queryValue(result)
+	}
+	pods, err := request.KListPods(types.KWorkloadDeployment, policy.Deployment)
+	if err != nil {
+		return 0, err
+	}
+	usage := 0.0
+	for _, pod := range pods {
+		result, err := request.KCPUPodAvg(pod, now)
+		if err != nil {
+			return 0, err
+		}
+		for _, podUsage := range result {
+			usage += podUsage.Usage
+		}
+	}
+	return usage, nil
+}
+
+//Queries must return a scalar or a single series
+func queryValue(result *metric.PromMetric) (float64, error) {
+	var scalar metric.Scalar
+	switch result.Type {
+	case metric.ScalarType:
+		scalar = result.Data.(metric.Scalar)
+	case metric.VectorType:
+		vectors := result.Data.([]metric.Vector)
+		if len(vectors) != 1 {
+			return 0, fmt.Errorf("query returned %d series instead of 1", len(vectors))
+		}
+		scalar = vectors[0].Scalar
+	default:
+		return 0, fmt.Errorf("query returned a %s instead of a scalar or vector", result.Type)
+	}
+	if scalar.Undefined {
+		return 0, errors.New("query returned no value")
+	}
+	return scalar.Value, nil
+}
@@ -0,0 +1,78 @@
+package autoscale
+
+import (
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/types/metric"
+	"testing"
+)
+
+func TestDesired(t *testing.T) {
+	cpu := types.ScalingPolicy{Deployment: "aggregator", MinReplicas: 1, MaxReplicas: 5, TargetCPU: 0.5}
+	query := types.ScalingPolicy{Deployment: "aggregator", MinReplicas: 2, MaxReplicas: 10, Query: "sum(executors)", TargetValue: 100}
+	tests := []struct {
+		Name    string
+		Policy  types.ScalingPolicy
+		Current int32
+		Value   float64
+		Want    int32
+	}{
+		{"scale up", cpu, 1, 1.2, 3},
+		{"scale down", cpu, 4, 0.6, 2},
+		//Within 10% of the capacity of 2 replicas
+		{"tolerance", cpu, 2, 1.08, 2},
+		{"above maximum", cpu, 2, 10, 5},
+		{"idle", cpu, 3, 0, 1},
+		{"query", query, 2, 450, 5},
+		{"below minimum", query, 3, 50, 2},
+	}
+	for _, test := range tests {
+		replicas := desired(test.Policy, test.Current, test.Value)
+		if replicas != test.Want {
+			t.Errorf("%s: Replicas incorrect. Got %d, Want %d", test.Name, replicas, test.Want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Policy types.ScalingPolicy
+		Valid  bool
+	}{
+		{"cpu", types.ScalingPolicy{Deployment: "aggregator", MinReplicas: 1, MaxReplicas: 3, TargetCPU: 0.5}, true},
+		{"query", types.ScalingPolicy{Deployment: "aggregator", MinReplicas: 1, MaxReplicas: 3, Query: "up", TargetValue: 1}, true},
+		{"no deployment", types.ScalingPolicy{MinReplicas: 1, MaxReplicas: 3, TargetCPU: 0.5}, false},
+		{"no minimum", types.ScalingPolicy{Deployment: "aggregator", MaxReplicas: 3, TargetCPU: 0.5}, false},
+		{"bounds", types.ScalingPolicy{Deployment: "aggregator", MinReplicas: 4, MaxReplicas: 3, TargetCPU: 0.5}, false},
+		{"both targets", types.ScalingPolicy{Deployment: "aggregator", MinReplicas: 1, MaxReplicas: 3, TargetCPU: 0.5, Query: "up", TargetValue: 1}, false},
+		{"no target", types.ScalingPolicy{Deployment: "aggregator", MinReplicas: 1, MaxReplicas: 3}, false},
+		{"query without target", types.ScalingPolicy{Deployment: "aggregator", MinReplicas: 1, MaxReplicas: 3, Query: "up"}, false},
+	}
+	for _, test := range tests {
+		err := validate(test.Policy)
+		if (err == nil) != test.Valid {
+			t.Errorf("%s: Validation incorrect. Got %v, Want valid %t", test.Name, err, test.Valid)
+		}
+	}
+}
+
+func TestQueryValue(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Result metric.PromMetric
+		Want   float64
+		Valid  bool
+	}{
+		{"scalar", metric.PromMetric{Type: metric.ScalarType, Data: metric.Scalar{Value: 3}}, 3, true},
+		{"vector", metric.PromMetric{Type: metric.VectorType, Data: []metric.Vector{{Scalar: metric.Scalar{Value: 7}}}}, 7, true},
+		{"several series", metric.PromMetric{Type: metric.VectorType, Data: []metric.Vector{{}, {}}}, 0, false},
+		{"no data", metric.PromMetric{Type: metric.ScalarType, Data: metric.Scalar{Undefined: true}}, 0, false},
+		{"matrix", metric.PromMetric{Type: metric.MatrixType, Data: []metric.Matrix{}}, 0, false},
+	}
+	for _, test := range tests {
+		value, err := queryValue(&test.Result)
+		if (err == nil) != test.Valid || value != test.Want {
+			t.Errorf("%s: Value incorrect. Got %g (%v), Want %g", test.Name, value, err, test.Want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"database/sql"
+	"osmoticframework/controller/alert"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"time"
+)
+
+/*
+	Registration authentication
+	Agent secrets are stored in "agentKeys", one-time bootstrap tokens in "bootstrapTokens" and revoked agents in "revokedAgents"
+	Secrets and tokens are not included in database error reports
+*/
+
+//Runs a statement that changes the database. reportArgs are included in the error report instead of the actual arguments
+func execute(query string, reportArgs []string, args ...interface{}) (sql.Result, error) {
+	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: reportArgs,
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during connecting to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer db.Close()
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: reportArgs,
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during writing to database")
+		log.Error.Println(err)
+	}
+	return result, err
+}
+
+//Stores the secret of an agent, replacing any previous one
+func SetAgentKey(agentId, secret string) error {
+	query := "INSERT INTO agentKeys (AgentId, Secret) VALUES (?, ?) ON DUPLICATE KEY UPDATE Secret = VALUES(Secret)"
+	_, err := execute(query, []string{agentId}, agentId, secret)
+	return err
+}
+
+//Gets the secret of an agent. Returns sql.ErrNoRows if the agent has none
+func GetAgentKey(agentId string) (string, error) {
+	query := "SELECT Secret FROM agentKeys WHERE AgentId = ?"
+	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{agentId},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during connecting to database")
+		log.Error.Println(err)
+		return "", err
+	}
+	defer db.Close()
+	var secret string
+	err = db.QueryRow(query, agentId).Scan(&secret)
+	if err != nil && err != sql.ErrNoRows {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{agentId},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred query to database")
+		log.Error.Println(err)
+	}
+	return secret, err
+}
+
+//Gets the secrets of all agents. Agent ID -> secret
+func ListAgentKeys() (map[string]string, error) {
+	query := "SELECT AgentId, Secret FROM agentKeys"
+	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during connecting to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(query)
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred query to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make(map[string]string)
+	for rows.Next() {
+		var agentId, secret string
+		if err := rows.Scan(&agentId, &secret); err != nil {
+			log.Error.Println("Error occurred reading from database")
+			log.Error.Println(err)
+			return nil, err
+		}
+		keys[agentId] = secret
+	}
+	return keys, nil
+}
+
+//Stores a new bootstrap token by its hash. expires may be nil
+func AddBootstrapToken(tokenHash, description string, expires *time.Time) error {
+	query := "INSERT INTO bootstrapTokens (TokenHash, Description, Created, Expires) VALUES (?, ?, ?, ?)"
+	var expiresColumn interface{}
+	if expires != nil {
+		expiresColumn = expires.UTC().Format(timeLayout)
+	}
+	_, err := execute(query, []string{description}, tokenHash, description, time.Now().UTC().Format(timeLayout), expiresColumn)
+	return err
+}
+
+//Marks a bootstrap token as used. Returns false if the token does not exist, is used or has expired
+func ConsumeBootstrapToken(tokenHash string) (bool, error) {
+	query := "UPDATE bootstrapTokens SET Used = TRUE WHERE TokenHash = ? AND Used = FALSE AND (Expires IS NULL OR Expires > UTC_TIMESTAMP())"
+	result, err := execute(query, []string{}, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+//Lists all bootstrap tokens, including used and expired ones
+func ListBootstrapTokens() ([]types.BootstrapToken, error) {
+	query := "SELECT TokenHash, Description, Created, Expires, Used FROM bootstrapTokens ORDER BY Created"
+	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during connecting to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(query)
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred query to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+	tokens := make([]types.BootstrapToken, 0)
+	for rows.Next() {
+		var token types.BootstrapToken
+		var created string
+		var expires sql.NullString
+		err := rows.Scan(&token.ID, &token.Description, &created, &expires, &token.Used)
+		if err != nil {
+			log.Error.Println("Error occurred reading from database")
+			log.Error.Println(err)
+			return nil, err
+		}
+		token.Created, _ = time.Parse(timeLayout, created)
+		if expires.Valid {
+			expiresTime, err := time.Parse(timeLayout, expires.String)
+			if err == nil {
+				token.Expires = &expiresTime
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+//Deletes a bootstrap token. Returns false if there is no such token
+func DeleteBootstrapToken(tokenHash string) (bool, error) {
+	query := "DELETE FROM bootstrapTokens WHERE TokenHash = ?"
+	result, err := execute(query, []string{}, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+//Records an agent as revoked and deletes its secret
+func RevokeAgent(agentId string) error {
+	_, err := execute("INSERT IGNORE INTO revokedAgents (AgentId) VALUES (?)", []string{agentId}, agentId)
+	if err != nil {
+		return err
+	}
+	_, err = execute("DELETE FROM agentKeys WHERE AgentId = ?", []string{agentId}, agentId)
+	return err
+}
+
+//Lists the IDs of all revoked agents
+func ListRevokedAgents() ([]string, error) {
+	query := "SELECT AgentId FROM revokedAgents"
+	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during connecting to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(query)
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred query to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+	agentIds := make([]string, 0)
+	for rows.Next() {
+		var agentId string
+		if err := rows.Scan(&agentId); err != nil {
+			log.Error.Println("Error occurred reading from database")
+			log.Error.Println(err)
+			return nil, err
+		}
+		agentIds = append(agentIds, agentId)
+	}
+	return agentIds, nil
+}
@@ -2,10 +2,13 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"osmoticframework/controller/alert"
 	"osmoticframework/controller/log"
 	"osmoticframework/controller/types"
 	"osmoticframework/controller/vars"
+	"strconv"
+	"time"
 )
 
 /*
@@ -17,17 +20,50 @@ type DBContainer struct {
 	ContainerID string
 	AgentID     string
 	Status      string
+	//Nil for containers added before the deploy arguments were stored
+	DeployArgs  *types.DeployArgs
+	RequestTime time.Time
+	Origin      types.ContainerOrigin
+	//Number of times Docker restarted the container
+	Restarts int
 }
 
-func AddContainer(agentId, containerId string) {
-	query := "INSERT INTO containers (ContainerId, AgentId, Status) VALUES (?, ?, 'running')"
+//MySQL DATETIME format. Times are stored in UTC
+const timeLayout = "2006-01-02 15:04:05"
+
+//Converts a container spec to the DeployArgs, RequestTime and Origin columns
+//Containers without a spec are recorded as API requests sent now
+func specColumns(spec *types.ContainerSpec) (interface{}, string, string) {
+	if spec == nil {
+		return nil, time.Now().UTC().Format(timeLayout), string(types.OriginAPI)
+	}
+	requestTime := spec.RequestTime
+	if requestTime.IsZero() {
+		requestTime = time.Now()
+	}
+	origin := spec.Origin
+	if origin == "" {
+		origin = types.OriginAPI
+	}
+	deployArgs, err := json.Marshal(spec.DeployArgs)
+	if err != nil {
+		log.Error.Println("Error occurred encoding deploy arguments")
+		log.Error.Println(err)
+		return nil, requestTime.UTC().Format(timeLayout), string(origin)
+	}
+	return string(deployArgs), requestTime.UTC().Format(timeLayout), string(origin)
+}
+
+//Adds a container with the spec it was deployed with. The spec may be nil if it is unknown
+func AddContainer(agentId, containerId string, spec *types.ContainerSpec) {
+	query := "INSERT INTO containers (ContainerId, AgentId, Status, DeployArgs, RequestTime, Origin) VALUES (?, ?, 'running', ?, ?, ?)"
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during connecting to database")
 		log.Error.Println(err)
 		return
@@ -35,22 +71,23 @@ func AddContainer(agentId, containerId string) {
 	defer db.Close()
 	stmt, err := db.Prepare(query)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return
 	}
-	_, err = stmt.Query(containerId, agentId)
+	deployArgs, requestTime, origin := specColumns(spec)
+	_, err = stmt.Exec(containerId, agentId, deployArgs, requestTime, origin)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return
@@ -58,17 +95,11 @@ func AddContainer(agentId, containerId string) {
 	//Update the agent in memory
 	agentInterface, _ := vars.Agents.Load(agentId)
 	agent := agentInterface.(types.Agent)
-	//Replace the entire agent struct. Since sync.Map does not allow changing variables within a struct
+	//Store a modified copy of the agent struct. Since sync.Map does not allow changing variables within a struct
 	containers := agent.Containers
 	containers = append(containers, containerId)
-	vars.Agents.Store(agentId, types.Agent{
-		InternalIP:    agent.InternalIP,
-		DeviceSupport: agent.DeviceSupport,
-		SensorSupport: agent.SensorSupport,
-		Containers:    containers,
-		LastAlive:     agent.LastAlive,
-		PingSeq:       agent.PingSeq,
-	})
+	agent.Containers = containers
+	vars.Agents.Store(agentId, agent)
 }
 
 //InfluxDB does not support updating entries. We must delete the row and insert again.
@@ -76,11 +107,11 @@ func StopContainer(agentId, containerId string) {
 	query := "UPDATE containers SET containers.Status = 'stopped' WHERE AgentId = ? AND ContainerId = ?"
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during connecting to database")
 		log.Error.Println(err)
 		return
@@ -88,37 +119,44 @@ func StopContainer(agentId, containerId string) {
 	defer db.Close()
 	stmt, err := db.Prepare(query)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return
 	}
 	_, err = stmt.Query(agentId, containerId)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 	}
 }
 
+//Updates the status and restart count of a container from its lifecycle events. Containers unknown to the database are ignored
+func SetContainerStatus(agentId, containerId, status string, restarts int) error {
+	query := "UPDATE containers SET Status = ?, Restarts = ? WHERE AgentId = ? AND ContainerId = ?"
+	_, err := execute(query, []string{status, strconv.Itoa(restarts), agentId, containerId}, status, restarts, agentId, containerId)
+	return err
+}
+
 //Remove the row completely.
 func RemoveContainer(agentId, containerId string) {
 	query := "DELETE FROM containers WHERE AgentId = ? AND ContainerId = ?"
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during connecting to database")
 		log.Error.Println(err)
 		return
@@ -126,29 +164,29 @@ func RemoveContainer(agentId, containerId string) {
 	defer db.Close()
 	stmt, err := db.Prepare(query)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return
 	}
 	_, err = stmt.Query(agentId, containerId)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 	}
 	//Update the agent in memory
 	agentInterface, _ := vars.Agents.Load(agentId)
 	agent := agentInterface.(types.Agent)
-	//Replace the entire agent struct. Since sync.Map does not allow changing variables within a struct
+	//Store a modified copy of the agent struct. Since sync.Map does not allow changing variables within a struct
 	containers := agent.Containers
 	//Find the array index of where the container ID is stored
 	index := 0
@@ -158,26 +196,21 @@ func RemoveContainer(agentId, containerId string) {
 			index++
 		}
 	}
-	vars.Agents.Store(agentId, types.Agent{
-		InternalIP:    agent.InternalIP,
-		DeviceSupport: agent.DeviceSupport,
-		SensorSupport: agent.SensorSupport,
-		//Slice out the removed container ID
-		Containers: containers[:index],
-		LastAlive:  agent.LastAlive,
-		PingSeq:    agent.PingSeq,
-	})
+	//Slice out the removed container ID
+	agent.Containers = containers[:index]
+	vars.Agents.Store(agentId, agent)
 }
 
-func UpdateContainer(agentId, oldContainer, containerId string) {
-	query := "UPDATE containers SET ContainerId = ? WHERE AgentId = ? AND ContainerId = ?"
+//Replaces a container with the updated one. The spec may be nil if it is unknown
+func UpdateContainer(agentId, oldContainer, containerId string, spec *types.ContainerSpec) {
+	query := "UPDATE containers SET ContainerId = ?, Status = 'running', DeployArgs = ?, RequestTime = ?, Origin = ? WHERE AgentId = ? AND ContainerId = ?"
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId, oldContainer},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during connecting to database")
 		log.Error.Println(err)
 		return
@@ -185,22 +218,23 @@ func UpdateContainer(agentId, oldContainer, containerId string) {
 	defer db.Close()
 	stmt, err := db.Prepare(query)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId, oldContainer},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return
 	}
-	_, err = stmt.Query(containerId, agentId, oldContainer)
+	deployArgs, requestTime, origin := specColumns(spec)
+	_, err = stmt.Exec(containerId, deployArgs, requestTime, origin, agentId, oldContainer)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{containerId, agentId, oldContainer},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return
@@ -208,7 +242,7 @@ func UpdateContainer(agentId, oldContainer, containerId string) {
 	//Update the agent in memory
 	agentInterface, _ := vars.Agents.Load(agentId)
 	agent := agentInterface.(types.Agent)
-	//Replace the entire agent struct. Since sync.Map does not allow changing variables within a struct
+	//Store a modified copy of the agent struct. Since sync.Map does not allow changing variables within a struct
 	containers := agent.Containers
 	//Find the array index of where the container ID is stored
 	index := 0
@@ -222,26 +256,20 @@ func UpdateContainer(agentId, oldContainer, containerId string) {
 	containers = containers[:index]
 	//Add the new one back
 	containers = append(containers, containerId)
-	vars.Agents.Store(agentId, types.Agent{
-		InternalIP:    agent.InternalIP,
-		DeviceSupport: agent.DeviceSupport,
-		SensorSupport: agent.SensorSupport,
-		Containers:    containers,
-		LastAlive:     agent.LastAlive,
-		PingSeq:       agent.PingSeq,
-	})
+	agent.Containers = containers
+	vars.Agents.Store(agentId, agent)
 }
 
 //List all containers registered to the database
 func ListContainers() []DBContainer {
-	query := "SELECT * FROM containers"
+	query := "SELECT " + containerColumns + " FROM containers"
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during connecting to database")
 		log.Error.Println(err)
 		return nil
@@ -249,22 +277,22 @@ func ListContainers() []DBContainer {
 	defer db.Close()
 	stmt, err := db.Prepare(query)
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred query to database")
 		log.Error.Println(err)
 		return nil
 	}
 	rows, err := stmt.Query()
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred query to database")
 		log.Error.Println(err)
 		return nil
@@ -272,18 +300,73 @@ func ListContainers() []DBContainer {
 	defer rows.Close()
 	containers := make([]DBContainer, 0)
 	for rows.Next() {
-		var AgentID, ContainerID, Status string
-		err := rows.Scan(&ContainerID, &AgentID, &Status)
+		container, err := scanContainer(rows)
 		if err != nil {
 			log.Error.Println("Error occurred reading from database")
 			log.Error.Println(err)
 			return nil
 		}
-		containers = append(containers, DBContainer{
-			AgentID:     AgentID,
-			ContainerID: ContainerID,
-			Status:      Status,
-		})
+		containers = append(containers, container)
 	}
 	return containers
 }
+
+//Gets a container of an agent registered to the database. Returns sql.ErrNoRows if there is no such container
+func GetContainer(agentId, containerId string) (DBContainer, error) {
+	query := "SELECT " + containerColumns + " FROM containers WHERE AgentId = ? AND ContainerId = ?"
+	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{agentId, containerId},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during connecting to database")
+		log.Error.Println(err)
+		return DBContainer{}, err
+	}
+	defer db.Close()
+	container, err := scanContainer(db.QueryRow(query, agentId, containerId))
+	if err != nil && err != sql.ErrNoRows {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{agentId, containerId},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred query to database")
+		log.Error.Println(err)
+	}
+	return container, err
+}
+
+//Columns read by scanContainer
+const containerColumns = "ContainerId, AgentId, Status, DeployArgs, RequestTime, Origin, Restarts"
+
+//Reads a container row. Works with both sql.Row and sql.Rows
+func scanContainer(row interface{ Scan(...interface{}) error }) (DBContainer, error) {
+	var AgentID, ContainerID, Status, RequestTime, Origin string
+	var DeployArgs sql.NullString
+	var Restarts int
+	err := row.Scan(&ContainerID, &AgentID, &Status, &DeployArgs, &RequestTime, &Origin, &Restarts)
+	if err != nil {
+		return DBContainer{}, err
+	}
+	container := DBContainer{
+		AgentID:     AgentID,
+		ContainerID: ContainerID,
+		Status:      Status,
+		Origin:      types.ContainerOrigin(Origin),
+		Restarts:    Restarts,
+	}
+	container.RequestTime, _ = time.Parse(timeLayout, RequestTime)
+	if DeployArgs.Valid {
+		var deployArgs types.DeployArgs
+		if err := json.Unmarshal([]byte(DeployArgs.String), &deployArgs); err != nil {
+			log.Warn.Printf("Deploy arguments of container %s cannot be read\n", ContainerID)
+			log.Warn.Println(err)
+		} else {
+			container.DeployArgs = &deployArgs
+		}
+	}
+	return container, nil
+}
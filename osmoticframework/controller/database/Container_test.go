@@ -0,0 +1,70 @@
+package database
+
+import (
+	"encoding/json"
+	"osmoticframework/controller/types"
+	"testing"
+	"time"
+)
+
+func TestSpecColumns(t *testing.T) {
+	requestTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		Name   string
+		Spec   *types.ContainerSpec
+		Image  string
+		Time   string
+		Origin string
+	}{
+		{
+			Name:   "unknown spec",
+			Spec:   nil,
+			Origin: "api",
+		},
+		{
+			Name: "scheduled",
+			Spec: &types.ContainerSpec{
+				DeployArgs:  types.DeployArgs{Image: "nginx"},
+				AuthInfo:    types.AuthInfo{Username: "user", Password: "secret"},
+				RequestTime: requestTime,
+				Origin:      types.OriginScheduler,
+			},
+			Image:  "nginx",
+			Time:   "2021-03-04 05:06:07",
+			Origin: "scheduler",
+		},
+		{
+			Name: "default origin",
+			Spec: &types.ContainerSpec{
+				DeployArgs:  types.DeployArgs{Image: "redis"},
+				RequestTime: requestTime,
+			},
+			Image:  "redis",
+			Time:   "2021-03-04 05:06:07",
+			Origin: "api",
+		},
+	}
+	for _, test := range tests {
+		deployArgs, requestTime, origin := specColumns(test.Spec)
+		if origin != test.Origin {
+			t.Errorf("%s: Origin incorrect. Got %v, Want %v", test.Name, origin, test.Origin)
+		}
+		if test.Time != "" && requestTime != test.Time {
+			t.Errorf("%s: Request time incorrect. Got %v, Want %v", test.Name, requestTime, test.Time)
+		}
+		if test.Spec == nil {
+			if deployArgs != nil {
+				t.Errorf("%s: Deploy arguments incorrect. Got %v, Want %v", test.Name, deployArgs, nil)
+			}
+			continue
+		}
+		var decoded types.DeployArgs
+		if err := json.Unmarshal([]byte(deployArgs.(string)), &decoded); err != nil {
+			t.Errorf("%s: Failed decoding deploy arguments. %v", test.Name, err)
+			continue
+		}
+		if decoded.Image != test.Image {
+			t.Errorf("%s: Image incorrect. Got %v, Want %v", test.Name, decoded.Image, test.Image)
+		}
+	}
+}
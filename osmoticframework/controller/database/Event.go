@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"osmoticframework/controller/alert"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	Event history
+	Crashes, disconnects, performance issues and Kubernetes pod transitions are written to "events". See controller/history
+*/
+
+//MySQL DATETIME(3) format. Times are stored in UTC
+const eventTimeLayout = "2006-01-02 15:04:05.000"
+
+//Default and maximum number of events returned by a query
+const (
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+)
+
+//Adds an event to the history
+func AddEvent(event types.Event) error {
+	query := "INSERT INTO events (Time, Source, AgentId, Type, Target, Payload) VALUES (?, ?, ?, ?, ?, ?)"
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	var payload interface{}
+	if len(event.Payload) > 0 {
+		payload = string(event.Payload)
+	}
+	_, err := execute(query, []string{string(event.Source), event.AgentId, event.Type, event.Target},
+		event.Time.UTC().Format(eventTimeLayout), string(event.Source), nullString(event.AgentId), event.Type, nullString(event.Target), payload)
+	return err
+}
+
+//Queries the history, newest first
+func QueryEvents(filter types.EventFilter) ([]types.Event, error) {
+	query, args := eventQuery(filter)
+	reportArgs := make([]string, 0, len(args))
+	for _, arg := range args {
+		reportArgs = append(reportArgs, arg.(string))
+	}
+	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: reportArgs,
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during connecting to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: reportArgs,
+			Error:     err,
+		})
+		log.Error.Println("Error occurred query to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+	events := make([]types.Event, 0)
+	for rows.Next() {
+		var Time, Source, Type string
+		var AgentId, Target, Payload sql.NullString
+		var event types.Event
+		err := rows.Scan(&event.ID, &Time, &Source, &AgentId, &Type, &Target, &Payload)
+		if err != nil {
+			log.Error.Println("Error occurred reading from database")
+			log.Error.Println(err)
+			return nil, err
+		}
+		event.Time, _ = time.ParseInLocation(eventTimeLayout, Time, time.UTC)
+		event.Source = types.EventSource(Source)
+		event.AgentId = AgentId.String
+		event.Type = Type
+		event.Target = Target.String
+		if Payload.Valid {
+			event.Payload = []byte(Payload.String)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+//Builds the query of a filter. All arguments are strings
+func eventQuery(filter types.EventFilter) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if filter.AgentId != "" {
+		conditions = append(conditions, "AgentId = ?")
+		args = append(args, filter.AgentId)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "Type = ?")
+		args = append(args, filter.Type)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "Time >= ?")
+		args = append(args, filter.From.UTC().Format(eventTimeLayout))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "Time <= ?")
+		args = append(args, filter.To.UTC().Format(eventTimeLayout))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultEventLimit
+	} else if limit > maxEventLimit {
+		limit = maxEventLimit
+	}
+	query := "SELECT ID, Time, Source, AgentId, Type, Target, Payload FROM events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY Time DESC, ID DESC LIMIT " + strconv.Itoa(limit)
+	return query, args
+}
+
+//Empty strings are stored as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
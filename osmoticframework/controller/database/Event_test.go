@@ -0,0 +1,51 @@
+package database
+
+import (
+	"osmoticframework/controller/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventQuery(t *testing.T) {
+	from := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	to := from.Add(time.Hour)
+	tests := []struct {
+		Name   string
+		Filter types.EventFilter
+		Where  string
+		Args   []interface{}
+		Limit  string
+	}{
+		{
+			Name:   "everything",
+			Filter: types.EventFilter{},
+			Args:   []interface{}{},
+			Limit:  "100",
+		},
+		{
+			Name:   "agent and type",
+			Filter: types.EventFilter{AgentId: "agent", Type: "container-crash", Limit: 10},
+			Where:  " WHERE AgentId = ? AND Type = ?",
+			Args:   []interface{}{"agent", "container-crash"},
+			Limit:  "10",
+		},
+		{
+			Name:   "time range",
+			Filter: types.EventFilter{From: from, To: to, Limit: 5000},
+			Where:  " WHERE Time >= ? AND Time <= ?",
+			Args:   []interface{}{"2021-03-04 05:06:07.000", "2021-03-04 06:06:07.000"},
+			Limit:  "1000",
+		},
+	}
+	for _, test := range tests {
+		query, args := eventQuery(test.Filter)
+		want := "SELECT ID, Time, Source, AgentId, Type, Target, Payload FROM events" + test.Where + " ORDER BY Time DESC, ID DESC LIMIT " + test.Limit
+		if query != want {
+			t.Errorf("%s: Query incorrect. Got %v, Want %v", test.Name, query, want)
+		}
+		if !reflect.DeepEqual(args, test.Args) {
+			t.Errorf("%s: Arguments incorrect. Got %v, Want %v", test.Name, args, test.Args)
+		}
+	}
+}
@@ -18,11 +18,11 @@ func GenerateAgentID() string {
 	query := "SELECT * FROM registry WHERE AgentId = ?"
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{agentId},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return ""
@@ -53,11 +53,11 @@ func Register(agentId, internalIP string, devSupport, sensorSupport []string) er
 	query := "INSERT INTO registry (AgentId, InternalIP) VALUES (?, ?)"
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     query,
 			QueryArgs: []string{},
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return err
@@ -69,7 +69,7 @@ func Register(agentId, internalIP string, devSupport, sensorSupport []string) er
 		return err
 	}
 	timestamp := time.Now()
-	_, err = stmt.Exec(agentId, internalIP, timestamp.Format("2006-01-02 15:04:05"))
+	_, err = stmt.Exec(agentId, internalIP)
 	if err != nil {
 		return err
 	}
@@ -78,22 +78,22 @@ func Register(agentId, internalIP string, devSupport, sensorSupport []string) er
 		query := "INSERT INTO devSupport (AgentId, Device) VALUES (? ,?)"
 		stmt, err = db.Prepare(query)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId, dev},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 			continue
 		}
 		_, err = stmt.Exec(agentId, dev)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId, dev},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 		}
@@ -103,22 +103,22 @@ func Register(agentId, internalIP string, devSupport, sensorSupport []string) er
 		query := "INSERT INTO sensorSupport (agentid, sensor) VALUES (?, ?)"
 		stmt, err = db.Prepare(query)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId, sensor},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 			continue
 		}
-		_, err = stmt.Exec(db, sensor)
+		_, err = stmt.Exec(agentId, sensor)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId, sensor},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 		}
@@ -151,11 +151,11 @@ func Unregister(agentId string) {
 	}
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     "",
 			QueryArgs: nil,
 			Error:     err,
-		}
+		})
 		log.Error.Println("Error occurred during writing to database")
 		log.Error.Println(err)
 		return
@@ -164,20 +164,20 @@ func Unregister(agentId string) {
 	for _, query := range queries {
 		stmt, err := db.Prepare(query)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId},
 				Error:     err,
-			}
+			})
 			return
 		}
 		_, err = stmt.Exec(agentId)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 		}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"osmoticframework/controller/alert"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+)
+
+/*
+	Edge replica sets
+	Each set is stored in "replicaSets" as JSON, without its authentication information. See controller/replicaset
+*/
+
+//Stores a replica set, replacing any previous set with the same name
+func SaveReplicaSet(set types.ReplicaSet) error {
+	spec, err := json.Marshal(set)
+	if err != nil {
+		log.Error.Println("Error occurred encoding replica set")
+		log.Error.Println(err)
+		return err
+	}
+	query := "INSERT INTO replicaSets (Name, Spec) VALUES (?, ?) ON DUPLICATE KEY UPDATE Spec = VALUES(Spec)"
+	_, err = execute(query, []string{set.Name}, set.Name, string(spec))
+	return err
+}
+
+//Deletes a replica set. Returns false if there is no such set
+func DeleteReplicaSet(name string) (bool, error) {
+	result, err := execute("DELETE FROM replicaSets WHERE Name = ?", []string{name}, name)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+//Lists all replica sets. Sets that cannot be decoded are skipped
+func ListReplicaSets() ([]types.ReplicaSet, error) {
+	query := "SELECT Name, Spec FROM replicaSets"
+	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred during connecting to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(query)
+	if err != nil {
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
+			Query:     query,
+			QueryArgs: []string{},
+			Error:     err,
+		})
+		log.Error.Println("Error occurred query to database")
+		log.Error.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+	sets := make([]types.ReplicaSet, 0)
+	for rows.Next() {
+		var name, spec string
+		if err := rows.Scan(&name, &spec); err != nil {
+			log.Error.Println("Error occurred reading from database")
+			log.Error.Println(err)
+			return nil, err
+		}
+		var set types.ReplicaSet
+		if err := json.Unmarshal([]byte(spec), &set); err != nil {
+			log.Error.Println("Error occurred decoding replica set " + name)
+			log.Error.Println(err)
+			continue
+		}
+		set.Name = name
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
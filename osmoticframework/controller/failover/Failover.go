@@ -0,0 +1,128 @@
+package failover
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/reconcile"
+	"osmoticframework/controller/replicaset"
+	"osmoticframework/controller/scheduler"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"strings"
+	"time"
+)
+
+/*
+Rescheduling of containers from disconnected agents
+When an agent disconnects, its containers are deployed again on other eligible agents after a grace period.
+The grace period gives agents with unstable connections time to come back before anything is moved.
+If no agent can run a container, it can be deployed to Kubernetes instead. See "failover_to_cloud" in the properties file.
+Containers that belong to a workload in the manifest or a replica set are left to the reconciler of the workload.
+*/
+
+//Called when an agent is marked dead. Rescheduling runs in the background after the grace period
+func AgentLost(agentId string) {
+	if !vars.IsFailoverEnable() {
+		return
+	}
+	//Take the specs now. The agent's containers are removed from the database when it is unregistered
+	specs := make(map[string]types.ContainerSpec)
+	vars.ContainerSpecs.Range(func(containerId, spec interface{}) bool {
+		if spec.(types.ContainerSpec).AgentId == agentId {
+			specs[containerId.(string)] = spec.(types.ContainerSpec)
+		}
+		return true
+	})
+	if len(specs) == 0 {
+		return
+	}
+	grace := time.Duration(vars.GetFailoverGracePeriod()) * time.Second
+	log.Warn.Printf("Failover >> Agent %s has %d containers. Rescheduling in %s unless it reconnects\n", agentId, len(specs), grace)
+	go func() {
+		time.Sleep(grace)
+		if _, ok := vars.Agents.Load(agentId); ok {
+			log.Info.Printf("Failover >> Agent %s reconnected. Containers are not rescheduled\n", agentId)
+			return
+		}
+		for containerId, spec := range specs {
+			vars.ContainerSpecs.Delete(containerId)
+			if managed(spec.DeployArgs.Image) {
+				continue
+			}
+			reschedule(agentId, containerId, spec)
+		}
+	}()
+}
+
+//Deploys the container on another agent, or Kubernetes if allowed
+func reschedule(agentId, containerId string, spec types.ContainerSpec) {
+	newAgentId, task, err := scheduler.Schedule(spec.DeployArgs, spec.AuthInfo, types.Constraints{Exclude: []string{agentId}}, types.OriginFailover, scheduler.DefaultDeployTimeout)
+	if err == nil {
+		result := request.Await(task)
+		if result.ResultType == request.Ok {
+			log.Info.Printf("Failover >> Container %s from agent %s moved to agent %s as %s\n", containerId, agentId, newAgentId, result.Content.(string))
+			return
+		}
+		err = result.Content.(error)
+	}
+	log.Error.Printf("Failover >> Failed moving container %s from agent %s to another agent\n", containerId, agentId)
+	log.Error.Println(err)
+	if !vars.IsFailoverToCloud() {
+		return
+	}
+	deployArgs := toKDeployArgs(containerId, spec.DeployArgs)
+	err = request.KRunDeployment(deployArgs, nil)
+	if err != nil {
+		log.Error.Printf("Failover >> Failed moving container %s from agent %s to the cloud\n", containerId, agentId)
+		log.Error.Println(err)
+		return
+	}
+	log.Info.Printf("Failover >> Container %s from agent %s moved to the cloud as deployment %s\n", containerId, agentId, deployArgs.DeploymentName)
+}
+
+//Checks if an image belongs to a workload in the manifest or a replica set
+func managed(image string) bool {
+	if replicaset.Managed(image) {
+		return true
+	}
+	for _, workload := range reconcile.GetManifest().Workloads {
+		if reconcile.SameImage(workload.DeployArgs.Image, image) {
+			return true
+		}
+	}
+	return false
+}
+
+//Converts the Docker deploy arguments to a Kubernetes deployment with one replica
+//Volumes and devices refer to the edge device, so they are left out
+func toKDeployArgs(containerId string, deployArgs types.DeployArgs) types.KDeployArgs {
+	//Kubernetes names must be lowercase alphanumeric. Docker container IDs are lowercase hex
+	name := "failover-" + strings.ToLower(containerId)
+	if len(name) > 21 {
+		name = name[:21]
+	}
+	podArgs := types.KPodArgs{
+		Image:        deployArgs.Image,
+		Label:        map[string]string{"app": name},
+		Name:         name,
+		ExposePorts:  deployArgs.ExposePorts,
+		Entrypoint:   deployArgs.Entrypoint,
+		Arguments:    deployArgs.Command,
+		Environment:  deployArgs.Environment,
+		MemLimit:     deployArgs.MemLimit,
+		MemSoftLimit: deployArgs.MemSoftLimit,
+		PullPolicy:   corev1.PullIfNotPresent,
+	}
+	if deployArgs.PullOptions == types.PullAlways {
+		podArgs.PullPolicy = corev1.PullAlways
+	}
+	if deployArgs.GPU != nil && deployArgs.GPU.Count != nil && *deployArgs.GPU.Count > 0 {
+		podArgs.Nvidia = *deployArgs.GPU.Count
+	}
+	return types.KDeployArgs{
+		PodArgs:        podArgs,
+		DeploymentName: name,
+		Replicas:       1,
+	}
+}
@@ -0,0 +1,35 @@
+package failover
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"osmoticframework/controller/types"
+	"testing"
+)
+
+func TestToKDeployArgs(t *testing.T) {
+	count := int64(1)
+	deployArgs := types.DeployArgs{
+		Image:       "executor:latest",
+		Command:     []string{"--port", "8080"},
+		Environment: []types.Environment{{Name: "A", Value: "b"}},
+		GPU:         &types.GPU{Count: &count},
+		PullOptions: types.PullAlways,
+	}
+	kDeployArgs := toKDeployArgs("0123456789ABCDEF0123", deployArgs)
+	const expectName = "failover-0123456789ab"
+	if kDeployArgs.DeploymentName != expectName || kDeployArgs.PodArgs.Name != expectName {
+		t.Errorf("Deployment name incorrect. Got %s, Want %s", kDeployArgs.DeploymentName, expectName)
+	}
+	if kDeployArgs.Replicas != 1 {
+		t.Errorf("Replicas incorrect. Got %d, Want 1", kDeployArgs.Replicas)
+	}
+	if kDeployArgs.PodArgs.Image != deployArgs.Image || len(kDeployArgs.PodArgs.Arguments) != 2 || len(kDeployArgs.PodArgs.Environment) != 1 {
+		t.Errorf("Pod arguments incorrect. Got %+v", kDeployArgs.PodArgs)
+	}
+	if kDeployArgs.PodArgs.PullPolicy != corev1.PullAlways {
+		t.Errorf("Pull policy incorrect. Got %s, Want %s", kDeployArgs.PodArgs.PullPolicy, corev1.PullAlways)
+	}
+	if kDeployArgs.PodArgs.Nvidia != 1 {
+		t.Errorf("GPU count incorrect. Got %d, Want 1", kDeployArgs.PodArgs.Nvidia)
+	}
+}
@@ -0,0 +1,168 @@
+package history
+
+import (
+	"encoding/json"
+	"osmoticframework/controller/alert"
+	"osmoticframework/controller/database"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/types"
+	"strings"
+	"time"
+)
+
+/*
+Event history
+Crashes, disconnects, performance issues, container lifecycle events, Kubernetes pod transitions and autoscaler changes are stored in the "events" table, so that failed runs can be investigated afterwards.
+The history subscribes to the alert buses. Database errors are not stored, as failing to store an event raises one.
+Query the history with database.QueryEvents, or GET /api/v1/events of the REST API
+*/
+
+//Pod transitions come in bursts when deployments are scaled
+const podBuffer = 500
+
+//Starts writing events to the database
+func Start() {
+	crashes, _ := alert.ContainerCrash.Subscribe("history", alert.DefaultBuffer)
+	disconnects, _ := alert.AgentDisconnect.Subscribe("history", alert.DefaultBuffer)
+	performanceIssues, _ := alert.PerformanceIssues.Subscribe("history", alert.DefaultBuffer)
+	lifecycle, _ := alert.ContainerEvents.Subscribe("history", alert.DefaultBuffer)
+	pods, _ := alert.PodStatus.Subscribe("history", podBuffer)
+	scalings, _ := alert.DeploymentScaling.Subscribe("history", alert.DefaultBuffer)
+	go func() {
+		for _crash := range crashes {
+			crash := _crash.(types.ContainerCrashReport)
+			record(ContainerCrash(crash, time.Now()))
+		}
+	}()
+	go func() {
+		for _agent := range disconnects {
+			agent := _agent.(types.OfflineAgent)
+			record(AgentDisconnect(agent, time.Now()))
+		}
+	}()
+	go func() {
+		for _report := range performanceIssues {
+			report := _report.(types.PerformanceReport)
+			record(PerformanceIssue(report, time.Now()))
+		}
+	}()
+	go func() {
+		for _event := range lifecycle {
+			event := _event.(types.ContainerEventReport)
+			record(ContainerEvent(event, time.Now()))
+		}
+	}()
+	go func() {
+		for _report := range pods {
+			report := _report.(types.PodStatusReport)
+			record(PodStatus(report, time.Now()))
+		}
+	}()
+	go func() {
+		for _report := range scalings {
+			report := _report.(types.ScalingReport)
+			record(DeploymentScaling(report, time.Now()))
+		}
+	}()
+}
+
+func record(event types.Event) {
+	err := database.AddEvent(event)
+	if err != nil {
+		log.Error.Printf("Failed storing %s event\n", event.Type)
+	}
+}
+
+//Conversion of the alerts of the controller
+
+func ContainerCrash(crash types.ContainerCrashReport, now time.Time) types.Event {
+	event := types.Event{
+		Time:    now,
+		Source:  types.EventSourceAgent,
+		AgentId: crash.AgentId,
+		Type:    "container-crash",
+		Target:  crash.ID,
+		Payload: payload(crash),
+	}
+	//Crashes in the cloud are reported as agents named cloud-deployment, cloud-job and cloud-cronjob
+	if strings.HasPrefix(crash.AgentId, "cloud-") {
+		event.Source = types.EventSourceCloud
+		event.AgentId = ""
+		if crash.Name != "" {
+			event.Target = crash.Name
+		}
+	}
+	return event
+}
+
+//Lifecycle events are stored as container-<action>, e.g. container-oom or container-health-status
+//The time is the time of the Docker event, if the agent sent one
+func ContainerEvent(event types.ContainerEventReport, now time.Time) types.Event {
+	if event.Time != 0 {
+		now = time.Unix(0, event.Time)
+	}
+	return types.Event{
+		Time:    now,
+		Source:  types.EventSourceAgent,
+		AgentId: event.AgentId,
+		Type:    "container-" + strings.ReplaceAll(event.Action, "_", "-"),
+		Target:  event.ID,
+		Payload: payload(event),
+	}
+}
+
+func AgentDisconnect(agent types.OfflineAgent, now time.Time) types.Event {
+	return types.Event{
+		Time:    now,
+		Source:  types.EventSourceAgent,
+		AgentId: agent.ID,
+		Type:    "agent-disconnect",
+		Payload: payload(agent.Agent),
+	}
+}
+
+func PerformanceIssue(report types.PerformanceReport, now time.Time) types.Event {
+	event := types.Event{
+		Time:    now,
+		Source:  types.EventSourceAgent,
+		AgentId: report.AgentId,
+		Type:    string(report.AlertType),
+		Target:  report.Container,
+		Payload: payload(report),
+	}
+	if report.CloudSide {
+		event.Source = types.EventSourceCloud
+		event.AgentId = ""
+	}
+	return event
+}
+
+func PodStatus(report types.PodStatusReport, now time.Time) types.Event {
+	return types.Event{
+		Time:    now,
+		Source:  types.EventSourceCloud,
+		Type:    "pod-status",
+		Target:  report.Pod,
+		Payload: payload(report),
+	}
+}
+
+func DeploymentScaling(report types.ScalingReport, now time.Time) types.Event {
+	return types.Event{
+		Time:    now,
+		Source:  types.EventSourceCloud,
+		Type:    "deployment-scaled",
+		Target:  report.Deployment,
+		Payload: payload(report),
+	}
+}
+
+func payload(report interface{}) json.RawMessage {
+	content, err := json.Marshal(report)
+	if err != nil {
+		log.Warn.Println("Failed encoding event")
+		log.Warn.Println(err)
+		return nil
+	}
+	return content
+}
@@ -0,0 +1,84 @@
+package history
+
+import (
+	"osmoticframework/controller/types"
+	"testing"
+	"time"
+)
+
+func TestConvert(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		Name    string
+		Event   types.Event
+		Source  types.EventSource
+		AgentId string
+		Type    string
+		Target  string
+	}{
+		{
+			Name:    "edge crash",
+			Event:   ContainerCrash(types.ContainerCrashReport{AgentId: "agent", ID: "container", Name: "fl-client"}, now),
+			Source:  types.EventSourceAgent,
+			AgentId: "agent",
+			Type:    "container-crash",
+			Target:  "container",
+		},
+		{
+			Name:   "pod crash",
+			Event:  ContainerCrash(types.ContainerCrashReport{AgentId: "cloud-deployment", ID: "fl-server", Name: "fl-server-7d9f"}, now),
+			Source: types.EventSourceCloud,
+			Type:   "container-crash",
+			Target: "fl-server-7d9f",
+		},
+		{
+			Name:    "lifecycle",
+			Event:   ContainerEvent(types.ContainerEventReport{AgentId: "agent", ID: "container", Action: "health_status", Health: "unhealthy"}, now),
+			Source:  types.EventSourceAgent,
+			AgentId: "agent",
+			Type:    "container-health-status",
+			Target:  "container",
+		},
+		{
+			Name:    "disconnect",
+			Event:   AgentDisconnect(types.OfflineAgent{ID: "agent"}, now),
+			Source:  types.EventSourceAgent,
+			AgentId: "agent",
+			Type:    "agent-disconnect",
+		},
+		{
+			Name:    "performance",
+			Event:   PerformanceIssue(types.PerformanceReport{AgentId: "agent", Container: "container", AlertType: types.PerformanceAlertTypeMemoryPressure}, now),
+			Source:  types.EventSourceAgent,
+			AgentId: "agent",
+			Type:    "memory-pressure",
+			Target:  "container",
+		},
+		{
+			Name:   "pod status",
+			Event:  PodStatus(types.PodStatusReport{Deployment: "fl-server", Pod: "fl-server-7d9f", Event: "modified", Phase: "Running"}, now),
+			Source: types.EventSourceCloud,
+			Type:   "pod-status",
+			Target: "fl-server-7d9f",
+		},
+		{
+			Name:   "deployment scaled",
+			Event:  DeploymentScaling(types.ScalingReport{Deployment: "aggregator", From: 1, To: 3, Value: 2.4, Target: 1}, now),
+			Source: types.EventSourceCloud,
+			Type:   "deployment-scaled",
+			Target: "aggregator",
+		},
+	}
+	for _, test := range tests {
+		event := test.Event
+		if event.Source != test.Source || event.AgentId != test.AgentId || event.Type != test.Type || event.Target != test.Target {
+			t.Errorf("%s: Event incorrect. Got %s %q %s %q, Want %s %q %s %q", test.Name, event.Source, event.AgentId, event.Type, event.Target, test.Source, test.AgentId, test.Type, test.Target)
+		}
+		if !event.Time.Equal(now) {
+			t.Errorf("%s: Time incorrect. Got %v, Want %v", test.Name, event.Time, now)
+		}
+		if len(event.Payload) == 0 {
+			t.Errorf("%s: Payload missing", test.Name)
+		}
+	}
+}
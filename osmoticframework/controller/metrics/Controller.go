@@ -0,0 +1,27 @@
+package metrics
+
+//Metrics updated by the controller
+
+//Upper bounds of the request latency buckets in seconds. Deploy requests pull images, which can take minutes
+var requestBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+//Time from sending a request to an agent until the result arrives
+var RequestDuration = NewHistogram("osmotic_request_duration_seconds", "Time until agents return the result of a request.", requestBuckets, "api", "command", "result")
+
+var RequestTimeouts = NewCounter("osmotic_request_timeouts_total", "Requests to agents that timed out before they were acknowledged.", "api")
+
+var RegistrationRejections = NewCounter("osmotic_registration_rejections_total", "Rejected agent registrations.", "reason")
+
+//type is the performance alert type for performance issues
+var Alerts = NewCounter("osmotic_alerts_total", "Alerts raised by the controller.", "type")
+
+//result is sent or failed
+var Notifications = NewCounter("osmotic_notifications_total", "Alerts sent to notification sinks.", "sink", "result")
+
+//subscriber is the name a subscriber of the alert bus subscribed with
+var AlertsDropped = NewCounter("osmotic_alerts_dropped_total", "Alerts dropped because a subscriber fell behind.", "type", "subscriber")
+
+var DatabaseErrors = NewCounter("osmotic_database_errors_total", "Failed database queries.")
+
+//direction is up or down
+var DeploymentScalings = NewCounter("osmotic_deployment_scalings_total", "Replica changes of cloud deployments made by the autoscaler.", "deployment", "direction")
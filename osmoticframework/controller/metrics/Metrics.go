@@ -0,0 +1,244 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+	Controller metrics in the Prometheus text format
+	Counters and histograms are updated where the events happen. They are defined in Controller.go.
+	Gauges that describe the current state, e.g. the number of agents, are read when Prometheus scrapes the controller. See GaugeFunc.
+	Metrics are written in the order they are registered.
+*/
+
+//A set of label values, in the same order as the label names of the metric
+type Labels []string
+
+//A value of a metric with its labels
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+var registryMutex sync.Mutex
+var registry []metric
+
+func register(m metric) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry = append(registry, m)
+}
+
+//Writes all registered metrics
+func WriteAll(w io.Writer) {
+	registryMutex.Lock()
+	metrics := make([]metric, len(registry))
+	copy(metrics, registry)
+	registryMutex.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+//Name, help text and label names of a metric
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+func (d desc) header(w io.Writer, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, d.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, metricType)
+}
+
+//Formats labels as {name="value",...}. extra is appended as is, e.g. le="0.5" for histogram buckets
+func (d desc) labels(values Labels, extra string) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, name := range d.labelNames {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name+"=\""+escape(value)+"\"")
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+//Label values joined as a map key
+func key(values Labels) string {
+	return strings.Join(values, "\xff")
+}
+
+//Samples sorted by their labels, so that the output is stable
+func sorted(values map[string]*Sample) []*Sample {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	samples := make([]*Sample, 0, len(keys))
+	for _, k := range keys {
+		samples = append(samples, values[k])
+	}
+	return samples
+}
+
+//A value that only goes up, e.g. the number of timed out requests
+type Counter struct {
+	desc
+	mutex  sync.Mutex
+	values map[string]*Sample
+}
+
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labelNames: labelNames}, values: make(map[string]*Sample)}
+	register(c)
+	return c
+}
+
+//Adds one to the counter with the label values
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *Counter) Add(value float64, labels ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	sample, ok := c.values[key(labels)]
+	if !ok {
+		sample = &Sample{Labels: labels}
+		c.values[key(labels)] = sample
+	}
+	sample.Value += value
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.header(w, "counter")
+	//Counters without labels are always written, so that rate() works from the first event
+	if len(c.labelNames) == 0 && len(c.values) == 0 {
+		fmt.Fprintf(w, "%s 0\n", c.name)
+	}
+	for _, sample := range sorted(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labels(sample.Labels, ""), formatValue(sample.Value))
+	}
+}
+
+//A gauge that is read when the metrics are scraped
+type GaugeFunc struct {
+	desc
+	collect func() []Sample
+}
+
+func NewGaugeFunc(name, help string, labelNames []string, collect func() []Sample) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help, labelNames: labelNames}, collect: collect}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.header(w, "gauge")
+	values := make(map[string]*Sample)
+	for _, sample := range g.collect() {
+		sample := sample
+		values[key(sample.Labels)] = &sample
+	}
+	for _, sample := range sorted(values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labels(sample.Labels, ""), formatValue(sample.Value))
+	}
+}
+
+//Distribution of observed values, e.g. request latency
+type Histogram struct {
+	desc
+	buckets []float64
+	mutex   sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels Labels
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+//buckets are the upper bounds of the buckets in increasing order. The +Inf bucket is added automatically
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{desc: desc{name: name, help: help, labelNames: labelNames}, buckets: buckets, values: make(map[string]*histogramValue)}
+	register(h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labels ...string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	v, ok := h.values[key(labels)]
+	if !ok {
+		v = &histogramValue{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.values[key(labels)] = v
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.header(w, "histogram")
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := h.values[k]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(v.labels, "le=\""+formatValue(bound)+"\""), v.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(v.labels, "le=\"+Inf\""), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labels(v.labels, ""), formatValue(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labels(v.labels, ""), v.count)
+	}
+}
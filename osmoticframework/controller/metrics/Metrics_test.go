@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	counter := &Counter{desc: desc{name: "test_total", help: "Test counter.", labelNames: []string{"type"}}, values: make(map[string]*Sample)}
+	histogram := &Histogram{desc: desc{name: "test_seconds", help: "Test histogram.", labelNames: []string{"api"}}, buckets: []float64{0.5, 1}, values: make(map[string]*histogramValue)}
+	gauge := &GaugeFunc{desc: desc{name: "test_agents", help: "Test gauge."}, collect: func() []Sample {
+		return []Sample{{Value: 3}}
+	}}
+	tests := []struct {
+		Name     string
+		Metric   metric
+		Update   func()
+		Expected string
+	}{
+		{
+			Name:   "counter",
+			Metric: counter,
+			Update: func() {
+				counter.Inc("b")
+				counter.Add(2, "a\"")
+			},
+			Expected: "# HELP test_total Test counter.\n# TYPE test_total counter\ntest_total{type=\"a\\\"\"} 2\ntest_total{type=\"b\"} 1\n",
+		},
+		{
+			Name:   "histogram",
+			Metric: histogram,
+			Update: func() {
+				histogram.Observe(0.25, "deploy")
+				histogram.Observe(2, "deploy")
+			},
+			Expected: "# HELP test_seconds Test histogram.\n# TYPE test_seconds histogram\n" +
+				"test_seconds_bucket{api=\"deploy\",le=\"0.5\"} 1\n" +
+				"test_seconds_bucket{api=\"deploy\",le=\"1\"} 1\n" +
+				"test_seconds_bucket{api=\"deploy\",le=\"+Inf\"} 2\n" +
+				"test_seconds_sum{api=\"deploy\"} 2.25\n" +
+				"test_seconds_count{api=\"deploy\"} 2\n",
+		},
+		{
+			Name:     "gauge",
+			Metric:   gauge,
+			Update:   func() {},
+			Expected: "# HELP test_agents Test gauge.\n# TYPE test_agents gauge\ntest_agents 3\n",
+		},
+	}
+	for _, test := range tests {
+		test.Update()
+		var buffer bytes.Buffer
+		test.Metric.write(&buffer)
+		if buffer.String() != test.Expected {
+			t.Errorf("%s: Output incorrect. Got\n%s\nWant\n%s", test.Name, buffer.String(), test.Expected)
+		}
+	}
+}
+
+func TestWriteAll(t *testing.T) {
+	var buffer bytes.Buffer
+	WriteAll(&buffer)
+	//Counters without labels are written before any event
+	if !strings.Contains(buffer.String(), "osmotic_database_errors_total 0\n") {
+		t.Errorf("Database error counter missing. Got\n%s", buffer.String())
+	}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"net/http"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/vars"
+	"strconv"
+)
+
+//Serves the metrics at /metrics in a separate goroutine
+//The server has its own mux, so the profiler is not exposed on this port
+func Init() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", handler)
+	address := ":" + strconv.Itoa(vars.GetMetricsPort())
+	go func() {
+		log.Info.Println("Serving controller metrics at " + address + "/metrics")
+		err := http.ListenAndServe(address, mux)
+		if err != nil {
+			log.Error.Println("Metrics server stopped")
+			log.Error.Println(err)
+		}
+	}()
+}
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	WriteAll(w)
+}
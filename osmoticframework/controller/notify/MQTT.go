@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lithammer/shortuuid"
+	"io"
+	"net"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"time"
+)
+
+/*
+	Publishes alerts as JSON to a topic on an MQTT broker
+	By default, this is the Mosquitto broker the controller deploys on Kubernetes. See mqttService in controller/auto
+	Alerts are rare, so each alert is published on its own connection with QoS 0. This needs no client library, only the CONNECT, PUBLISH and DISCONNECT packets of MQTT 3.1.1
+*/
+
+//Node port of the Mosquitto service
+const defaultMQTTPort = "30001"
+
+const defaultMQTTTopic = "osmotic/alerts"
+
+type mqttSink struct {
+	address  string
+	topic    string
+	username string
+	password string
+}
+
+func newMQTT(config types.NotifierConfig) (Sink, error) {
+	sink := &mqttSink{
+		address:  config.Address,
+		topic:    config.Topic,
+		username: config.Username,
+		password: config.Password,
+	}
+	if sink.address == "" {
+		sink.address = net.JoinHostPort(vars.GetListeningIP(), defaultMQTTPort)
+	}
+	if sink.topic == "" {
+		sink.topic = defaultMQTTTopic
+	}
+	if sink.password != "" && sink.username == "" {
+		return nil, errors.New("mqtt notifier has a password without a username")
+	}
+	return sink, nil
+}
+
+func (m *mqttSink) Name() string {
+	return "mqtt " + m.address + " topic " + m.topic
+}
+
+func (m *mqttSink) Send(notification Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", m.address, sendTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(sendTimeout))
+	_, err = conn.Write(m.connectPacket())
+	if err != nil {
+		return err
+	}
+	//CONNACK has a fixed length. The last byte is the return code
+	connack := make([]byte, 4)
+	_, err = io.ReadFull(conn, connack)
+	if err != nil {
+		return err
+	}
+	if connack[0] != 0x20 {
+		return errors.New("mqtt broker did not acknowledge the connection")
+	}
+	if connack[3] != 0 {
+		return fmt.Errorf("mqtt broker refused the connection with code %d", connack[3])
+	}
+	_, err = conn.Write(packet(0x30, append(encodeString(m.topic), payload...)))
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write([]byte{0xe0, 0x00})
+	return err
+}
+
+func (m *mqttSink) connectPacket() []byte {
+	var body bytes.Buffer
+	body.Write(encodeString("MQTT"))
+	//Protocol level 4 is MQTT 3.1.1
+	body.WriteByte(4)
+	//Clean session
+	flags := byte(0x02)
+	if m.username != "" {
+		flags |= 0x80
+	}
+	if m.password != "" {
+		flags |= 0x40
+	}
+	body.WriteByte(flags)
+	//Keep alive in seconds
+	body.Write([]byte{0x00, 0x3c})
+	//Each connection has its own client ID. Brokers disconnect clients that reuse an ID
+	body.Write(encodeString("osmotic-controller-" + shortuuid.New()))
+	if m.username != "" {
+		body.Write(encodeString(m.username))
+	}
+	if m.password != "" {
+		body.Write(encodeString(m.password))
+	}
+	return packet(0x10, body.Bytes())
+}
+
+//Prepends the fixed header of a packet. The remaining length is encoded 7 bits per byte
+func packet(header byte, body []byte) []byte {
+	encoded := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		encoded = append(encoded, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(encoded, body...)
+}
+
+//Strings are prefixed with their length in 2 bytes
+func encodeString(value string) []byte {
+	encoded := make([]byte, 2, 2+len(value))
+	binary.BigEndian.PutUint16(encoded, uint16(len(value)))
+	return append(encoded, value...)
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"osmoticframework/controller/types"
+	"testing"
+)
+
+func TestPacket(t *testing.T) {
+	tests := []struct {
+		Length   int
+		Expected []byte
+	}{
+		{0, []byte{0x30, 0x00}},
+		{127, []byte{0x30, 0x7f}},
+		{128, []byte{0x30, 0x80, 0x01}},
+		{16383, []byte{0x30, 0xff, 0x7f}},
+		{16384, []byte{0x30, 0x80, 0x80, 0x01}},
+	}
+	for _, test := range tests {
+		encoded := packet(0x30, make([]byte, test.Length))
+		header := encoded[:len(encoded)-test.Length]
+		if !bytes.Equal(header, test.Expected) {
+			t.Errorf("Header of %d bytes incorrect. Got %x, Want %x", test.Length, header, test.Expected)
+		}
+	}
+}
+
+//Reads the packets of one alert from a fake broker
+func TestMQTTSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("Cannot listen on localhost")
+	}
+	defer listener.Close()
+	packets := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		//CONNECT
+		header := make([]byte, 2)
+		_, _ = io.ReadFull(conn, header)
+		_, _ = io.ReadFull(conn, make([]byte, header[1]))
+		_, _ = conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+		//PUBLISH and DISCONNECT
+		rest, _ := io.ReadAll(conn)
+		packets <- append(header[:1], rest...)
+	}()
+	sink, err := newMQTT(types.NotifierConfig{Type: types.NotifierMQTT, Address: listener.Addr().String(), Topic: "alerts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Send(ContainerCrash(types.ContainerCrashReport{AgentId: "a", ID: "c"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := <-packets
+	if received[0] != 0x10 {
+		t.Errorf("First packet incorrect. Got %x, Want CONNECT", received[0])
+	}
+	publish := received[1:]
+	if publish[0] != 0x30 || !bytes.Contains(publish, []byte("alerts{")) || !bytes.Contains(publish, []byte(`"container-crash"`)) {
+		t.Errorf("PUBLISH packet incorrect. Got %q", publish)
+	}
+	if !bytes.HasSuffix(publish, []byte{0xe0, 0x00}) {
+		t.Errorf("Connection not closed with DISCONNECT")
+	}
+}
@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"fmt"
+	"osmoticframework/controller/alert"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"time"
+)
+
+/*
+Alert notifications
+Alerts are sent to the sinks configured in "notifiers" in the properties file. See types.NotifierConfig
+Each sink has its own filter on the alert type, agent and severity.
+The notifier subscribes to every alert bus. A slow sink never blocks the controller. Alerts are dropped by the bus if the sinks fall behind.
+*/
+
+//Timeout of each delivery to a sink
+const sendTimeout = 10 * time.Second
+
+//An alert as sent to the sinks
+type Notification struct {
+	//The alert types are the same as the type label of the alert metrics, e.g. container-crash or cpu-usage-high
+	Type      string              `json:"type"`
+	Severity  types.AlertSeverity `json:"severity"`
+	AgentId   string              `json:"agentId,omitempty"`
+	Container string              `json:"container,omitempty"`
+	Time      time.Time           `json:"time"`
+	Message   string              `json:"message"`
+	//The original report of the alert
+	Details interface{} `json:"details,omitempty"`
+}
+
+type Sink interface {
+	Name() string
+	Send(notification Notification) error
+}
+
+//A sink and the alerts it receives
+type filteredSink struct {
+	sink   Sink
+	config types.NotifierConfig
+}
+
+var sinks []filteredSink
+
+//Creates the sinks from the properties file and starts sending notifications
+//Invalid sinks are skipped
+func Init() {
+	for _, config := range vars.GetNotifiers() {
+		sink, err := newSink(config)
+		if err != nil {
+			log.Error.Printf("Ignoring %s notifier\n", config.Type)
+			log.Error.Println(err)
+			continue
+		}
+		sinks = append(sinks, filteredSink{sink: sink, config: config})
+		log.Info.Println("Sending alerts to " + sink.Name())
+	}
+	if len(sinks) == 0 {
+		return
+	}
+	crashes, _ := alert.ContainerCrash.Subscribe("notify", alert.DefaultBuffer)
+	disconnects, _ := alert.AgentDisconnect.Subscribe("notify", alert.DefaultBuffer)
+	performanceIssues, _ := alert.PerformanceIssues.Subscribe("notify", alert.DefaultBuffer)
+	databaseErrors, _ := alert.DatabaseErrors.Subscribe("notify", alert.DefaultBuffer)
+	go func() {
+		for _crash := range crashes {
+			crash := _crash.(types.ContainerCrashReport)
+			deliver(ContainerCrash(crash))
+		}
+	}()
+	go func() {
+		for _agent := range disconnects {
+			agent := _agent.(types.OfflineAgent)
+			deliver(AgentDisconnect(agent))
+		}
+	}()
+	go func() {
+		for _report := range performanceIssues {
+			report := _report.(types.PerformanceReport)
+			deliver(PerformanceIssue(report))
+		}
+	}()
+	go func() {
+		for _report := range databaseErrors {
+			report := _report.(types.DatabaseErrorReport)
+			deliver(DatabaseError(report))
+		}
+	}()
+}
+
+func newSink(config types.NotifierConfig) (Sink, error) {
+	switch config.Type {
+	case types.NotifierWebhook:
+		return newWebhook(config)
+	case types.NotifierSMTP:
+		return newSMTP(config)
+	case types.NotifierMQTT:
+		return newMQTT(config)
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", config.Type)
+	}
+}
+
+func deliver(notification Notification) {
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+	for _, sink := range sinks {
+		if !matches(sink.config, notification) {
+			continue
+		}
+		err := sink.sink.Send(notification)
+		if err != nil {
+			metrics.Notifications.Inc(string(sink.config.Type), "failed")
+			log.Error.Println("Failed sending alert to " + sink.sink.Name())
+			log.Error.Println(err)
+			continue
+		}
+		metrics.Notifications.Inc(string(sink.config.Type), "sent")
+	}
+}
+
+//Checks the filters of a sink. Empty filters match everything
+func matches(config types.NotifierConfig, notification Notification) bool {
+	if len(config.AlertTypes) > 0 && !contains(config.AlertTypes, notification.Type) {
+		return false
+	}
+	if len(config.Agents) > 0 && !contains(config.Agents, notification.AgentId) {
+		return false
+	}
+	return severityLevel(notification.Severity) >= severityLevel(config.MinSeverity)
+}
+
+func severityLevel(severity types.AlertSeverity) int {
+	switch severity {
+	case types.SeverityWarning:
+		return 1
+	case types.SeverityCritical:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+//Conversion of the alerts of the controller
+
+func ContainerCrash(crash types.ContainerCrashReport) Notification {
+	message := fmt.Sprintf("Container %s (%s) on agent %s exited with code %d", crash.Name, crash.Image, crash.AgentId, crash.ExitCode)
+	//The full output is in the details
+	if len(crash.Output) > 0 {
+		message += ". Last output: " + crash.Output[len(crash.Output)-1]
+	}
+	return Notification{
+		Type:      "container-crash",
+		Severity:  types.SeverityCritical,
+		AgentId:   crash.AgentId,
+		Container: crash.ID,
+		Message:   message,
+		Details:   crash,
+	}
+}
+
+func AgentDisconnect(agent types.OfflineAgent) Notification {
+	return Notification{
+		Type:     "agent-disconnect",
+		Severity: types.SeverityCritical,
+		AgentId:  agent.ID,
+		Message:  fmt.Sprintf("Agent %s disconnected with %d containers", agent.ID, len(agent.Agent.Containers)),
+		Details:  agent.Agent,
+	}
+}
+
+func PerformanceIssue(report types.PerformanceReport) Notification {
+	subject := "Agent " + report.AgentId
+	if report.Container != "" {
+		subject = fmt.Sprintf("Container %s on agent %s", report.Container, report.AgentId)
+	}
+	return Notification{
+		Type:      string(report.AlertType),
+		Severity:  types.SeverityWarning,
+		AgentId:   report.AgentId,
+		Container: report.Container,
+		Message:   fmt.Sprintf("%s raised %s with value %g", subject, report.AlertType, report.Value),
+		Details:   report,
+	}
+}
+
+func DatabaseError(report types.DatabaseErrorReport) Notification {
+	return Notification{
+		Type:     "database-error",
+		Severity: types.SeverityWarning,
+		Message:  fmt.Sprintf("Database query failed: %s", report.Error),
+		//The error does not encode to JSON
+		Details: map[string]interface{}{
+			"query": report.Query,
+			"error": fmt.Sprint(report.Error),
+		},
+	}
+}
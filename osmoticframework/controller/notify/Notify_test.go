@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"osmoticframework/controller/types"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	crash := ContainerCrash(types.ContainerCrashReport{AgentId: "a", ID: "c", ExitCode: 1})
+	latency := PerformanceIssue(types.PerformanceReport{AgentId: "b", AlertType: types.PerformanceAlertTypeNetworkLatency, Value: 4000})
+	tests := []struct {
+		Config       types.NotifierConfig
+		Notification Notification
+		Expected     bool
+	}{
+		{types.NotifierConfig{}, crash, true},
+		{types.NotifierConfig{AlertTypes: []string{"container-crash"}}, crash, true},
+		{types.NotifierConfig{AlertTypes: []string{"container-crash"}}, latency, false},
+		{types.NotifierConfig{Agents: []string{"b"}}, crash, false},
+		{types.NotifierConfig{Agents: []string{"b"}}, latency, true},
+		{types.NotifierConfig{MinSeverity: types.SeverityCritical}, crash, true},
+		{types.NotifierConfig{MinSeverity: types.SeverityCritical}, latency, false},
+		{types.NotifierConfig{MinSeverity: types.SeverityWarning}, latency, true},
+	}
+	for _, test := range tests {
+		result := matches(test.Config, test.Notification)
+		if result != test.Expected {
+			t.Errorf("Filter %+v on %s alert incorrect. Got %t, Want %t", test.Config, test.Notification.Type, result, test.Expected)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var notification Notification
+		_ = json.NewDecoder(r.Body).Decode(&notification)
+		received <- notification
+	}))
+	defer server.Close()
+	sink, err := newWebhook(types.NotifierConfig{Type: types.NotifierWebhook, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Send(AgentDisconnect(types.OfflineAgent{ID: "a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification := <-received
+	if notification.Type != "agent-disconnect" || notification.AgentId != "a" {
+		t.Errorf("Webhook notification incorrect. Got %+v", notification)
+	}
+	//Rejected requests are errors
+	sink, _ = newWebhook(types.NotifierConfig{Type: types.NotifierWebhook, URL: server.URL})
+	if err := sink.Send(AgentDisconnect(types.OfflineAgent{ID: "a"})); err == nil {
+		t.Errorf("Webhook rejection not reported")
+	}
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"osmoticframework/controller/types"
+	"strings"
+	"time"
+)
+
+//Emails alerts. Authentication is only used if a username is given
+type smtpSink struct {
+	address string
+	host    string
+	auth    smtp.Auth
+	from    string
+	to      []string
+}
+
+func newSMTP(config types.NotifierConfig) (Sink, error) {
+	if config.Address == "" || config.From == "" || len(config.To) == 0 {
+		return nil, errors.New("smtp notifier needs an address, a sender and recipients")
+	}
+	host, _, err := net.SplitHostPort(config.Address)
+	if err != nil {
+		return nil, err
+	}
+	sink := &smtpSink{
+		address: config.Address,
+		host:    host,
+		from:    config.From,
+		to:      config.To,
+	}
+	if config.Username != "" {
+		sink.auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+	return sink, nil
+}
+
+func (s *smtpSink) Name() string {
+	return "smtp " + s.address
+}
+
+//Same steps as smtp.SendMail. But with a deadline, so that a stuck mail server does not hold up other alerts
+func (s *smtpSink) Send(notification Notification) error {
+	conn, err := net.DialTimeout("tcp", s.address, sendTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(sendTimeout))
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: s.host})
+		if err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support authentication")
+		}
+		err = client.Auth(s.auth)
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(s.from)
+	if err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(s.message(notification))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (s *smtpSink) message(notification Notification) []byte {
+	var message strings.Builder
+	message.WriteString("From: " + s.from + "\r\n")
+	message.WriteString("To: " + strings.Join(s.to, ", ") + "\r\n")
+	message.WriteString(fmt.Sprintf("Subject: [%s] Osmotic alert %s\r\n", notification.Severity, notification.Type))
+	message.WriteString("Date: " + notification.Time.Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(notification.Message + "\r\n")
+	if notification.AgentId != "" {
+		message.WriteString("\r\nAgent: " + notification.AgentId + "\r\n")
+	}
+	if notification.Container != "" {
+		message.WriteString("Container: " + notification.Container + "\r\n")
+	}
+	return []byte(message.String())
+}
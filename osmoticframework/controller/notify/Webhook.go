@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"osmoticframework/controller/types"
+)
+
+//Posts alerts as JSON to a URL
+type webhook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newWebhook(config types.NotifierConfig) (Sink, error) {
+	if config.URL == "" {
+		return nil, errors.New("webhook has no url")
+	}
+	return &webhook{
+		url:     config.URL,
+		headers: config.Headers,
+		client:  &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+func (w *webhook) Name() string {
+	return "webhook " + w.url
+}
+
+func (w *webhook) Send(notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
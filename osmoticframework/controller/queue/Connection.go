@@ -0,0 +1,238 @@
+package queue
+
+import (
+	"errors"
+	"github.com/streadway/amqp"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/vars"
+	"sync"
+	"time"
+)
+
+/*
+	Connection manager
+	The controller reconnects to RabbitMQ when the connection or the channel is closed, e.g. when the broker restarts.
+	After reconnecting, the handlers registered with OnReconnect declare their queues and consumers again.
+	Requests that agents have not acknowledged yet are then published again. See PublishRequest.
+*/
+
+//Delay before reconnecting. It doubles after every failed attempt, up to maxReconnectDelay
+const minReconnectDelay = time.Second
+const maxReconnectDelay = time.Minute
+
+//Returned when publishing while the controller is reconnecting
+var ErrNotConnected = errors.New("not connected to RabbitMQ")
+
+//Guards Server, Ch, connected and connectedAt. They are replaced when reconnecting
+var connMutex sync.RWMutex
+var connected bool
+var connectedAt time.Time
+
+var handlerMutex sync.Mutex
+var reconnectHandlers []func()
+
+//Requests not yet acknowledged by agents. Request ID -> pendingMessage
+var pending sync.Map
+
+type pendingMessage struct {
+	agentId    string
+	routingKey string
+	body       []byte
+	//Signature headers. Kept, so that a copy sent again is recognized as the same request
+	headers amqp.Table
+	queued  time.Time
+}
+
+//Dials the server and opens a channel. The connection is watched, and reconnects if it is closed
+func connect() error {
+	server, err := dialServer()
+	if err != nil {
+		return err
+	}
+	ch, err := server.Channel()
+	if err != nil {
+		_ = server.Close()
+		return err
+	}
+	connMutex.Lock()
+	Server = server
+	Ch = ch
+	connected = true
+	connectedAt = time.Now()
+	connMutex.Unlock()
+	log.Info.Println("Connected to RabbitMQ server")
+	go watch(server.NotifyClose(make(chan *amqp.Error, 1)), ch.NotifyClose(make(chan *amqp.Error, 1)))
+	return nil
+}
+
+//Waits until the connection or channel is closed, then reconnects
+func watch(serverClosed, channelClosed chan *amqp.Error) {
+	var closeErr *amqp.Error
+	select {
+	case closeErr = <-serverClosed:
+	case closeErr = <-channelClosed:
+	}
+	if vars.IsTerminate() {
+		return
+	}
+	connMutex.Lock()
+	connected = false
+	//The connection may still be open if only the channel was closed
+	_ = Server.Close()
+	connMutex.Unlock()
+	log.Error.Println("Lost connection to RabbitMQ")
+	if closeErr != nil {
+		log.Error.Println(closeErr)
+	}
+	delay := minReconnectDelay
+	for {
+		log.Info.Printf("Reconnecting to RabbitMQ in %s\n", delay)
+		time.Sleep(delay)
+		if vars.IsTerminate() {
+			return
+		}
+		err := connect()
+		if err == nil {
+			break
+		}
+		log.Error.Println("Failed to dial RabbitMQ")
+		log.Error.Println(err)
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+	connMutex.RLock()
+	since := connectedAt
+	connMutex.RUnlock()
+	handlerMutex.Lock()
+	handlers := reconnectHandlers
+	handlerMutex.Unlock()
+	for _, handler := range handlers {
+		handler()
+	}
+	republish(since)
+}
+
+//Registers a function to run after reconnecting. Used to declare queues and consumers again
+func OnReconnect(handler func()) {
+	handlerMutex.Lock()
+	defer handlerMutex.Unlock()
+	reconnectHandlers = append(reconnectHandlers, handler)
+}
+
+//Checks if the controller is connected to RabbitMQ
+func IsConnected() bool {
+	connMutex.RLock()
+	defer connMutex.RUnlock()
+	return connected
+}
+
+//Closes the connection without reconnecting. Set the terminate flag before calling this
+func Close() {
+	connMutex.Lock()
+	defer connMutex.Unlock()
+	connected = false
+	if Ch != nil {
+		_ = Ch.Close()
+	}
+	if Server != nil {
+		_ = Server.Close()
+	}
+}
+
+//The current channel. It changes after reconnecting
+func channel() *amqp.Channel {
+	connMutex.RLock()
+	defer connMutex.RUnlock()
+	return Ch
+}
+
+//Publishes a JSON message to a queue
+func Publish(routingKey string, body []byte) error {
+	return publish(routingKey, body, nil)
+}
+
+func publish(routingKey string, body []byte, headers amqp.Table) error {
+	connMutex.RLock()
+	defer connMutex.RUnlock()
+	if !connected {
+		return ErrNotConnected
+	}
+	return Ch.Publish(
+		"",
+		routingKey,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType: "application/json",
+			Headers:     headers,
+			Body:        body,
+		},
+	)
+}
+
+//Signs a request with the agent's secret
+func signRequest(agentId, routingKey string, body []byte) (amqp.Table, error) {
+	secret, ok := vars.AgentKeys.Load(agentId)
+	if !ok {
+		return nil, ErrUnknownAgent
+	}
+	return sign(secret.(string), routingKey, agentId, body, time.Now())
+}
+
+//Publishes a signed request to an agent
+//If the connection is lost before the agent acknowledges the request, it is published again after reconnecting
+//Call Done when the agent acknowledges the request, or when it is given up
+func PublishRequest(requestId, agentId, routingKey string, body []byte) error {
+	headers, err := signRequest(agentId, routingKey, body)
+	if err != nil {
+		return err
+	}
+	pending.Store(requestId, pendingMessage{agentId: agentId, routingKey: routingKey, body: body, headers: headers, queued: time.Now()})
+	err = publish(routingKey, body, headers)
+	if err == ErrNotConnected || err == amqp.ErrClosed {
+		log.Warn.Printf("Not connected to RabbitMQ. Request %s will be sent after reconnecting\n", requestId)
+		return nil
+	}
+	if err != nil {
+		pending.Delete(requestId)
+	}
+	return err
+}
+
+//Stops publishing a request again after reconnecting
+func Done(requestId string) {
+	pending.Delete(requestId)
+}
+
+//Publishes all requests that have not been acknowledged
+//Requests queued after reconnecting have been sent already
+//Requests are sent again with their original signature. If the first copy reached the agent, the agent rejects the second as a replay, so it does not run twice
+//Requests older than maxMessageAge would be rejected as expired, so they are given up
+func republish(since time.Time) {
+	count := 0
+	now := time.Now()
+	pending.Range(func(requestId, _message interface{}) bool {
+		message := _message.(pendingMessage)
+		if !message.queued.Before(since) {
+			return true
+		}
+		if now.Sub(message.queued) > maxMessageAge {
+			log.Warn.Printf("Request %s expired while disconnected. Not sending it again\n", requestId)
+			pending.Delete(requestId)
+			return true
+		}
+		err := publish(message.routingKey, message.body, message.headers)
+		if err != nil {
+			log.Error.Printf("Failed sending request %s again\n", requestId)
+			log.Error.Println(err)
+			return true
+		}
+		count++
+		return true
+	})
+	if count > 0 {
+		log.Info.Printf("Sent %d unacknowledged requests again\n", count)
+	}
+}
@@ -0,0 +1,36 @@
+package queue
+
+import (
+	"osmoticframework/controller/vars"
+	"testing"
+)
+
+func TestPublishRequestDisconnected(t *testing.T) {
+	//Requests published while disconnected are kept until they are sent again or given up
+	vars.AgentKeys.Store("agent", "secret")
+	defer vars.AgentKeys.Delete("agent")
+	err := PublishRequest("request", "agent", "deploy-agent", []byte("{}"))
+	if err != nil {
+		t.Errorf("Publishing while disconnected failed. Got %v, Want %v", err, nil)
+	}
+	if _, ok := pending.Load("request"); !ok {
+		t.Errorf("Request not kept for reconnecting")
+	}
+	Done("request")
+	if _, ok := pending.Load("request"); ok {
+		t.Errorf("Request kept after Done")
+	}
+	if err := Publish("ping", []byte("{}")); err != ErrNotConnected {
+		t.Errorf("Publish error incorrect. Got %v, Want %v", err, ErrNotConnected)
+	}
+}
+
+func TestPublishRequestUnknownAgent(t *testing.T) {
+	err := PublishRequest("request", "unknown", "deploy-unknown", []byte("{}"))
+	if err != ErrUnknownAgent {
+		t.Errorf("Publish error incorrect. Got %v, Want %v", err, ErrUnknownAgent)
+	}
+	if _, ok := pending.Load("request"); ok {
+		t.Errorf("Request to unknown agent kept for reconnecting")
+	}
+}
@@ -19,41 +19,37 @@ var deployQueue []amqp.Queue
 Connects to the RabbitMQ server. This function must be called first before sending any requests.
 This sets up the channel and queue needed for sending request to agents.
 It does NOT setup the queue to receive response messages. See ApiInit.go.
+If the connection is lost afterwards, the controller reconnects by itself. See Connection.go
 */
 func Init() {
 	log.Info.Println("Connecting to server " + vars.GetRabbitAddress())
-	if strings.HasPrefix(vars.GetRabbitAddress(), "amqp://") {
-		dial()
-	} else if strings.HasPrefix(vars.GetRabbitAddress(), "amqps://") {
-		dialTls()
-	} else {
+	if !strings.HasPrefix(vars.GetRabbitAddress(), "amqp://") && !strings.HasPrefix(vars.GetRabbitAddress(), "amqps://") {
 		log.Fatal.Fatalln("Invalid RabbitMQ address. Address must start with amqp:// or amqps://")
 	}
-}
-
-func dial() {
-	var err error
-	Server, err = amqp.Dial(vars.GetRabbitAddress())
+	err := connect()
 	if err != nil {
 		log.Fatal.Println("Failed to dial RabbitMQ")
 		log.Fatal.Panicln(err)
 	}
-	Ch, err = Server.Channel()
-	if err != nil {
-		log.Fatal.Println("Failed to dial RabbitMQ")
-		log.Fatal.Panicln(err)
+}
+
+//Dials the server with or without TLS, depending on the address
+func dialServer() (*amqp.Connection, error) {
+	if strings.HasPrefix(vars.GetRabbitAddress(), "amqps://") {
+		return dialTls()
 	}
+	return amqp.Dial(vars.GetRabbitAddress())
 }
 
-func dialTls() {
+func dialTls() (*amqp.Connection, error) {
 	tlsConf := new(tls.Config)
 	//RootCA
 	tlsConf.RootCAs = x509.NewCertPool()
 	cacert := filepath.Join(vars.GetCredDirectory(), "ca_certificate.pem")
 	ca, err := ioutil.ReadFile(cacert)
 	if err != nil {
-		log.Fatal.Println("Failed to load CA certificate")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed to load CA certificate")
+		return nil, err
 	}
 	tlsConf.RootCAs.AppendCertsFromPEM(ca)
 	//Load key pair
@@ -61,27 +57,16 @@ func dialTls() {
 	clientKey := filepath.Join(vars.GetCredDirectory(), "client_key.pem")
 	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
 	if err != nil {
-		log.Fatal.Println("Failed to load client certificate")
-		log.Fatal.Panicln(err)
+		log.Error.Println("Failed to load client certificate")
+		return nil, err
 	}
 	tlsConf.Certificates = append(tlsConf.Certificates, cert)
-	Server, err := amqp.DialTLS(vars.GetRabbitAddress(), tlsConf)
-	if err != nil {
-		log.Fatal.Println("Failed to dial RabbitMQ")
-		log.Fatal.Panicln(err)
-	}
-	log.Info.Println("Connected to RabbitMQ server")
-	Ch, err = Server.Channel()
-	if err != nil {
-		log.Fatal.Println("Failed to dial RabbitMQ")
-		log.Fatal.Panicln(err)
-	}
-	log.Info.Println("Channel created")
+	return amqp.DialTLS(vars.GetRabbitAddress(), tlsConf)
 }
 
 //Declares a queue on RabbitMQ, limits to only one consumer
 func DeclareControllerQueue(queueName string, durable bool) (amqp.Queue, error) {
-	queue, err := Ch.QueueDeclare(
+	queue, err := channel().QueueDeclare(
 		queueName,
 		durable,
 		false,
@@ -96,7 +81,7 @@ func DeclareControllerQueue(queueName string, durable bool) (amqp.Queue, error)
 
 //Declares a queue on RabbitMQ
 func DeclareQueue(queueName string, durable bool) (amqp.Queue, error) {
-	queue, err := Ch.QueueDeclare(
+	queue, err := channel().QueueDeclare(
 		queueName,
 		durable,
 		false,
@@ -109,7 +94,7 @@ func DeclareQueue(queueName string, durable bool) (amqp.Queue, error) {
 
 //Declares a queue in RabbitMQ, which expires if no messages are sent within expireTime
 func DeclareExpireQueue(queueName string, expireTime int) (amqp.Queue, error) {
-	queue, err := Ch.QueueDeclare(
+	queue, err := channel().QueueDeclare(
 		queueName,
 		false,
 		false,
@@ -123,9 +108,15 @@ func DeclareExpireQueue(queueName string, expireTime int) (amqp.Queue, error) {
 	return queue, err
 }
 
+//Deletes a queue. Consumers of the queue are cancelled
+func DeleteQueue(queueName string) error {
+	_, err := channel().QueueDelete(queueName, false, false, false)
+	return err
+}
+
 //Declares a consumer using the queue
 func NewConsumer(queueName string) (<-chan amqp.Delivery, error) {
-	consumer, err := Ch.Consume(
+	consumer, err := channel().Consume(
 		queueName,
 		"",
 		false,
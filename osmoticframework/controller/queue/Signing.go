@@ -0,0 +1,126 @@
+package queue
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/streadway/amqp"
+	"osmoticframework/controller/vars"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+	Message signing
+	Requests to agents and messages from agents are signed with HMAC-SHA256, using the secret the agent received when it registered.
+	The signature is sent in the message headers along with the agent ID, a timestamp and a random nonce. It covers the queue name,
+	so a message cannot be replayed to another queue. Messages older than maxMessageAge, or with a nonce that has been seen, are rejected.
+	Registration and ping messages are not signed. Registration is protected by bootstrap tokens instead.
+*/
+
+//Messages older than this are rejected. Nonces are remembered for the same time
+//This also allows for the clock difference between the controller and agents
+const maxMessageAge = 5 * time.Minute
+
+var ErrUnsigned = errors.New("message is not signed")
+var ErrUnknownAgent = errors.New("unknown agent")
+var ErrBadSignature = errors.New("invalid signature")
+var ErrExpired = errors.New("message expired")
+var ErrReplayed = errors.New("message replayed")
+
+//Nonces of verified messages. Nonce -> time.Time of the message
+var seenNonces = nonceCache{seen: make(map[string]time.Time)}
+
+type nonceCache struct {
+	mutex     sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+//Records a nonce. Returns false if the nonce has been seen
+func (c *nonceCache) add(nonce string, timestamp, now time.Time) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	//Expired messages are rejected before their nonce is checked, so their nonces can be forgotten
+	if now.Sub(c.lastPrune) > time.Minute {
+		for seenNonce, seenTime := range c.seen {
+			if now.Sub(seenTime) > maxMessageAge {
+				delete(c.seen, seenNonce)
+			}
+		}
+		c.lastPrune = now
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = timestamp
+	return true
+}
+
+//Creates the signature headers of a message
+func sign(secret, routingKey, agentId string, body []byte, now time.Time) (amqp.Table, error) {
+	buffer := make([]byte, 16)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(buffer)
+	timestamp := now.UnixMilli()
+	return amqp.Table{
+		"agentId":   agentId,
+		"timestamp": timestamp,
+		"nonce":     nonce,
+		"signature": signature(secret, routingKey, agentId, timestamp, nonce, body),
+	}, nil
+}
+
+func signature(secret, routingKey, agentId string, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(routingKey + "\n" + agentId + "\n" + strconv.FormatInt(timestamp, 10) + "\n" + nonce + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//Checks the signature of a message received from an agent. Returns the ID of the agent that sent it
+func Verify(routingKey string, message amqp.Delivery) (string, error) {
+	agentId, _ := message.Headers["agentId"].(string)
+	if agentId == "" {
+		return "", ErrUnsigned
+	}
+	if vars.IsRevoked(agentId) {
+		return agentId, ErrUnknownAgent
+	}
+	secret, ok := vars.AgentKeys.Load(agentId)
+	if !ok {
+		return agentId, ErrUnknownAgent
+	}
+	return agentId, verify(secret.(string), routingKey, message.Headers, message.Body, time.Now())
+}
+
+func verify(secret, routingKey string, headers amqp.Table, body []byte, now time.Time) error {
+	agentId, _ := headers["agentId"].(string)
+	timestamp, ok := headers["timestamp"].(int64)
+	if !ok {
+		return ErrUnsigned
+	}
+	nonce, _ := headers["nonce"].(string)
+	received, _ := headers["signature"].(string)
+	if nonce == "" || received == "" {
+		return ErrUnsigned
+	}
+	expected := signature(secret, routingKey, agentId, timestamp, nonce, body)
+	if !hmac.Equal([]byte(received), []byte(expected)) {
+		return ErrBadSignature
+	}
+	sent := time.UnixMilli(timestamp)
+	if now.Sub(sent) > maxMessageAge || sent.Sub(now) > maxMessageAge {
+		return ErrExpired
+	}
+	if !seenNonces.add(nonce, sent, now) {
+		return ErrReplayed
+	}
+	return nil
+}
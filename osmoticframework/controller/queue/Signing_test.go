@@ -0,0 +1,53 @@
+package queue
+
+import (
+	"github.com/streadway/amqp"
+	"testing"
+	"time"
+)
+
+func TestSignature(t *testing.T) {
+	//The agent computes the same signature. See agent/api/Signing_test.go
+	expected := "cc975cdeaf96acc9af432611e52e9e2e7badee5fcc15c4a3c0194b2ab4c5a69b"
+	if result := signature("secret", "response", "agent", 1000, "nonce", []byte("{}")); result != expected {
+		t.Errorf("Signature incorrect. Got %s, Want %s", result, expected)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"requestId":"a"}`)
+	tests := []struct {
+		Name       string
+		Secret     string
+		RoutingKey string
+		Body       []byte
+		Time       time.Time
+		Expected   error
+	}{
+		{Name: "valid", Secret: "secret", RoutingKey: "response", Body: body, Time: now, Expected: nil},
+		{Name: "wrong secret", Secret: "other", RoutingKey: "response", Body: body, Time: now, Expected: ErrBadSignature},
+		{Name: "wrong queue", Secret: "secret", RoutingKey: "alert", Body: body, Time: now, Expected: ErrBadSignature},
+		{Name: "tampered", Secret: "secret", RoutingKey: "response", Body: []byte(`{"requestId":"b"}`), Time: now, Expected: ErrBadSignature},
+		{Name: "expired", Secret: "secret", RoutingKey: "response", Body: body, Time: now.Add(maxMessageAge + time.Second), Expected: ErrExpired},
+	}
+	for _, test := range tests {
+		headers, err := sign("secret", "response", "agent", body, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := verify(test.Secret, test.RoutingKey, headers, test.Body, test.Time); err != test.Expected {
+			t.Errorf("%s: Verification incorrect. Got %v, Want %v", test.Name, err, test.Expected)
+		}
+	}
+	headers, _ := sign("secret", "response", "agent", body, now)
+	if err := verify("secret", "response", headers, body, now); err != nil {
+		t.Errorf("First delivery rejected. Got %v, Want %v", err, nil)
+	}
+	if err := verify("secret", "response", headers, body, now); err != ErrReplayed {
+		t.Errorf("Replay not rejected. Got %v, Want %v", err, ErrReplayed)
+	}
+	if err := verify("secret", "response", amqp.Table{}, body, now); err != ErrUnsigned {
+		t.Errorf("Unsigned message not rejected. Got %v, Want %v", err, ErrUnsigned)
+	}
+}
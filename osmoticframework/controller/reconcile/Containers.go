@@ -0,0 +1,93 @@
+package reconcile
+
+import (
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"sort"
+)
+
+//Listing and planning shared by the reconciler and replica sets
+
+//A container on an agent
+type ContainerRef struct {
+	AgentId     string
+	ContainerId string
+}
+
+//Lists the containers on all connected agents. owner is used in the log
+//Agents that fail to respond are left out. Nothing is deployed to or removed from them in this round
+func ListContainers(owner string) (map[string]types.Agent, map[string][]types.Container) {
+	agents := make(map[string]types.Agent)
+	vars.Agents.Range(func(agentId, agent interface{}) bool {
+		agents[agentId.(string)] = agent.(types.Agent)
+		return true
+	})
+	containers := make(map[string][]types.Container)
+	for agentId := range agents {
+		result := request.Await(request.ListRequest(agentId, listTimeout))
+		if result.ResultType == request.Error {
+			log.Error.Printf("%s >> Failed listing containers on agent %s. Skipping agent\n", owner, agentId)
+			log.Error.Println(result.Content.(error))
+			continue
+		}
+		containers[agentId] = result.Content.([]types.Container)
+	}
+	return agents, containers
+}
+
+//Returns the agents of a listing in a fixed order, so that rounds always make the same choices
+func SortedAgents(containers map[string][]types.Container) []string {
+	agentIds := make([]string, 0, len(containers))
+	for agentId := range containers {
+		agentIds = append(agentIds, agentId)
+	}
+	sort.Strings(agentIds)
+	return agentIds
+}
+
+//Checks if the status of a container means it is running, or is being restarted by Docker
+func Live(status string) bool {
+	return status == "running" || status == "restarting"
+}
+
+/*
+	Picks the containers of an image out of a listing
+	Returns the containers to keep on each agent, and the containers to remove because replace reports them
+	Other images are left out
+*/
+func Split(image string, containers map[string][]types.Container, replace func(types.Container) bool) (map[string][]string, []ContainerRef) {
+	keep := make(map[string][]string)
+	remove := make([]ContainerRef, 0)
+	for _, agentId := range SortedAgents(containers) {
+		for _, container := range containers[agentId] {
+			if !SameImage(container.Image, image) {
+				continue
+			}
+			if replace(container) {
+				remove = append(remove, ContainerRef{AgentId: agentId, ContainerId: container.ID})
+			} else {
+				keep[agentId] = append(keep[agentId], container.ID)
+			}
+		}
+	}
+	return keep, remove
+}
+
+//Sorts agents by where an image should go first
+//Agents already running the most of it are preferred so that containers are not moved around. Then the agents with the fewest containers.
+func RankAgents(agentIds []string, containers map[string][]types.Container, keep map[string][]string) {
+	sort.Slice(agentIds, func(i, j int) bool {
+		a, b := agentIds[i], agentIds[j]
+		if len(keep[a]) != len(keep[b]) {
+			return len(keep[a]) > len(keep[b])
+		}
+		//Containers of the image itself do not count towards the load of an agent
+		loadA, loadB := len(containers[a])-len(keep[a]), len(containers[b])-len(keep[b])
+		if loadA != loadB {
+			return loadA < loadB
+		}
+		return a < b
+	})
+}
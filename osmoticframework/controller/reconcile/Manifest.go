@@ -0,0 +1,69 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"osmoticframework/controller/types"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+//Reads and validates a manifest file. The file can be either YAML or JSON
+func LoadManifest(path string) (types.Manifest, error) {
+	var manifest types.Manifest
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	err = yaml.Unmarshal(content, &manifest)
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, validate(manifest)
+}
+
+func validate(manifest types.Manifest) error {
+	names := make(map[string]bool)
+	images := make(map[string]string)
+	for _, workload := range manifest.Workloads {
+		if workload.Name == "" {
+			return errors.New("workload name is required")
+		}
+		if names[workload.Name] {
+			return fmt.Errorf("duplicate workload %s", workload.Name)
+		}
+		names[workload.Name] = true
+		if workload.DeployArgs.Image == "" {
+			return fmt.Errorf("workload %s: image is required", workload.Name)
+		}
+		//Containers are matched to workloads by image. Two workloads with the same image would fight over the same containers
+		image := normalizeImage(workload.DeployArgs.Image)
+		if other, ok := images[image]; ok {
+			return fmt.Errorf("workload %s: image %s is already used by workload %s", workload.Name, workload.DeployArgs.Image, other)
+		}
+		images[image] = workload.Name
+		if workload.Replicas < 0 {
+			return fmt.Errorf("workload %s: replicas cannot be negative", workload.Name)
+		}
+	}
+	return nil
+}
+
+//Checks if two image names refer to the same image
+func SameImage(a, b string) bool {
+	return normalizeImage(a) == normalizeImage(b)
+}
+
+//Docker defaults to the latest tag if an image has none
+//e.g. nginx -> nginx:latest, localhost:32000/executor -> localhost:32000/executor:latest
+func normalizeImage(image string) string {
+	if strings.Contains(image, "@") {
+		return image
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if !strings.Contains(name, ":") {
+		return image + ":latest"
+	}
+	return image
+}
@@ -0,0 +1,150 @@
+package reconcile
+
+import (
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/scheduler"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"sync"
+	"time"
+)
+
+/*
+Edge workload reconciliation
+The manifest describes the containers that should be running on the agents. See types.EdgeWorkload
+Every interval, the manifest is reloaded and the containers on each agent are listed. Missing containers are deployed and extra ones are deleted.
+Containers are matched to workloads by image. Containers of images that are not in the manifest are never touched.
+Removing a workload from the manifest leaves its containers running. Set its replicas to 0 to remove them.
+*/
+
+const listTimeout = 30
+const deleteTimeout = 30
+
+var manifest types.Manifest
+var manifestLock sync.RWMutex
+
+//Reconciliation rounds must not overlap, otherwise the same container can be deployed twice
+var reconcileLock sync.Mutex
+
+//Returns the last manifest that was loaded successfully
+func GetManifest() types.Manifest {
+	manifestLock.RLock()
+	defer manifestLock.RUnlock()
+	return manifest
+}
+
+//Runs the reconciliation loop. This blocks until the controller terminates
+func Start() {
+	path := vars.GetManifestPath()
+	interval := time.Duration(vars.GetReconcileInterval()) * time.Second
+	log.Info.Println("Reconciling edge workloads from " + path)
+	for !vars.IsTerminate() {
+		//Give agents time to register before the first round
+		time.Sleep(interval)
+		reload(path)
+		Reconcile()
+	}
+}
+
+//Reloads the manifest file, so that changes apply without restarting the controller
+//If the file is broken, the previous manifest is kept
+func reload(path string) {
+	newManifest, err := LoadManifest(path)
+	if err != nil {
+		log.Error.Println("Failed loading manifest. Keeping the previous one")
+		log.Error.Println(err)
+		return
+	}
+	manifestLock.Lock()
+	manifest = newManifest
+	manifestLock.Unlock()
+}
+
+//Compares the containers on all agents against the manifest and corrects any drift
+func Reconcile() {
+	reconcileLock.Lock()
+	defer reconcileLock.Unlock()
+	agents, containers := ListContainers("Reconcile")
+	for _, workload := range GetManifest().Workloads {
+		run, remove := plan(workload, agents, containers)
+		for _, container := range remove {
+			log.Info.Printf("Reconcile %s >> Removing container %s from agent %s\n", workload.Name, container.ContainerId, container.AgentId)
+			result := request.Await(request.DeleteRequest(container.AgentId, container.ContainerId, false, deleteTimeout))
+			if result.ResultType == request.Error {
+				log.Error.Printf("Reconcile %s >> Failed removing container %s from agent %s\n", workload.Name, container.ContainerId, container.AgentId)
+				log.Error.Println(result.Content.(error))
+			}
+		}
+		for _, agentId := range run {
+			log.Info.Printf("Reconcile %s >> Deploying to agent %s\n", workload.Name, agentId)
+			result := request.Await(request.RunRequestWithOrigin(agentId, workload.DeployArgs, workload.AuthInfo, types.OriginManifest, scheduler.DefaultDeployTimeout))
+			if result.ResultType == request.Error {
+				log.Error.Printf("Reconcile %s >> Failed deploying to agent %s\n", workload.Name, agentId)
+				log.Error.Println(result.Content.(error))
+				continue
+			}
+			//Keep the listing up to date so that later workloads see the new container when choosing agents
+			containers[agentId] = append(containers[agentId], types.Container{
+				ID:     result.Content.(string),
+				Image:  workload.DeployArgs.Image,
+				Status: "running",
+			})
+		}
+	}
+}
+
+//Works out which agents need a new container of the workload, and which containers of the workload should be removed
+func plan(workload types.EdgeWorkload, agents map[string]types.Agent, containers map[string][]types.Container) ([]string, []ContainerRef) {
+	//Agent ID -> Running containers of the workload
+	//Crashed or stopped containers are replaced
+	running, remove := Split(workload.DeployArgs.Image, containers, func(container types.Container) bool {
+		return !Live(container.Status)
+	})
+	targets := selectAgents(workload, agents, containers, running)
+	run := make([]string, 0)
+	for _, agentId := range SortedAgents(containers) {
+		want := 0
+		if targets[agentId] {
+			want = 1
+		}
+		alive := running[agentId]
+		if len(alive) < want {
+			run = append(run, agentId)
+		}
+		for i := want; i < len(alive); i++ {
+			remove = append(remove, ContainerRef{AgentId: agentId, ContainerId: alive[i]})
+		}
+	}
+	return run, remove
+}
+
+//Chooses the agents that should run the workload
+//With replicas, the agents are taken in the order of RankAgents
+func selectAgents(workload types.EdgeWorkload, agents map[string]types.Agent, containers map[string][]types.Container, running map[string][]string) map[string]bool {
+	targets := make(map[string]bool)
+	eligible := make([]string, 0)
+	constraints := types.Constraints{DeviceSupport: workload.DeviceSupport, SensorSupport: workload.SensorSupport}
+	for agentId := range containers {
+		if scheduler.Eligible(agentId, agents[agentId], constraints) {
+			eligible = append(eligible, agentId)
+		}
+	}
+	if len(workload.Agents) > 0 {
+		listed := make(map[string]bool)
+		for _, agentId := range workload.Agents {
+			listed[agentId] = true
+		}
+		for _, agentId := range eligible {
+			if listed[agentId] {
+				targets[agentId] = true
+			}
+		}
+		return targets
+	}
+	RankAgents(eligible, containers, running)
+	for i := 0; i < workload.Replicas && i < len(eligible); i++ {
+		targets[eligible[i]] = true
+	}
+	return targets
+}
@@ -0,0 +1,101 @@
+package reconcile
+
+import (
+	"osmoticframework/controller/types"
+	"reflect"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	agents := map[string]types.Agent{
+		"a": {DeviceSupport: []string{"gpu"}},
+		"b": {DeviceSupport: []string{"gpu"}},
+		"c": {},
+	}
+	executor := func(id, status string) types.Container {
+		return types.Container{ID: id, Image: "executor", Status: status}
+	}
+	other := types.Container{ID: "other", Image: "nginx:latest", Status: "running"}
+	tests := []struct {
+		name       string
+		workload   types.EdgeWorkload
+		containers map[string][]types.Container
+		wantRun    []string
+		wantRemove []ContainerRef
+	}{
+		{
+			name:       "deploy missing replicas to the least busy agents",
+			workload:   types.EdgeWorkload{DeployArgs: types.DeployArgs{Image: "executor:latest"}, Replicas: 2},
+			containers: map[string][]types.Container{"a": {other}, "b": {}, "c": {}},
+			wantRun:    []string{"b", "c"},
+			wantRemove: []ContainerRef{},
+		},
+		{
+			name:       "keep existing replicas and remove extras",
+			workload:   types.EdgeWorkload{DeployArgs: types.DeployArgs{Image: "executor"}, Replicas: 1},
+			containers: map[string][]types.Container{"a": {}, "b": {executor("1", "running"), executor("2", "running")}, "c": {executor("3", "running")}},
+			wantRun:    []string{},
+			wantRemove: []ContainerRef{{"b", "2"}, {"c", "3"}},
+		},
+		{
+			name:       "replace crashed containers",
+			workload:   types.EdgeWorkload{DeployArgs: types.DeployArgs{Image: "executor"}, Replicas: 1},
+			containers: map[string][]types.Container{"a": {executor("1", "exited")}},
+			wantRun:    []string{"a"},
+			wantRemove: []ContainerRef{{"a", "1"}},
+		},
+		{
+			name:       "filter by device support",
+			workload:   types.EdgeWorkload{DeployArgs: types.DeployArgs{Image: "executor"}, Replicas: 3, DeviceSupport: []string{"gpu"}},
+			containers: map[string][]types.Container{"a": {}, "b": {}, "c": {}},
+			wantRun:    []string{"a", "b"},
+			wantRemove: []ContainerRef{},
+		},
+		{
+			name:       "listed agents",
+			workload:   types.EdgeWorkload{DeployArgs: types.DeployArgs{Image: "executor"}, Agents: []string{"c", "missing"}},
+			containers: map[string][]types.Container{"a": {executor("1", "running")}, "b": {}, "c": {}},
+			wantRun:    []string{"c"},
+			wantRemove: []ContainerRef{{"a", "1"}},
+		},
+		{
+			name:       "never touch other images",
+			workload:   types.EdgeWorkload{DeployArgs: types.DeployArgs{Image: "executor"}, Replicas: 0},
+			containers: map[string][]types.Container{"a": {other}},
+			wantRun:    []string{},
+			wantRemove: []ContainerRef{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, remove := plan(tt.workload, agents, tt.containers)
+			if !reflect.DeepEqual(run, tt.wantRun) {
+				t.Errorf("Run incorrect. Got %v, Want %v", run, tt.wantRun)
+			}
+			if !reflect.DeepEqual(remove, tt.wantRemove) {
+				t.Errorf("Remove incorrect. Got %v, Want %v", remove, tt.wantRemove)
+			}
+		})
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	manifest, err := LoadManifest("../../yml/manifest-example.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Workloads) != 2 {
+		t.Fatalf("Workload count incorrect. Got %d, Want 2", len(manifest.Workloads))
+	}
+	executor := manifest.Workloads[0]
+	if executor.Replicas != 3 || executor.DeployArgs.Image != "localhost:32000/iot_executor:latest" || len(executor.DeployArgs.Environment) != 1 {
+		t.Errorf("Executor workload incorrect. Got %+v", executor)
+	}
+	duplicate := types.Manifest{Workloads: []types.EdgeWorkload{
+		{Name: "a", DeployArgs: types.DeployArgs{Image: "nginx"}},
+		{Name: "b", DeployArgs: types.DeployArgs{Image: "nginx:latest"}},
+	}}
+	if validate(duplicate) == nil {
+		t.Errorf("Duplicate images should not be allowed")
+	}
+}
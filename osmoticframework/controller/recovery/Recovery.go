@@ -5,8 +5,11 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"osmoticframework/controller/alert"
 	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/auth"
+	"osmoticframework/controller/database"
 	"osmoticframework/controller/log"
 	"osmoticframework/controller/queue"
+	"osmoticframework/controller/reconcile"
 	"osmoticframework/controller/types"
 	"osmoticframework/controller/vars"
 	"strconv"
@@ -20,50 +23,59 @@ import (
 func Recover() {
 	db, err := sql.Open("mysql", vars.GetDatabaseAddress())
 	if err != nil {
-		alert.DatabaseErrors <- types.DatabaseErrorReport{
+		alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 			Query:     "",
 			QueryArgs: []string{},
 			Error:     err,
-		}
+		})
 		log.Fatal.Println("Error occurred during writing to database")
 		log.Fatal.Panicln(err)
 		return
 	}
 	defer db.Close()
 	//Recover list of agents from database and write back to memory
-	registry, err := db.Query("SELECT * FROM registry")
+	registry, err := db.Query("SELECT AgentId, InternalIP FROM registry")
 	if err != nil {
 		//Failing to recover would mean a lot of agents operating without controller input. Thus panic.
 		log.Fatal.Println("Recovery failed")
 		log.Fatal.Panicln(err)
 	}
 	log.Info.Println("Checking database")
+	//Revoked agents must stay revoked after a restart
+	err = auth.LoadRevoked()
+	if err == nil {
+		//Messages from agents cannot be verified without their secrets
+		err = auth.LoadKeys()
+	}
+	if err != nil {
+		log.Fatal.Println("Recovery failed")
+		log.Fatal.Panicln(err)
+	}
 	recoverCount := 0
 	for registry.Next() {
 		//Get all containers
 		var agentId string
-		var timestamp time.Time
 		var internalIP string
-		_ = registry.Scan(&agentId, &timestamp, &internalIP)
+		_ = registry.Scan(&agentId, &internalIP)
 		query := "SELECT containers.ContainerId FROM containers WHERE AgentId = ?"
 		containerStmt, err := db.Prepare(query)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 			continue
 		}
 		containerQuery, err := containerStmt.Query(agentId)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 			continue
@@ -79,22 +91,22 @@ func Recover() {
 		query = "SELECT devSupport.Device FROM devSupport WHERE AgentId = ?"
 		devStmt, err := db.Prepare(query)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 			continue
 		}
 		devQuery, err := devStmt.Query(agentId)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 			continue
@@ -110,22 +122,22 @@ func Recover() {
 		query = "SELECT sensorSupport.Sensor FROM sensorSupport WHERE AgentId = ?"
 		sensorStmt, err := db.Prepare(query)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 			continue
 		}
 		sensorQuery, err := sensorStmt.Query(agentId)
 		if err != nil {
-			alert.DatabaseErrors <- types.DatabaseErrorReport{
+			alert.DatabaseErrors.Publish(types.DatabaseErrorReport{
 				Query:     query,
 				QueryArgs: []string{agentId},
 				Error:     err,
-			}
+			})
 			log.Error.Println("Error occurred during writing to database")
 			log.Error.Println(err)
 			continue
@@ -137,13 +149,18 @@ func Recover() {
 			sensorSupport = append(sensorSupport, sensor)
 		}
 
-		vars.Agents.Store(agentId, types.Agent{
-			Containers:    containers,
-			LastAlive:     timestamp.UnixNano(),
-			InternalIP:    internalIP,
-			DeviceSupport: devSupport,
-			SensorSupport: sensorSupport,
-		})
+		//Keep anything already known about the agent in memory, such as the ping latency
+		var agent types.Agent
+		if loaded, ok := vars.Agents.Load(agentId); ok {
+			agent = loaded.(types.Agent)
+		}
+		agent.Containers = containers
+		//Agents get the full heartbeat timeout to report back after the controller restarts
+		agent.LastAlive = time.Now().Unix()
+		agent.InternalIP = internalIP
+		agent.DeviceSupport = devSupport
+		agent.SensorSupport = sensorSupport
+		vars.Agents.Store(agentId, agent)
 		recoverCount++
 	}
 
@@ -154,6 +171,24 @@ func Recover() {
 		return
 	}
 
+	//Restore the deploy specs of running containers, so they can be moved if their agent does not come back
+	//Restarting containers count as well. Like stopped ones, crashed containers are not moved
+	//Authentication information is not stored in the database. Images of recovered containers are pulled without it
+	for _, container := range database.ListContainers() {
+		if !reconcile.Live(container.Status) || container.DeployArgs == nil {
+			continue
+		}
+		if _, ok := vars.Agents.Load(container.AgentID); !ok {
+			continue
+		}
+		vars.ContainerSpecs.Store(container.ContainerID, types.ContainerSpec{
+			AgentId:     container.AgentID,
+			DeployArgs:  *container.DeployArgs,
+			RequestTime: container.RequestTime,
+			Origin:      container.Origin,
+		})
+	}
+
 	//Setup RabbitMQ queues for every agent
 	agents := make(map[string][]string, 0)
 	vars.Agents.Range(func(_agentId, _agent interface{}) bool {
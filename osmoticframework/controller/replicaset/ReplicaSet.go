@@ -0,0 +1,406 @@
+package replicaset
+
+import (
+	"errors"
+	"fmt"
+	"osmoticframework/controller/alert"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/database"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/reconcile"
+	"osmoticframework/controller/scheduler"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/vars"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Edge replica sets
+A replica set keeps a number of containers with the same deploy arguments running across the agents. See types.ReplicaSet
+Sets are created with Apply and stored in the database, so that they are kept when the controller restarts.
+The containers of all sets are reconciled when an agent connects or disconnects, when a container of a set crashes or is removed, and every resync interval.
+Missing replicas are deployed, and crashed or extra containers are removed. Containers that exited with code 0 are kept and count as replicas. Containers on agents that no longer satisfy the selector are moved.
+Like the manifest, containers are matched to sets by image. Sets whose image is used by a workload in the manifest are left to the reconciler.
+*/
+
+const deleteTimeout = 30
+
+//Events that arrive together, e.g. the crashes of all containers of an agent, are handled in one round
+const settleTime = 5 * time.Second
+
+//Rounds also run periodically, in case an event was dropped
+const resyncInterval = time.Minute
+
+var ErrNotFound = errors.New("replica set not found")
+
+//Name -> types.ReplicaSet
+var sets sync.Map
+
+//Name -> types.ReplicaSetStatus
+var statuses sync.Map
+
+//Rounds must not overlap, otherwise the same replica can be deployed twice
+var reconcileLock sync.Mutex
+
+//Wakes the reconciliation loop. Holds at most one pending round
+var wake = make(chan struct{}, 1)
+
+//Loads the replica sets from the database and starts the reconciliation loop
+//Sets applied before this are kept
+func Init() {
+	stored, err := database.ListReplicaSets()
+	if err != nil {
+		log.Error.Println("Failed loading replica sets. Only sets applied from now on are kept running")
+	}
+	for _, set := range stored {
+		sets.LoadOrStore(set.Name, set)
+	}
+	connects, _ := alert.AgentConnect.Subscribe("replicaset", alert.DefaultBuffer)
+	disconnects, _ := alert.AgentDisconnect.Subscribe("replicaset", alert.DefaultBuffer)
+	crashes, _ := alert.ContainerCrash.Subscribe("replicaset", alert.DefaultBuffer)
+	events, _ := alert.ContainerEvents.Subscribe("replicaset", alert.DefaultBuffer)
+	go func() {
+		for range connects {
+			trigger()
+		}
+	}()
+	go func() {
+		for range disconnects {
+			trigger()
+		}
+	}()
+	go func() {
+		for _crash := range crashes {
+			crash := _crash.(types.ContainerCrashReport)
+			//Crashes in the cloud have no agent
+			if crash.AgentId != "" && Managed(crash.Image) {
+				trigger()
+			}
+		}
+	}()
+	go func() {
+		for _event := range events {
+			event := _event.(types.ContainerEventReport)
+			if event.Action == "destroy" && Managed(event.Image) {
+				trigger()
+			}
+		}
+	}()
+	go loop()
+}
+
+func loop() {
+	for !vars.IsTerminate() {
+		select {
+		case <-wake:
+		case <-time.After(resyncInterval):
+		}
+		//Also limits how often crash looping containers are deployed again
+		time.Sleep(settleTime)
+		select {
+		case <-wake:
+		default:
+		}
+		Reconcile()
+	}
+}
+
+//Schedules a reconciliation round
+func trigger() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+//Creates or replaces a replica set. Its containers are reconciled in the background
+func Apply(set types.ReplicaSet) error {
+	if set.Spread == "" {
+		set.Spread = types.SpreadOnePerAgent
+	}
+	err := Validate(set)
+	if err != nil {
+		return err
+	}
+	err = database.SaveReplicaSet(set)
+	if err != nil {
+		return err
+	}
+	sets.Store(set.Name, set)
+	log.Info.Printf("Replica set %s >> Applied with %d replicas of %s\n", set.Name, set.Replicas, set.DeployArgs.Image)
+	trigger()
+	return nil
+}
+
+//Checks a replica set before it is applied
+func Validate(set types.ReplicaSet) error {
+	if set.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(set.Name) > 64 || strings.Contains(set.Name, "/") {
+		return errors.New("name must be at most 64 characters without slashes")
+	}
+	if set.DeployArgs.Image == "" {
+		return errors.New("image is required")
+	}
+	if set.Replicas < 0 {
+		return errors.New("replicas cannot be negative")
+	}
+	switch set.Spread {
+	case "", types.SpreadOnePerAgent, types.SpreadBalanced:
+	default:
+		return fmt.Errorf("unknown spread policy %q", set.Spread)
+	}
+	var conflict error
+	sets.Range(func(_, _other interface{}) bool {
+		other := _other.(types.ReplicaSet)
+		if other.Name != set.Name && reconcile.SameImage(other.DeployArgs.Image, set.DeployArgs.Image) {
+			conflict = fmt.Errorf("image is already used by replica set %s", other.Name)
+			return false
+		}
+		return true
+	})
+	if conflict != nil {
+		return conflict
+	}
+	if inManifest(set.DeployArgs.Image) {
+		return errors.New("image is already used by a workload in the manifest")
+	}
+	return nil
+}
+
+//Changes the number of replicas of a set
+func Scale(name string, replicas int) error {
+	_set, ok := sets.Load(name)
+	if !ok {
+		return ErrNotFound
+	}
+	set := _set.(types.ReplicaSet)
+	set.Replicas = replicas
+	return Apply(set)
+}
+
+//Deletes a replica set. Its containers are removed in the background
+func Delete(name string) error {
+	_set, ok := sets.Load(name)
+	if !ok {
+		return ErrNotFound
+	}
+	_, err := database.DeleteReplicaSet(name)
+	if err != nil {
+		return err
+	}
+	sets.Delete(name)
+	statuses.Delete(name)
+	log.Info.Printf("Replica set %s >> Deleted. Removing its containers\n", name)
+	go drain(_set.(types.ReplicaSet))
+	return nil
+}
+
+//Returns a replica set with its containers as of the last round
+func Get(name string) (types.ReplicaSetStatus, error) {
+	_set, ok := sets.Load(name)
+	if !ok {
+		return types.ReplicaSetStatus{}, ErrNotFound
+	}
+	status := types.ReplicaSetStatus{Placement: map[string][]string{}}
+	if _status, ok := statuses.Load(name); ok {
+		status = _status.(types.ReplicaSetStatus)
+	}
+	//The set may have changed since the last round
+	status.ReplicaSet = _set.(types.ReplicaSet)
+	return status, nil
+}
+
+//Returns all replica sets, sorted by name
+func List() []types.ReplicaSetStatus {
+	list := make([]types.ReplicaSetStatus, 0)
+	for _, set := range current() {
+		status, err := Get(set.Name)
+		if err == nil {
+			list = append(list, status)
+		}
+	}
+	return list
+}
+
+//Checks if an image belongs to a replica set
+func Managed(image string) bool {
+	managed := false
+	sets.Range(func(_, set interface{}) bool {
+		managed = reconcile.SameImage(set.(types.ReplicaSet).DeployArgs.Image, image)
+		return !managed
+	})
+	return managed
+}
+
+//Compares the containers on all agents against the replica sets and corrects any drift
+func Reconcile() {
+	reconcileLock.Lock()
+	defer reconcileLock.Unlock()
+	list := current()
+	if len(list) == 0 {
+		return
+	}
+	agents, containers := reconcile.ListContainers("Replica set")
+	for _, set := range list {
+		if inManifest(set.DeployArgs.Image) {
+			log.Warn.Printf("Replica set %s >> Image %s is used by a workload in the manifest. Skipping set\n", set.Name, set.DeployArgs.Image)
+			continue
+		}
+		run, remove, placement := plan(set, agents, containers)
+		for _, container := range remove {
+			log.Info.Printf("Replica set %s >> Removing container %s from agent %s\n", set.Name, container.ContainerId, container.AgentId)
+			result := request.Await(request.DeleteRequest(container.AgentId, container.ContainerId, false, deleteTimeout))
+			if result.ResultType == request.Error {
+				log.Error.Printf("Replica set %s >> Failed removing container %s from agent %s\n", set.Name, container.ContainerId, container.AgentId)
+				log.Error.Println(result.Content.(error))
+			}
+		}
+		for _, agentId := range run {
+			log.Info.Printf("Replica set %s >> Deploying to agent %s\n", set.Name, agentId)
+			result := request.Await(request.RunRequestWithOrigin(agentId, set.DeployArgs, set.AuthInfo, types.OriginReplicaSet, scheduler.DefaultDeployTimeout))
+			if result.ResultType == request.Error {
+				log.Error.Printf("Replica set %s >> Failed deploying to agent %s\n", set.Name, agentId)
+				log.Error.Println(result.Content.(error))
+				continue
+			}
+			placement[agentId] = append(placement[agentId], result.Content.(string))
+			//Keep the listing up to date so that later sets see the new container when choosing agents
+			containers[agentId] = append(containers[agentId], types.Container{
+				ID:     result.Content.(string),
+				Image:  set.DeployArgs.Image,
+				Status: "running",
+			})
+		}
+		running := 0
+		for _, containerIds := range placement {
+			running += len(containerIds)
+		}
+		if running < set.Replicas {
+			log.Warn.Printf("Replica set %s >> %d of %d replicas running\n", set.Name, running, set.Replicas)
+		}
+		//The set may have been deleted during the round
+		if _, ok := sets.Load(set.Name); ok {
+			statuses.Store(set.Name, types.ReplicaSetStatus{
+				Placement:  placement,
+				Running:    running,
+				Reconciled: time.Now(),
+			})
+		}
+	}
+}
+
+//Removes all containers of a deleted replica set
+func drain(set types.ReplicaSet) {
+	reconcileLock.Lock()
+	defer reconcileLock.Unlock()
+	_, containers := reconcile.ListContainers("Replica set")
+	for agentId, list := range containers {
+		for _, container := range list {
+			if !reconcile.SameImage(container.Image, set.DeployArgs.Image) {
+				continue
+			}
+			log.Info.Printf("Replica set %s >> Removing container %s from agent %s\n", set.Name, container.ID, agentId)
+			result := request.Await(request.DeleteRequest(agentId, container.ID, false, deleteTimeout))
+			if result.ResultType == request.Error {
+				log.Error.Printf("Replica set %s >> Failed removing container %s from agent %s\n", set.Name, container.ID, agentId)
+				log.Error.Println(result.Content.(error))
+			}
+		}
+	}
+}
+
+//The applied replica sets, sorted by name
+func current() []types.ReplicaSet {
+	list := make([]types.ReplicaSet, 0)
+	sets.Range(func(_, set interface{}) bool {
+		list = append(list, set.(types.ReplicaSet))
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+//Checks if an image belongs to a workload in the manifest
+func inManifest(image string) bool {
+	for _, workload := range reconcile.GetManifest().Workloads {
+		if reconcile.SameImage(workload.DeployArgs.Image, image) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Works out where the replicas of a set should run
+	Returns the agents that need a new container, once for each container, the containers to remove and the containers that are kept
+*/
+func plan(set types.ReplicaSet, agents map[string]types.Agent, containers map[string][]types.Container) ([]string, []reconcile.ContainerRef, map[string][]string) {
+	//Agent ID -> Running or finished containers of the set
+	running, remove := reconcile.Split(set.DeployArgs.Image, containers, crashed)
+	wanted := spread(set, agents, containers, running)
+	run := make([]string, 0)
+	placement := make(map[string][]string)
+	for _, agentId := range reconcile.SortedAgents(containers) {
+		want, alive := wanted[agentId], running[agentId]
+		for i := len(alive); i < want; i++ {
+			run = append(run, agentId)
+		}
+		for i := want; i < len(alive); i++ {
+			remove = append(remove, reconcile.ContainerRef{AgentId: agentId, ContainerId: alive[i]})
+		}
+		if len(alive) > 0 && want > 0 {
+			if want < len(alive) {
+				alive = alive[:want]
+			}
+			placement[agentId] = alive
+		}
+	}
+	return run, remove, placement
+}
+
+//Checks if a container of a set should be replaced
+//Containers that exited with code 0 finished their work and are kept. Running them again would repeat it
+//Removed containers are not listed at all, so they are replaced as missing replicas
+func crashed(container types.Container) bool {
+	if reconcile.Live(container.Status) {
+		return false
+	}
+	return container.Status != "exited" || container.ExitCode != 0
+}
+
+//Works out how many containers of the set each agent should run
+//Agents are filled in the order of reconcile.RankAgents
+func spread(set types.ReplicaSet, agents map[string]types.Agent, containers map[string][]types.Container, running map[string][]string) map[string]int {
+	wanted := make(map[string]int)
+	eligible := make([]string, 0)
+	for agentId := range containers {
+		if agent, ok := agents[agentId]; ok && scheduler.Eligible(agentId, agent, set.Selector) {
+			eligible = append(eligible, agentId)
+		}
+	}
+	if len(eligible) == 0 {
+		return wanted
+	}
+	reconcile.RankAgents(eligible, containers, running)
+	each, extra := 0, set.Replicas
+	if set.Spread == types.SpreadBalanced {
+		each, extra = set.Replicas/len(eligible), set.Replicas%len(eligible)
+	}
+	for i, agentId := range eligible {
+		want := each
+		if i < extra {
+			want++
+		}
+		if want > 0 {
+			wanted[agentId] = want
+		}
+	}
+	return wanted
+}
@@ -0,0 +1,142 @@
+package replicaset
+
+import (
+	"osmoticframework/controller/reconcile"
+	"osmoticframework/controller/types"
+	"reflect"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	agents := map[string]types.Agent{
+		"a": {DeviceSupport: []string{"gpu"}},
+		"b": {DeviceSupport: []string{"gpu"}},
+		"c": {},
+	}
+	executor := func(id, status string) types.Container {
+		return types.Container{ID: id, Image: "executor", Status: status}
+	}
+	crashed := types.Container{ID: "1", Image: "executor", Status: "exited", ExitCode: 1}
+	other := types.Container{ID: "other", Image: "nginx:latest", Status: "running"}
+	set := func(replicas int, spread types.SpreadPolicy) types.ReplicaSet {
+		return types.ReplicaSet{Name: "executor", DeployArgs: types.DeployArgs{Image: "executor:latest"}, Replicas: replicas, Spread: spread}
+	}
+	tests := []struct {
+		name          string
+		set           types.ReplicaSet
+		containers    map[string][]types.Container
+		wantRun       []string
+		wantRemove    []reconcile.ContainerRef
+		wantPlacement map[string][]string
+	}{
+		{
+			name:          "deploy missing replicas to the least busy agents",
+			set:           set(2, types.SpreadOnePerAgent),
+			containers:    map[string][]types.Container{"a": {other}, "b": {}, "c": {}},
+			wantRun:       []string{"b", "c"},
+			wantRemove:    []reconcile.ContainerRef{},
+			wantPlacement: map[string][]string{},
+		},
+		{
+			name:          "at most one per agent",
+			set:           set(5, types.SpreadOnePerAgent),
+			containers:    map[string][]types.Container{"a": {executor("1", "running"), executor("2", "running")}, "b": {}},
+			wantRun:       []string{"b"},
+			wantRemove:    []reconcile.ContainerRef{{AgentId: "a", ContainerId: "2"}},
+			wantPlacement: map[string][]string{"a": {"1"}},
+		},
+		{
+			name:          "balanced spreads several per agent",
+			set:           set(5, types.SpreadBalanced),
+			containers:    map[string][]types.Container{"a": {executor("1", "running")}, "b": {}},
+			wantRun:       []string{"a", "a", "b", "b"},
+			wantRemove:    []reconcile.ContainerRef{},
+			wantPlacement: map[string][]string{"a": {"1"}},
+		},
+		{
+			name:          "scale down keeps agents with the most replicas",
+			set:           set(1, types.SpreadBalanced),
+			containers:    map[string][]types.Container{"a": {executor("1", "running")}, "b": {executor("2", "running"), executor("3", "running")}},
+			wantRun:       []string{},
+			wantRemove:    []reconcile.ContainerRef{{AgentId: "a", ContainerId: "1"}, {AgentId: "b", ContainerId: "3"}},
+			wantPlacement: map[string][]string{"b": {"2"}},
+		},
+		{
+			name:          "replace crashed containers",
+			set:           set(1, types.SpreadOnePerAgent),
+			containers:    map[string][]types.Container{"a": {crashed}},
+			wantRun:       []string{"a"},
+			wantRemove:    []reconcile.ContainerRef{{AgentId: "a", ContainerId: "1"}},
+			wantPlacement: map[string][]string{},
+		},
+		{
+			name:          "keep finished containers",
+			set:           set(2, types.SpreadOnePerAgent),
+			containers:    map[string][]types.Container{"a": {executor("1", "exited")}, "b": {executor("2", "running")}},
+			wantRun:       []string{},
+			wantRemove:    []reconcile.ContainerRef{},
+			wantPlacement: map[string][]string{"a": {"1"}, "b": {"2"}},
+		},
+		{
+			name: "move containers off agents outside the selector",
+			set: types.ReplicaSet{
+				DeployArgs: types.DeployArgs{Image: "executor"},
+				Replicas:   1,
+				Selector:   types.Constraints{DeviceSupport: []string{"gpu"}},
+			},
+			containers:    map[string][]types.Container{"a": {}, "c": {executor("1", "running")}},
+			wantRun:       []string{"a"},
+			wantRemove:    []reconcile.ContainerRef{{AgentId: "c", ContainerId: "1"}},
+			wantPlacement: map[string][]string{},
+		},
+		{
+			name:          "never touch other images",
+			set:           set(0, types.SpreadOnePerAgent),
+			containers:    map[string][]types.Container{"a": {other}},
+			wantRun:       []string{},
+			wantRemove:    []reconcile.ContainerRef{},
+			wantPlacement: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, remove, placement := plan(tt.set, agents, tt.containers)
+			if !reflect.DeepEqual(run, tt.wantRun) {
+				t.Errorf("Run incorrect. Got %v, Want %v", run, tt.wantRun)
+			}
+			if !reflect.DeepEqual(remove, tt.wantRemove) {
+				t.Errorf("Remove incorrect. Got %v, Want %v", remove, tt.wantRemove)
+			}
+			if !reflect.DeepEqual(placement, tt.wantPlacement) {
+				t.Errorf("Placement incorrect. Got %v, Want %v", placement, tt.wantPlacement)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	sets.Store("existing", types.ReplicaSet{Name: "existing", DeployArgs: types.DeployArgs{Image: "nginx"}})
+	defer sets.Delete("existing")
+	tests := []struct {
+		name    string
+		set     types.ReplicaSet
+		wantErr bool
+	}{
+		{"valid", types.ReplicaSet{Name: "executor", DeployArgs: types.DeployArgs{Image: "executor"}, Replicas: 3}, false},
+		{"replace itself", types.ReplicaSet{Name: "existing", DeployArgs: types.DeployArgs{Image: "nginx:latest"}}, false},
+		{"missing name", types.ReplicaSet{DeployArgs: types.DeployArgs{Image: "executor"}}, true},
+		{"slash in name", types.ReplicaSet{Name: "a/b", DeployArgs: types.DeployArgs{Image: "executor"}}, true},
+		{"missing image", types.ReplicaSet{Name: "executor"}, true},
+		{"negative replicas", types.ReplicaSet{Name: "executor", DeployArgs: types.DeployArgs{Image: "executor"}, Replicas: -1}, true},
+		{"unknown spread", types.ReplicaSet{Name: "executor", DeployArgs: types.DeployArgs{Image: "executor"}, Spread: "random"}, true},
+		{"image of another set", types.ReplicaSet{Name: "executor", DeployArgs: types.DeployArgs{Image: "nginx:latest"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.set)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() Got %v, Want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"osmoticframework/controller/alert"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/metrics"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/types/metric"
+	"osmoticframework/controller/vars"
+	"sync"
+	"time"
+)
+
+/*
+Threshold alerting on edge metrics
+Every interval, each rule is evaluated on every agent, or on every container of every agent. See types.AlertRule
+A rule fires when its value stays above the threshold for the configured number of seconds. This stops short spikes from raising alerts.
+The alert is raised once per breach, or again every repeat period while the value stays above the threshold.
+Alerts are pushed to alert.PerformanceIssues
+*/
+
+//Timeout of the monitor requests. Shorter than the interval, so that evaluations do not pile up
+const requestTimeout = 10
+
+//An agent, or a container of an agent, that a rule is evaluated on
+type target struct {
+	rule        int
+	agentId     string
+	containerId string
+}
+
+//State of a rule on a target
+type breach struct {
+	//When the value went above the threshold. Zero if the value is below
+	since time.Time
+	//When the alert was last raised during this breach. Zero if it was not raised
+	fired time.Time
+}
+
+//A value of a rule on a target
+type measurement struct {
+	target target
+	value  float64
+	err    error
+}
+
+//Only accessed by the evaluation loop
+var breaches = make(map[target]*breach)
+
+//Runs the evaluation loop. This blocks until the controller terminates
+func Start() {
+	rules := make([]types.AlertRule, 0)
+	for _, rule := range vars.GetAlertRules() {
+		err := validate(rule)
+		if err != nil {
+			log.Warn.Printf("Ignoring alerting rule %s: %s\n", rule.Type, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return
+	}
+	interval := time.Duration(vars.GetAlertInterval()) * time.Second
+	log.Info.Printf("Evaluating %d alerting rules every %s\n", len(rules), interval)
+	for !vars.IsTerminate() {
+		time.Sleep(interval)
+		evaluate(rules, time.Now())
+	}
+}
+
+func validate(rule types.AlertRule) error {
+	switch rule.Type {
+	case types.PerformanceAlertTypeCPUUsageHigh, types.PerformanceAlertTypeMemoryPressure:
+	case types.PerformanceAlertTypeDiskPressure:
+		if rule.Container {
+			return errors.New("containers are not supported")
+		}
+	case types.PerformanceAlertTypeNetworkLatency:
+		return errors.New("raised from heartbeats")
+	default:
+		return errors.New("unknown alert type")
+	}
+	if rule.Threshold <= 0 {
+		return errors.New("threshold must be above 0")
+	}
+	if rule.For < 0 || rule.Repeat < 0 {
+		return errors.New("for and repeat cannot be negative")
+	}
+	return nil
+}
+
+//Measures all rules on all agents, and raises the alerts
+//Agents are measured in parallel. The measurements are applied one by one afterwards
+func evaluate(rules []types.AlertRule, now time.Time) {
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	results := make([]measurement, 0)
+	vars.Agents.Range(func(agentId, agent interface{}) bool {
+		wg.Add(1)
+		go func(agentId string, agent types.Agent) {
+			defer wg.Done()
+			agentResults := measureAgent(rules, agentId, agent.Containers, now)
+			lock.Lock()
+			results = append(results, agentResults...)
+			lock.Unlock()
+		}(agentId.(string), agent.(types.Agent))
+		return true
+	})
+	wg.Wait()
+	seen := make(map[target]bool)
+	for _, result := range results {
+		seen[result.target] = true
+		if result.err != nil {
+			log.Warn.Printf("%s >> Failed evaluating alerting rule %s\n", result.target.agentId, rules[result.target.rule].Type)
+			log.Warn.Println(result.err)
+			continue
+		}
+		state, ok := breaches[result.target]
+		if !ok {
+			state = &breach{}
+			breaches[result.target] = state
+		}
+		if state.update(rules[result.target.rule], result.value, now) {
+			raise(rules[result.target.rule], result.target, result.value)
+		}
+	}
+	//Forget agents that disconnected and containers that were removed
+	for key := range breaches {
+		if !seen[key] {
+			delete(breaches, key)
+		}
+	}
+}
+
+func measureAgent(rules []types.AlertRule, agentId string, containers []string, now time.Time) []measurement {
+	results := make([]measurement, 0)
+	for i, rule := range rules {
+		if !rule.Container {
+			value, err := measure(rule, agentId, "", now)
+			results = append(results, measurement{target: target{rule: i, agentId: agentId}, value: value, err: err})
+			continue
+		}
+		for _, containerId := range containers {
+			value, err := measure(rule, agentId, containerId, now)
+			results = append(results, measurement{target: target{rule: i, agentId: agentId, containerId: containerId}, value: value, err: err})
+		}
+	}
+	return results
+}
+
+//Updates the state with a new value. Returns true if the alert must be raised
+func (b *breach) update(rule types.AlertRule, value float64, now time.Time) bool {
+	if value <= rule.Threshold {
+		b.since = time.Time{}
+		b.fired = time.Time{}
+		return false
+	}
+	if b.since.IsZero() {
+		b.since = now
+	}
+	if now.Sub(b.since) < time.Duration(rule.For)*time.Second {
+		return false
+	}
+	if b.fired.IsZero() || rule.Repeat > 0 && now.Sub(b.fired) >= time.Duration(rule.Repeat)*time.Second {
+		b.fired = now
+		return true
+	}
+	return false
+}
+
+func raise(rule types.AlertRule, target target, value float64) {
+	if target.containerId != "" {
+		log.Warn.Printf("Container %s on agent %s raised %s. Value %g is above %g\n", target.containerId, target.agentId, rule.Type, value, rule.Threshold)
+	} else {
+		log.Warn.Printf("Agent %s raised %s. Value %g is above %g\n", target.agentId, rule.Type, value, rule.Threshold)
+	}
+	metrics.Alerts.Inc(string(rule.Type))
+	alert.PerformanceIssues.Publish(types.PerformanceReport{
+		AgentId:   target.agentId,
+		CloudSide: false,
+		Container: target.containerId,
+		AlertType: rule.Type,
+		Value:     value,
+	})
+}
+
+//Queries the value of a rule. The container ID is empty for rules on agents
+func measure(rule types.AlertRule, agentId, containerId string, now time.Time) (float64, error) {
+	switch rule.Type {
+	case types.PerformanceAlertTypeCPUUsageHigh:
+		if containerId != "" {
+			return containerCPU(agentId, containerId, now)
+		}
+		result, err := await(request.CPUUtilizationRequest(agentId, now, requestTimeout))
+		if err != nil {
+			return 0, err
+		}
+		return result.(metric.CpuEdgeOverallUsageMetric).Usage, nil
+	case types.PerformanceAlertTypeMemoryPressure:
+		if containerId != "" {
+			result, err := await(request.MemoryContainerRequest(agentId, containerId, now, requestTimeout))
+			if err != nil {
+				return 0, err
+			}
+			return float64(result.(metric.MemoryContainerMetric).Usage), nil
+		}
+		return edgeMemory(agentId, now)
+	case types.PerformanceAlertTypeDiskPressure:
+		return edgeDisk(agentId, now)
+	default:
+		return 0, fmt.Errorf("unknown alert type %s", rule.Type)
+	}
+}
+
+//Number of cores used by a container. The agent reports the usage of each core
+func containerCPU(agentId, containerId string, now time.Time) (float64, error) {
+	result, err := await(request.CPUContainerAvgRequest(agentId, containerId, now, requestTimeout))
+	if err != nil {
+		return 0, err
+	}
+	usage := 0.0
+	for _, core := range result.([]metric.CpuContainerMetric) {
+		usage += core.Usage
+	}
+	return usage, nil
+}
+
+//Fraction of the memory used by an agent
+func edgeMemory(agentId string, now time.Time) (float64, error) {
+	used, err := await(request.MemoryEdgeRequest(agentId, now, requestTimeout))
+	if err != nil {
+		return 0, err
+	}
+	total, err := await(request.MemoryEdgeTotalRequest(agentId, now, requestTimeout))
+	if err != nil {
+		return 0, err
+	}
+	if total.(metric.MemoryEdgeMetric).Usage == 0 {
+		return 0, errors.New("total memory unknown")
+	}
+	return float64(used.(metric.MemoryEdgeMetric).Usage) / float64(total.(metric.MemoryEdgeMetric).Usage), nil
+}
+
+//Fraction used of the fullest filesystem of an agent
+func edgeDisk(agentId string, now time.Time) (float64, error) {
+	used, err := await(request.IOFilesystemUsedRequest(agentId, now, requestTimeout))
+	if err != nil {
+		return 0, err
+	}
+	size, err := await(request.IOFilesystemSizeRequest(agentId, now, requestTimeout))
+	if err != nil {
+		return 0, err
+	}
+	return fullest(used.([]metric.IOFilesystemBytesMetric), size.([]metric.IOFilesystemBytesMetric)), nil
+}
+
+//Filesystems are matched by device and mount point
+func fullest(used, size []metric.IOFilesystemBytesMetric) float64 {
+	sizes := make(map[string]uint64)
+	for _, filesystem := range size {
+		sizes[filesystem.Device+" "+filesystem.MountPoint] = filesystem.Bytes
+	}
+	fraction := 0.0
+	for _, filesystem := range used {
+		total := sizes[filesystem.Device+" "+filesystem.MountPoint]
+		if total == 0 {
+			continue
+		}
+		if current := float64(filesystem.Bytes) / float64(total); current > fraction {
+			fraction = current
+		}
+	}
+	return fraction
+}
+
+func await(task *request.RequestTask) (interface{}, error) {
+	result := request.Await(task)
+	if result.ResultType == request.Error {
+		return nil, result.Content.(error)
+	}
+	return result.Content, nil
+}
@@ -0,0 +1,90 @@
+package rules
+
+import (
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/types/metric"
+	"testing"
+	"time"
+)
+
+func TestBreachUpdate(t *testing.T) {
+	rule := types.AlertRule{Type: types.PerformanceAlertTypeCPUUsageHigh, Threshold: 0.9, For: 30, Repeat: 60}
+	start := time.Unix(1000, 0)
+	//Values and expected alerts in order
+	tests := []struct {
+		Offset int
+		Value  float64
+		Fire   bool
+	}{
+		{0, 0.5, false},
+		//Above the threshold, but not for long enough
+		{10, 0.95, false},
+		{30, 0.95, false},
+		{40, 0.95, true},
+		//Still breached. Not raised again before the repeat period
+		{70, 0.95, false},
+		{100, 0.95, true},
+		//Back to normal. The next breach must last long enough again
+		{110, 0.9, false},
+		{120, 0.95, false},
+		{150, 0.95, true},
+	}
+	state := &breach{}
+	for _, test := range tests {
+		fire := state.update(rule, test.Value, start.Add(time.Duration(test.Offset)*time.Second))
+		if fire != test.Fire {
+			t.Errorf("Alert at %ds with value %g incorrect. Got %t, Want %t", test.Offset, test.Value, fire, test.Fire)
+		}
+	}
+	//Raised once per breach without a repeat period
+	once := types.AlertRule{Type: types.PerformanceAlertTypeCPUUsageHigh, Threshold: 0.9}
+	state = &breach{}
+	fired := 0
+	for i := 0; i < 10; i++ {
+		if state.update(once, 1, start.Add(time.Duration(i)*time.Minute)) {
+			fired++
+		}
+	}
+	if fired != 1 {
+		t.Errorf("Alerts without repeat incorrect. Got %d, Want 1", fired)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		Rule  types.AlertRule
+		Valid bool
+	}{
+		{types.AlertRule{Type: types.PerformanceAlertTypeCPUUsageHigh, Threshold: 0.9}, true},
+		{types.AlertRule{Type: types.PerformanceAlertTypeMemoryPressure, Container: true, Threshold: 512e6, For: 60}, true},
+		{types.AlertRule{Type: types.PerformanceAlertTypeDiskPressure, Threshold: 0.8}, true},
+		{types.AlertRule{Type: types.PerformanceAlertTypeDiskPressure, Container: true, Threshold: 0.8}, false},
+		{types.AlertRule{Type: types.PerformanceAlertTypeNetworkLatency, Threshold: 3000}, false},
+		{types.AlertRule{Type: "gpu-usage-high", Threshold: 0.9}, false},
+		{types.AlertRule{Type: types.PerformanceAlertTypeCPUUsageHigh}, false},
+		{types.AlertRule{Type: types.PerformanceAlertTypeCPUUsageHigh, Threshold: 0.9, For: -1}, false},
+	}
+	for _, test := range tests {
+		err := validate(test.Rule)
+		if (err == nil) != test.Valid {
+			t.Errorf("Rule %+v validation incorrect. Got %v, Want valid: %t", test.Rule, err, test.Valid)
+		}
+	}
+}
+
+func TestFullest(t *testing.T) {
+	used := []metric.IOFilesystemBytesMetric{
+		{Device: "sda1", MountPoint: "/", Bytes: 50},
+		{Device: "sdb1", MountPoint: "/data", Bytes: 90},
+		//No size
+		{Device: "tmpfs", MountPoint: "/run", Bytes: 10},
+	}
+	size := []metric.IOFilesystemBytesMetric{
+		{Device: "sda1", MountPoint: "/", Bytes: 100},
+		{Device: "sdb1", MountPoint: "/data", Bytes: 100},
+	}
+	fraction := fullest(used, size)
+	if fraction != 0.9 {
+		t.Errorf("Fullest filesystem incorrect. Got %g, Want 0.9", fraction)
+	}
+}
@@ -0,0 +1,212 @@
+package scheduler
+
+import (
+	"errors"
+	"osmoticframework/controller/api/impl/request"
+	"osmoticframework/controller/log"
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/types/metric"
+	"osmoticframework/controller/vars"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Placement scheduler
+Chooses an agent to run a container on, instead of the caller picking an agent ID by hand.
+Agents are filtered by the required devices and sensors, then ranked by their CPU, memory and disk usage and the number of containers they run.
+Usage is queried through the monitor API and cached for a short time, so scheduling many containers at once does not flood the agents with requests.
+*/
+
+//How much each resource counts towards the score of an agent
+const (
+	cpuWeight       = 0.4
+	memoryWeight    = 0.3
+	diskWeight      = 0.1
+	containerWeight = 0.2
+)
+
+//Score given to a resource if the agent did not report it. Agents with unknown usage are ranked in the middle
+const unknownUsage = 0.5
+
+const metricTimeout = 10
+const cacheDuration = 30 * time.Second
+
+//Deploying containers requires pulling images, which takes longer than other requests
+const DefaultDeployTimeout = 180
+
+var ErrNoCandidate = errors.New("no agent satisfies the constraints")
+
+//Agent ID -> usage
+var usageCache sync.Map
+
+type usage struct {
+	time   time.Time
+	cpu    float64
+	memory float64
+	disk   float64
+}
+
+//Chooses the best agent for the container and sends a run request to it
+//Returns the chosen agent ID and the request task. Receive from task.Result for the container ID
+//The origin is recorded with the container. Use OriginScheduler unless the container is placed on behalf of another component
+func Schedule(deployArgs types.DeployArgs, authInfo types.AuthInfo, constraints types.Constraints, origin types.ContainerOrigin, timeout float64) (string, *request.RequestTask, error) {
+	candidates := Rank(constraints)
+	if len(candidates) == 0 {
+		return "", nil, ErrNoCandidate
+	}
+	agentId := candidates[0].AgentId
+	log.Info.Printf("Scheduler >> Placing %s on agent %s (score %.2f)\n", deployArgs.Image, agentId, candidates[0].Score)
+	task := request.RunRequestWithOrigin(agentId, deployArgs, authInfo, origin, timeout)
+	if task == nil {
+		return agentId, nil, errors.New("failed sending request")
+	}
+	//The agent now runs one more container. Drop its cached usage so the next placement sees the change
+	usageCache.Delete(agentId)
+	return agentId, task, nil
+}
+
+//Returns all agents that satisfy the constraints, best agent first
+func Rank(constraints types.Constraints) []types.Candidate {
+	agents := make(map[string]types.Agent)
+	vars.Agents.Range(func(agentId, agent interface{}) bool {
+		if Eligible(agentId.(string), agent.(types.Agent), constraints) {
+			agents[agentId.(string)] = agent.(types.Agent)
+		}
+		return true
+	})
+	//Query the agents in parallel. Each one can take up to the metric timeout
+	candidates := make([]types.Candidate, 0, len(agents))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for agentId, agent := range agents {
+		wg.Add(1)
+		go func(agentId string, agent types.Agent) {
+			defer wg.Done()
+			agentUsage := getUsage(agentId)
+			lock.Lock()
+			candidates = append(candidates, types.Candidate{
+				AgentId:    agentId,
+				CPU:        agentUsage.cpu,
+				Memory:     agentUsage.memory,
+				Disk:       agentUsage.disk,
+				Containers: len(agent.Containers),
+			})
+			lock.Unlock()
+		}(agentId, agent)
+	}
+	wg.Wait()
+	score(candidates)
+	return candidates
+}
+
+//Scores and sorts the candidates. Container counts are relative to the busiest candidate
+func score(candidates []types.Candidate) {
+	maxContainers := 0
+	for _, candidate := range candidates {
+		if candidate.Containers > maxContainers {
+			maxContainers = candidate.Containers
+		}
+	}
+	for i := range candidates {
+		containers := 0.0
+		if maxContainers > 0 {
+			containers = float64(candidates[i].Containers) / float64(maxContainers)
+		}
+		candidates[i].Score = cpuWeight*known(candidates[i].CPU) +
+			memoryWeight*known(candidates[i].Memory) +
+			diskWeight*known(candidates[i].Disk) +
+			containerWeight*containers
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score < candidates[j].Score
+		}
+		return candidates[i].AgentId < candidates[j].AgentId
+	})
+}
+
+func known(ratio float64) float64 {
+	if ratio < 0 {
+		return unknownUsage
+	}
+	return ratio
+}
+
+//Checks if an agent satisfies the constraints
+func Eligible(agentId string, agent types.Agent, constraints types.Constraints) bool {
+	for _, excluded := range constraints.Exclude {
+		if excluded == agentId {
+			return false
+		}
+	}
+	return supports(agent.DeviceSupport, constraints.DeviceSupport) && supports(agent.SensorSupport, constraints.SensorSupport)
+}
+
+//Checks if an agent supports all of the required devices or sensors
+func supports(available, required []string) bool {
+	for _, r := range required {
+		found := false
+		for _, a := range available {
+			if a == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//Returns the cached usage of an agent, or queries it if the cache is stale
+func getUsage(agentId string) usage {
+	if _cached, ok := usageCache.Load(agentId); ok {
+		cached := _cached.(usage)
+		if time.Since(cached.time) < cacheDuration {
+			return cached
+		}
+	}
+	now := time.Now()
+	agentUsage := usage{time: now, cpu: -1, memory: -1, disk: -1}
+	if result := request.Await(request.CPUUtilizationRequest(agentId, now, metricTimeout)); result.ResultType == request.Ok {
+		agentUsage.cpu = result.Content.(metric.CpuEdgeOverallUsageMetric).Usage
+	}
+	used := request.Await(request.MemoryEdgeRequest(agentId, now, metricTimeout))
+	total := request.Await(request.MemoryEdgeTotalRequest(agentId, now, metricTimeout))
+	if used.ResultType == request.Ok && total.ResultType == request.Ok {
+		agentUsage.memory = ratio(used.Content.(metric.MemoryEdgeMetric).Usage, total.Content.(metric.MemoryEdgeMetric).Usage)
+	}
+	used = request.Await(request.IOFilesystemUsedRequest(agentId, now, metricTimeout))
+	total = request.Await(request.IOFilesystemSizeRequest(agentId, now, metricTimeout))
+	if used.ResultType == request.Ok && total.ResultType == request.Ok {
+		agentUsage.disk = diskUsage(used.Content.([]metric.IOFilesystemBytesMetric), total.Content.([]metric.IOFilesystemBytesMetric))
+	}
+	usageCache.Store(agentId, agentUsage)
+	return agentUsage
+}
+
+//Disk usage of the root filesystem. Containers are stored there by default
+func diskUsage(used, size []metric.IOFilesystemBytesMetric) float64 {
+	var usedBytes, sizeBytes uint64
+	for _, filesystem := range used {
+		if filesystem.MountPoint == "/" {
+			usedBytes = filesystem.Bytes
+		}
+	}
+	for _, filesystem := range size {
+		if filesystem.MountPoint == "/" {
+			sizeBytes = filesystem.Bytes
+		}
+	}
+	return ratio(usedBytes, sizeBytes)
+}
+
+func ratio(used, total uint64) float64 {
+	if total == 0 {
+		return -1
+	}
+	return float64(used) / float64(total)
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"osmoticframework/controller/types"
+	"osmoticframework/controller/types/metric"
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	candidates := []types.Candidate{
+		{AgentId: "busy", CPU: 0.9, Memory: 0.8, Disk: 0.5, Containers: 4},
+		{AgentId: "idle", CPU: 0.1, Memory: 0.2, Disk: 0.5, Containers: 0},
+		{AgentId: "unknown", CPU: -1, Memory: -1, Disk: -1, Containers: 2},
+		{AgentId: "idle-b", CPU: 0.1, Memory: 0.2, Disk: 0.5, Containers: 0},
+	}
+	score(candidates)
+	got := make([]string, 0)
+	for _, candidate := range candidates {
+		got = append(got, candidate.AgentId)
+	}
+	want := []string{"idle", "idle-b", "unknown", "busy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Ranking incorrect. Got %v, Want %v", got, want)
+	}
+}
+
+func TestEligible(t *testing.T) {
+	agent := types.Agent{DeviceSupport: []string{"gpu", "camera"}, SensorSupport: []string{"temperature"}}
+	tests := []struct {
+		name        string
+		constraints types.Constraints
+		want        bool
+	}{
+		{"no constraints", types.Constraints{}, true},
+		{"supported", types.Constraints{DeviceSupport: []string{"gpu"}, SensorSupport: []string{"temperature"}}, true},
+		{"missing device", types.Constraints{DeviceSupport: []string{"gpu", "tpu"}}, false},
+		{"missing sensor", types.Constraints{SensorSupport: []string{"humidity"}}, false},
+		{"excluded", types.Constraints{Exclude: []string{"agent"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Eligible("agent", agent, tt.constraints); got != tt.want {
+				t.Errorf("Eligible() Got %v, Want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	used := []metric.IOFilesystemBytesMetric{{MountPoint: "/boot", Bytes: 90}, {MountPoint: "/", Bytes: 25}}
+	size := []metric.IOFilesystemBytesMetric{{MountPoint: "/boot", Bytes: 100}, {MountPoint: "/", Bytes: 100}}
+	if got := diskUsage(used, size); got != 0.25 {
+		t.Errorf("Disk usage incorrect. Got %f, Want 0.25", got)
+	}
+	if got := diskUsage(used, nil); got != -1 {
+		t.Errorf("Disk usage without size incorrect. Got %f, Want -1", got)
+	}
+}
@@ -8,6 +8,7 @@ type AlertType string
 
 const (
 	AlertContainerCrash AlertType = "containerCrash"
+	AlertContainerEvent AlertType = "containerEvent"
 )
 
 type OfflineAgent struct {
@@ -40,6 +41,27 @@ type ContainerCrashReport struct {
 	Image    string
 	Status   string
 	ExitCode int
+	//The last lines of output of the container. Empty for crashes in the cloud
+	Output []string
+}
+
+//Lifecycle event of a container on an agent
+type ContainerEventReport struct {
+	AgentId string
+	ID      string
+	Name    string
+	Image   string
+	//The Docker event. create, start, restart, stop, die, oom, health_status or destroy
+	Action string
+	//Status of the container after the event. Empty if the event does not change it
+	Status   string
+	ExitCode int
+	//Result of the Docker healthcheck. healthy, unhealthy or starting. Only set for health_status events
+	Health       string
+	RestartCount int
+	OOMKilled    bool
+	//UNIX time in nanoseconds
+	Time int64
 }
 
 type PerformanceAlertType string
@@ -56,4 +78,26 @@ type PerformanceReport struct {
 	CloudSide bool
 	Container string
 	AlertType PerformanceAlertType
+	//The value that raised the alert. The latency in milliseconds for network-latency. See AlertRule for the other types
+	Value float64
+}
+
+/*
+	Threshold rule on edge metrics. See controller/rules
+	The value of each alert type is
+		cpu-usage-high - Fraction of the CPU used by the agent (0 to 1), or number of cores used by the container
+		memory-pressure - Fraction of the memory used by the agent (0 to 1), or bytes used by the container
+		disk-pressure - Fraction used of the fullest filesystem of the agent (0 to 1). Containers are not supported
+	network-latency is raised from the heartbeats and cannot be used in rules
+*/
+type AlertRule struct {
+	Type PerformanceAlertType `json:"type"`
+	//Evaluate each container of the agents instead of the agents
+	Container bool `json:"container,omitempty"`
+	//The rule fires when the value is above the threshold
+	Threshold float64 `json:"threshold"`
+	//Seconds the value must stay above the threshold before the rule fires
+	For int `json:"for,omitempty"`
+	//Seconds before the alert is raised again while the value stays above the threshold. Zero raises it once
+	Repeat int `json:"repeat,omitempty"`
 }
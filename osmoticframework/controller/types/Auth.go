@@ -0,0 +1,14 @@
+package types
+
+import "time"
+
+//A one-time bootstrap token for registering an agent
+//The token itself is only shown when it is issued. It is identified by its SHA-256 hash afterwards
+type BootstrapToken struct {
+	ID          string
+	Description string
+	Created     time.Time
+	//Nil if the token does not expire
+	Expires *time.Time
+	Used    bool
+}
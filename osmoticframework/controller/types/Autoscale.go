@@ -0,0 +1,31 @@
+package types
+
+/*
+	Horizontal scaling policy of a cloud deployment. See controller/autoscale
+	The autoscaler measures a value and sets the replicas to ceil(value / target), within the replica bounds
+	Set one of
+		TargetCPU - The value is the number of cores used by all pods of the deployment. The target is the cores each replica should use
+		Query - The value is the result of the PromQL expression on the cloud Prometheus. The target is TargetValue, the value each replica should handle
+*/
+type ScalingPolicy struct {
+	//Name of a deployment created with KRunDeployment
+	Deployment  string  `json:"deployment"`
+	MinReplicas int32   `json:"min_replicas"`
+	MaxReplicas int32   `json:"max_replicas"`
+	TargetCPU   float64 `json:"target_cpu,omitempty"`
+	//Must return a scalar or a single series, e.g. sum(rate(aggregator_messages_total[1m]))
+	Query       string  `json:"query,omitempty"`
+	TargetValue float64 `json:"target_value,omitempty"`
+	//Seconds after a scaling before the deployment may be scaled down. Scaling up is never delayed
+	Cooldown int `json:"cooldown,omitempty"`
+}
+
+//A change of replicas made by the autoscaler
+type ScalingReport struct {
+	Deployment string
+	From       int32
+	To         int32
+	//The measured value and the target of the policy
+	Value  float64
+	Target float64
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 //Deployment information for Docker
 
 type DeployArgs struct {
@@ -82,6 +84,34 @@ type ExposePort struct {
 	Protocol      Protocol
 }
 
+//A container deployed through the API, with the arguments it was started with
+//This is used to deploy the container again if its agent disconnects
+//The spec is also written to the containers table in the database, except the authentication information
+type ContainerSpec struct {
+	AgentId    string
+	DeployArgs DeployArgs
+	AuthInfo   AuthInfo `json:"-"`
+	//When the run or update request was sent
+	RequestTime time.Time
+	Origin      ContainerOrigin
+}
+
+//What requested a container to be deployed
+type ContainerOrigin string
+
+const (
+	//Direct API calls, including the REST API
+	OriginAPI ContainerOrigin = "api"
+	//Placed by the scheduler
+	OriginScheduler ContainerOrigin = "scheduler"
+	//Deployed by the reconciler to match the manifest
+	OriginManifest ContainerOrigin = "manifest"
+	//Moved from a disconnected agent
+	OriginFailover ContainerOrigin = "failover"
+	//Deployed to keep the replicas of a replica set
+	OriginReplicaSet ContainerOrigin = "replicaset"
+)
+
 //Authentication information for pulling images from Docker Hub
 type AuthInfo struct {
 	Username string
@@ -111,6 +141,25 @@ const (
 	PullIfNotExist PullOption = "ifNotExist"
 )
 
+//A line of output of a container
+type LogLine struct {
+	//stdout or stderr. Empty for Kubernetes pods, which merge both
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+	//Only set for Kubernetes pods
+	Pod string `json:"pod,omitempty"`
+}
+
+//Result of a command run inside a container
+type ExecResult struct {
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	//Set if the output was cut at the limit of the agent
+	Truncated bool `json:"truncated"`
+}
+
 //Container struct. Used when a listing container call is made
 type Container struct {
 	ID    string
@@ -118,6 +167,8 @@ type Container struct {
 	//Container arguments
 	Command string
 	Status  string
+	//Exit code of the last run. Only meaningful once the container has exited
+	ExitCode int
 	//Total container file size
 	SizeRootFs int64
 	//Container file size, excluding its base image
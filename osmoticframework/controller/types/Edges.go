@@ -9,4 +9,5 @@ type Agent struct {
 	Containers    []string //Array of container IDs. The list of containers hosted in the agent device
 	LastAlive     int64    //Last time the agent sent a heartbeat. In UNIX nanosecond timestamp.
 	PingSeq       int64    //Last ping sequence number
+	Latency       int64    //Latency of the last ping in milliseconds
 }
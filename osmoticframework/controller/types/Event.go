@@ -0,0 +1,51 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//Where an event happened. Edge events are reported by agents, cloud events by Kubernetes
+type EventSource string
+
+const (
+	EventSourceAgent EventSource = "agent"
+	EventSourceCloud EventSource = "cloud"
+)
+
+//An entry of the event history. See controller/history
+type Event struct {
+	ID     int64       `json:"id"`
+	Time   time.Time   `json:"time"`
+	Source EventSource `json:"source"`
+	//Empty for cloud events
+	AgentId string `json:"agentId,omitempty"`
+	//The alert types, e.g. container-crash or cpu-usage-high, and pod-status for Kubernetes pod transitions
+	Type string `json:"type"`
+	//The container ID on agents, or the pod name in the cloud. Empty if the event is about the whole agent
+	Target string `json:"target,omitempty"`
+	//The original report of the event
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+//Filter of event history queries. Empty fields match everything
+type EventFilter struct {
+	AgentId string
+	Type    string
+	From    time.Time
+	To      time.Time
+	//Maximum number of events returned, newest first
+	Limit int
+}
+
+//A change of state of a Kubernetes pod, as seen by the pod watchers
+type PodStatusReport struct {
+	Deployment string
+	Pod        string
+	//added, modified or deleted
+	Event string
+	//The pod phase, e.g. Pending or Running
+	Phase string
+	//The state of the first container of the pod, e.g. ContainerCreating or CrashLoopBackOff
+	Status string
+}
@@ -249,3 +249,11 @@ type KConfigMap struct {
 	//The value is the content of the file
 	BinaryData map[string][]byte
 }
+
+//Kind of the workload that owns the pods. See KGetPodLogs
+type KWorkload string
+
+const (
+	KWorkloadDeployment KWorkload = "deployment"
+	KWorkloadJob        KWorkload = "job"
+)
@@ -0,0 +1,26 @@
+package types
+
+//Declarative description of edge workloads. The controller keeps the containers on the agents in line with this.
+//See controller/reconcile
+
+type Manifest struct {
+	Workloads []EdgeWorkload `json:"workloads"`
+}
+
+type EdgeWorkload struct {
+	//Unique name of the workload. Only used for logging
+	Name string `json:"name"`
+	//Containers of a workload are identified by their image. Images must be unique across workloads
+	DeployArgs DeployArgs `json:"deployArgs"`
+	AuthInfo   AuthInfo   `json:"authInfo"`
+	/*
+		Placement. Use only ONE of the following options. If agents are listed, replicas is ignored.
+		Agents - Run one container on each of the listed agents
+		Replicas - Run this many containers, at most one per agent. Set to 0 to remove all containers of the workload.
+	*/
+	Agents   []string `json:"agents,omitempty"`
+	Replicas int      `json:"replicas,omitempty"`
+	//Only agents that support all of the listed devices and sensors are selected
+	DeviceSupport []string `json:"deviceSupport,omitempty"`
+	SensorSupport []string `json:"sensorSupport,omitempty"`
+}
@@ -0,0 +1,40 @@
+package types
+
+//Sinks that receive alerts from the controller. See controller/notify
+
+type NotifierType string
+
+const (
+	NotifierWebhook NotifierType = "webhook"
+	NotifierSMTP    NotifierType = "smtp"
+	NotifierMQTT    NotifierType = "mqtt"
+)
+
+type AlertSeverity string
+
+//In order of importance
+const (
+	SeverityInfo     AlertSeverity = "info"
+	SeverityWarning  AlertSeverity = "warning"
+	SeverityCritical AlertSeverity = "critical"
+)
+
+type NotifierConfig struct {
+	Type NotifierType `json:"type"`
+	//Webhook - URL the alerts are posted to as JSON, and extra headers of the request, e.g. for authentication
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	//SMTP and MQTT - Address of the server in host:port. MQTT defaults to the broker the controller deploys on Kubernetes
+	Address  string `json:"address,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	//SMTP - Sender and recipients of the emails
+	From string   `json:"from,omitempty"`
+	To   []string `json:"to,omitempty"`
+	//MQTT - Topic the alerts are published to as JSON
+	Topic string `json:"topic,omitempty"`
+	//Filters. An empty filter lets everything through
+	AlertTypes  []string      `json:"alert_types,omitempty"`
+	Agents      []string      `json:"agents,omitempty"`
+	MinSeverity AlertSeverity `json:"min_severity,omitempty"`
+}
@@ -0,0 +1,39 @@
+package types
+
+import "time"
+
+/*
+	A workload that runs a number of copies across the agents. See controller/replicaset
+	Containers of a replica set are identified by their image. Images must be unique across replica sets and the manifest
+	The authentication information is not stored in the database. Apply sets with private images again after the controller restarts
+*/
+type ReplicaSet struct {
+	Name       string     `json:"name"`
+	DeployArgs DeployArgs `json:"deployArgs"`
+	AuthInfo   AuthInfo   `json:"-"`
+	//Number of containers to keep running. Set to 0 to remove all containers of the set
+	Replicas int `json:"replicas"`
+	//Only agents that satisfy the selector run containers of the set
+	Selector Constraints  `json:"selector,omitempty"`
+	Spread   SpreadPolicy `json:"spread,omitempty"`
+}
+
+//How the containers of a replica set are placed on the agents
+type SpreadPolicy string
+
+const (
+	//At most one container per agent. Replicas above the number of eligible agents are not deployed. This is the default
+	SpreadOnePerAgent SpreadPolicy = "one-per-agent"
+	//Containers are spread evenly, with several per agent if there are fewer eligible agents than replicas
+	SpreadBalanced SpreadPolicy = "balanced"
+)
+
+//A replica set and its containers as of the last reconciliation
+type ReplicaSetStatus struct {
+	ReplicaSet
+	//Agent ID -> Running containers of the set. Includes containers that finished with exit code 0
+	Placement map[string][]string `json:"placement"`
+	Running   int                 `json:"running"`
+	//Zero if the set was not reconciled yet
+	Reconciled time.Time `json:"reconciled"`
+}
@@ -0,0 +1,23 @@
+package types
+
+//Requirements for choosing an agent to run a container on. See controller/scheduler
+type Constraints struct {
+	//Only agents that support all of the listed devices and sensors are selected
+	DeviceSupport []string `json:"deviceSupport,omitempty"`
+	SensorSupport []string `json:"sensorSupport,omitempty"`
+	//Agent IDs that must not be chosen
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+//An agent that satisfies the constraints, with the resource usage used to rank it
+type Candidate struct {
+	AgentId string
+	//Usage ratios from 0 to 1. -1 if the agent did not report the metric
+	CPU    float64
+	Memory float64
+	Disk   float64
+	//Number of containers running on the agent
+	Containers int
+	//Lower is better
+	Score float64
+}
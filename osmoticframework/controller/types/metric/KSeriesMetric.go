@@ -0,0 +1,42 @@
+package metric
+
+//Results of range queries on Kubernetes. Each series is one line in a graph
+
+//CPU usage or time per core of a node
+type KCPUCoreSeries struct {
+	Core   int
+	Node   string
+	Values []Scalar
+}
+
+//Metrics of a whole node, such as CPU utilization or memory usage
+type KNodeSeries struct {
+	Node   string
+	Values []Scalar
+}
+
+//Metrics of a whole pod, such as CPU or memory usage
+type KPodSeries struct {
+	Pod    string
+	Values []Scalar
+}
+
+//Disk IO and network metrics per device
+type KNodeDeviceSeries struct {
+	Node   string
+	Device string
+	Values []Scalar
+}
+
+type KPodDeviceSeries struct {
+	Pod    string
+	Device string
+	Values []Scalar
+}
+
+type KFilesystemSeries struct {
+	Node       string
+	Device     string
+	Mountpoint string
+	Values     []Scalar
+}
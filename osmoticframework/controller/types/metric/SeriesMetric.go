@@ -0,0 +1,59 @@
+package metric
+
+//Results of range queries on edge devices. Each series is one line in a graph
+//The values are taken from the matrix Prometheus returns. Values are undefined where Prometheus has no data
+
+//CPU usage or time per core
+type CpuEdgeSeries struct {
+	Core   int
+	Agent  string
+	Values []Scalar
+}
+
+type CpuContainerSeries struct {
+	Container string
+	Core      int
+	Agent     string
+	Values    []Scalar
+}
+
+//Metrics of the whole edge device, such as CPU utilization or memory usage
+type EdgeSeries struct {
+	Agent  string
+	Values []Scalar
+}
+
+//Metrics of a whole container, such as memory usage
+type ContainerSeries struct {
+	Container string
+	Agent     string
+	Values    []Scalar
+}
+
+//Disk IO and network metrics per device
+type DeviceEdgeSeries struct {
+	Agent  string
+	Device string
+	Values []Scalar
+}
+
+type DeviceContainerSeries struct {
+	Container string
+	Agent     string
+	Device    string
+	Values    []Scalar
+}
+
+type FilesystemSeries struct {
+	Agent      string
+	Device     string
+	MountPoint string
+	Values     []Scalar
+}
+
+type ThermalSeries struct {
+	Agent    string
+	ZoneName string
+	ZoneUUID string
+	Values   []Scalar
+}
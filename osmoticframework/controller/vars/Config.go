@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net"
 	"osmoticframework/controller/log"
+	"osmoticframework/controller/types"
 	"regexp"
 	"sync"
 )
@@ -13,13 +14,30 @@ var config configStruct
 var cloudIP string
 
 type configStruct struct {
-	RabbitAddress     string   `json:"rabbitAddress"`
-	DatabaseAddress   string   `json:"databaseAddress"`
-	PrometheusAddress string   `json:"prometheusAddress"`
-	KubeConfigPath    string   `json:"kuberConfigPath"`
-	EnableProfiler    bool     `json:"enable_profiler,omitempty"`
-	ProfilerPort      int      `json:"profiler_port,omitempty" default:"6060"`
-	CIRepo            []string `json:"ci_repo,omitempty"`
+	RabbitAddress     string                 `json:"rabbitAddress"`
+	DatabaseAddress   string                 `json:"databaseAddress"`
+	PrometheusAddress string                 `json:"prometheusAddress"`
+	KubeConfigPath    string                 `json:"kuberConfigPath"`
+	EnableProfiler    bool                   `json:"enable_profiler,omitempty"`
+	ProfilerPort      int                    `json:"profiler_port,omitempty" default:"6060"`
+	CIRepo            []string               `json:"ci_repo,omitempty"`
+	EnableRestApi     bool                   `json:"enable_rest_api,omitempty"`
+	RestApiPort       int                    `json:"rest_api_port,omitempty" default:"8000"`
+	RestApiToken      string                 `json:"rest_api_token,omitempty"`
+	ManifestPath      string                 `json:"manifest_path,omitempty"`
+	ReconcileInterval int                    `json:"reconcile_interval,omitempty" default:"30"`
+	EnableFailover    bool                   `json:"enable_failover,omitempty"`
+	FailoverGrace     int                    `json:"failover_grace_period,omitempty" default:"60"`
+	FailoverToCloud   bool                   `json:"failover_to_cloud,omitempty"`
+	RegistrationAuth  bool                   `json:"enable_registration_auth,omitempty"`
+	RegistrationToken []string               `json:"registration_tokens,omitempty"`
+	EnableMetrics     bool                   `json:"enable_metrics,omitempty"`
+	MetricsPort       int                    `json:"metrics_port,omitempty" default:"9101"`
+	AlertRules        []types.AlertRule      `json:"alert_rules,omitempty"`
+	AlertInterval     int                    `json:"alert_interval,omitempty" default:"15"`
+	Notifiers         []types.NotifierConfig `json:"notifiers,omitempty"`
+	ScalingPolicies   []types.ScalingPolicy  `json:"autoscaling,omitempty"`
+	AutoscaleInterval int                    `json:"autoscale_interval,omitempty" default:"30"`
 }
 
 func LoadConfig(jsonBytes []byte) {
@@ -109,3 +127,102 @@ func GetProfilerPort() int {
 func GetCIRepo() []string {
 	return config.CIRepo
 }
+
+func IsRestApiEnable() bool {
+	return config.EnableRestApi
+}
+
+func GetRestApiPort() int {
+	if config.RestApiPort == 0 {
+		return 8000
+	}
+	return config.RestApiPort
+}
+
+//Bearer token required by the REST API. Leave empty to disable authentication
+func GetRestApiToken() string {
+	return config.RestApiToken
+}
+
+//Path to the edge workload manifest. Leave empty to disable reconciliation
+func GetManifestPath() string {
+	return config.ManifestPath
+}
+
+//Seconds between each reconciliation of the edge workload manifest
+func GetReconcileInterval() int {
+	if config.ReconcileInterval <= 0 {
+		return 30
+	}
+	return config.ReconcileInterval
+}
+
+func IsFailoverEnable() bool {
+	return config.EnableFailover
+}
+
+//Seconds to wait after an agent disconnects before its containers are moved to other agents
+func GetFailoverGracePeriod() int {
+	if config.FailoverGrace <= 0 {
+		return 60
+	}
+	return config.FailoverGrace
+}
+
+//Deploy containers to Kubernetes if no other agent can run them
+func IsFailoverToCloud() bool {
+	return config.FailoverToCloud
+}
+
+//Agents must present a bootstrap token to register, and their secret to rejoin
+func IsRegistrationAuthEnable() bool {
+	return config.RegistrationAuth
+}
+
+//Pre-shared bootstrap tokens. These can be used any number of times. One-time tokens are issued through the REST API
+func GetRegistrationTokens() []string {
+	return config.RegistrationToken
+}
+
+//Serve Prometheus metrics of the controller at /metrics
+func IsMetricsEnable() bool {
+	return config.EnableMetrics
+}
+
+func GetMetricsPort() int {
+	if config.MetricsPort == 0 {
+		return 9101
+	}
+	return config.MetricsPort
+}
+
+//Threshold rules on edge metrics. Leave empty to disable alerting rules
+func GetAlertRules() []types.AlertRule {
+	return config.AlertRules
+}
+
+//Seconds between each evaluation of the alerting rules
+func GetAlertInterval() int {
+	if config.AlertInterval <= 0 {
+		return 15
+	}
+	return config.AlertInterval
+}
+
+//Sinks that receive alerts. Leave empty to only log alerts
+func GetNotifiers() []types.NotifierConfig {
+	return config.Notifiers
+}
+
+//Autoscaling policies of cloud deployments. Leave empty to disable the autoscaler
+func GetScalingPolicies() []types.ScalingPolicy {
+	return config.ScalingPolicies
+}
+
+//Seconds between each evaluation of the autoscaling policies
+func GetAutoscaleInterval() int {
+	if config.AutoscaleInterval <= 0 {
+		return 30
+	}
+	return config.AutoscaleInterval
+}
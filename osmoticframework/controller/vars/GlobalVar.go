@@ -11,6 +11,18 @@ import (
 //Stores all agents and containers registered in memory
 var Agents sync.Map
 
+//Stores the deploy arguments of containers running on agents
+//Container ID -> types.ContainerSpec
+var ContainerSpecs sync.Map
+
+//Secrets of agents. Messages between the controller and agents are signed with them
+//Agent ID -> string
+var AgentKeys sync.Map
+
+//Agents that are no longer allowed to connect. Their messages are ignored
+//Agent ID -> time.Time of the revocation
+var RevokedAgents sync.Map
+
 //Stores all deployed cloud resources (Deployments, Service, Jobs, Cronjobs)
 //Each map stores the name of the resource in its key, value is always nil and ignored.
 var Deployments sync.Map
@@ -63,3 +75,8 @@ func GetCredDirectory() string {
 	}
 	return logsDir
 }
+
+func IsRevoked(agentId string) bool {
+	_, ok := RevokedAgents.Load(agentId)
+	return ok
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//HTTP client for the controller REST API. See controller/api/rest
+type client struct {
+	address string
+	token   string
+	http    *http.Client
+}
+
+//Response body of the REST API
+type response struct {
+	Status string          `json:"status"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+func newClient(address, token string) *client {
+	return &client{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		//Deploying containers can take minutes while the agent pulls the image
+		http: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+//Sends a request to the controller and decodes the result into out. out can be nil if the result is not needed
+func (c *client) do(method, path string, query url.Values, body interface{}, out interface{}) error {
+	endpoint := c.address + "/api/v1" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var result response
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return fmt.Errorf("invalid response from controller (HTTP %d)", resp.StatusCode)
+	}
+	if result.Status != "ok" {
+		if result.Error == "" {
+			return fmt.Errorf("request failed (HTTP %d)", resp.StatusCode)
+		}
+		return errors.New(result.Error)
+	}
+	if out == nil || len(result.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(result.Result, out)
+}
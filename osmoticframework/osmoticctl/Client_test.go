@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"status": "failed", "error": "unauthorized"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "ok", "result": [{"ID": "agent-a", "InternalIP": "10.0.0.1"}]}`))
+	}))
+	defer server.Close()
+
+	var agents []agent
+	err := newClient(server.URL, "wrong").do(http.MethodGet, "/agents", nil, nil, &agents)
+	if err == nil || err.Error() != "unauthorized" {
+		t.Errorf("Error incorrect. Got %v, Want unauthorized", err)
+	}
+	err = newClient(server.URL+"/", "secret").do(http.MethodGet, "/agents", nil, nil, &agents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 1 || agents[0].ID != "agent-a" || agents[0].InternalIP != "10.0.0.1" {
+		t.Errorf("Agents incorrect. Got %v", agents)
+	}
+}
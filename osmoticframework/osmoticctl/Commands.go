@@ -0,0 +1,641 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"osmoticframework/controller/types"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+//Returned when the command line arguments are wrong. The usage is printed instead of the error
+var errUsage = errors.New("usage")
+
+type cli struct {
+	client     *client
+	jsonOutput bool
+}
+
+//An agent as returned by the REST API
+type agent struct {
+	ID string `json:"ID"`
+	types.Agent
+}
+
+type deployBody struct {
+	DeployArgs types.DeployArgs `json:"deployArgs"`
+	AuthInfo   types.AuthInfo   `json:"authInfo"`
+}
+
+type scheduleBody struct {
+	DeployArgs  types.DeployArgs  `json:"deployArgs"`
+	AuthInfo    types.AuthInfo    `json:"authInfo"`
+	Constraints types.Constraints `json:"constraints"`
+}
+
+type replicaSetBody struct {
+	ReplicaSet types.ReplicaSet `json:"replicaSet"`
+	AuthInfo   types.AuthInfo   `json:"authInfo"`
+}
+
+func (c *cli) agents(command string, args []string) error {
+	switch command {
+	case "list":
+		var agents []agent
+		err := c.client.do(http.MethodGet, "/agents", nil, nil, &agents)
+		if err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJson(agents)
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(writer, "ID\tIP\tCONTAINERS\tDEVICES\tSENSORS\tLAST SEEN")
+		for _, agent := range agents {
+			fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\t%s\n", agent.ID, agent.InternalIP, len(agent.Containers),
+				strings.Join(agent.DeviceSupport, ","), strings.Join(agent.SensorSupport, ","), lastSeen(agent.LastAlive))
+		}
+		return writer.Flush()
+	case "get":
+		if len(args) != 1 {
+			return errUsage
+		}
+		var agent agent
+		err := c.client.do(http.MethodGet, "/agents/"+url.PathEscape(args[0]), nil, nil, &agent)
+		if err != nil {
+			return err
+		}
+		return printJson(agent)
+	case "revoke":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return c.client.do(http.MethodPost, "/agents/"+url.PathEscape(args[0])+"/revoke", nil, nil, nil)
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) containers(command string, args []string) error {
+	switch command {
+	case "list":
+		if len(args) != 1 {
+			return errUsage
+		}
+		var containers []types.Container
+		err := c.client.do(http.MethodGet, containerPath(args[0]), nil, nil, &containers)
+		if err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJson(containers)
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(writer, "ID\tIMAGE\tSTATUS\tCOMMAND")
+		for _, container := range containers {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", container.ID, container.Image, container.Status, container.Command)
+		}
+		return writer.Flush()
+	case "inspect":
+		if len(args) != 2 {
+			return errUsage
+		}
+		var container types.Container
+		err := c.client.do(http.MethodGet, containerPath(args[0], args[1]), nil, nil, &container)
+		if err != nil {
+			return err
+		}
+		return printJson(container)
+	case "spec":
+		if len(args) != 2 {
+			return errUsage
+		}
+		var spec interface{}
+		err := c.client.do(http.MethodGet, containerPath(args[0], args[1], "spec"), nil, nil, &spec)
+		if err != nil {
+			return err
+		}
+		return printJson(spec)
+	case "logs":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		tail := flags.Int("tail", 0, "Number of lines from the end. Defaults to 100")
+		since := flags.Int64("since", 0, "UNIX timestamp of the oldest line")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+		query := url.Values{}
+		if *tail != 0 {
+			query.Set("tail", strconv.Itoa(*tail))
+		}
+		if *since != 0 {
+			query.Set("since", strconv.FormatInt(*since, 10))
+		}
+		var lines []types.LogLine
+		err := c.client.do(http.MethodGet, containerPath(flags.Arg(0), flags.Arg(1), "logs"), query, nil, &lines)
+		if err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJson(lines)
+		}
+		for _, line := range lines {
+			if line.Stream == "stderr" {
+				fmt.Fprintln(os.Stderr, line.Text)
+			} else {
+				fmt.Println(line.Text)
+			}
+		}
+		return nil
+	case "exec":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		timeout := flags.Duration("timeout", 30*time.Second, "Time the command may run")
+		if err := flags.Parse(args); err != nil || flags.NArg() < 3 {
+			return errUsage
+		}
+		body := map[string]interface{}{
+			"command": flags.Args()[2:],
+			"timeout": timeout.Seconds(),
+		}
+		var result types.ExecResult
+		err := c.client.do(http.MethodPost, containerPath(flags.Arg(0), flags.Arg(1), "exec"), nil, body, &result)
+		if err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJson(result)
+		}
+		fmt.Print(result.Stdout)
+		fmt.Fprint(os.Stderr, result.Stderr)
+		if result.Truncated {
+			fmt.Fprintln(os.Stderr, "Output truncated by the agent")
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("command exited with code %d", result.ExitCode)
+		}
+		return nil
+	case "run", "update":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		file := flags.String("f", "-", "YAML or JSON file of the deploy arguments. - reads from stdin")
+		username := flags.String("username", "", "Registry username for pulling the image")
+		password := flags.String("password", "", "Registry password for pulling the image")
+		if err := flags.Parse(args); err != nil {
+			return errUsage
+		}
+		deployArgs, err := readDeployArgs(*file)
+		if err != nil {
+			return err
+		}
+		body := deployBody{
+			DeployArgs: deployArgs,
+			AuthInfo:   types.AuthInfo{Username: *username, Password: *password},
+		}
+		var containerId string
+		if command == "run" {
+			if flags.NArg() != 1 {
+				return errUsage
+			}
+			err = c.client.do(http.MethodPost, containerPath(flags.Arg(0)), nil, body, &containerId)
+		} else {
+			if flags.NArg() != 2 {
+				return errUsage
+			}
+			err = c.client.do(http.MethodPut, containerPath(flags.Arg(0), flags.Arg(1)), nil, body, &containerId)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(containerId)
+		return nil
+	case "schedule":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		file := flags.String("f", "-", "YAML or JSON file of the deploy arguments. - reads from stdin")
+		username := flags.String("username", "", "Registry username for pulling the image")
+		password := flags.String("password", "", "Registry password for pulling the image")
+		devices := flags.String("device", "", "Comma separated devices the agent must support")
+		sensors := flags.String("sensor", "", "Comma separated sensors the agent must support")
+		exclude := flags.String("exclude", "", "Comma separated agent IDs that must not be chosen")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+			return errUsage
+		}
+		deployArgs, err := readDeployArgs(*file)
+		if err != nil {
+			return err
+		}
+		body := scheduleBody{
+			DeployArgs: deployArgs,
+			AuthInfo:   types.AuthInfo{Username: *username, Password: *password},
+			Constraints: types.Constraints{
+				DeviceSupport: splitList(*devices),
+				SensorSupport: splitList(*sensors),
+				Exclude:       splitList(*exclude),
+			},
+		}
+		var placement struct {
+			AgentId     string `json:"agentId"`
+			ContainerId string `json:"containerId"`
+		}
+		err = c.client.do(http.MethodPost, "/schedule", nil, body, &placement)
+		if err != nil {
+			return err
+		}
+		fmt.Println(placement.AgentId + " " + placement.ContainerId)
+		return nil
+	case "stop":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return c.client.do(http.MethodPost, containerPath(args[0], args[1], "stop"), nil, nil, nil)
+	case "delete":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		deleteImage := flags.Bool("image", false, "Also delete the image of the container")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+		query := url.Values{}
+		query.Set("deleteImage", strconv.FormatBool(*deleteImage))
+		return c.client.do(http.MethodDelete, containerPath(flags.Arg(0), flags.Arg(1)), query, nil, nil)
+	default:
+		return errUsage
+	}
+}
+
+//Pods of the cloud deployments and jobs
+func (c *cli) pods(command string, args []string) error {
+	switch command {
+	case "logs":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		tail := flags.Int("tail", 0, "Number of lines from the end of each pod. Defaults to 100")
+		since := flags.Int64("since", 0, "UNIX timestamp of the oldest line")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+		var path string
+		switch types.KWorkload(flags.Arg(0)) {
+		case types.KWorkloadDeployment:
+			path = "/cloud/deployments/" + url.PathEscape(flags.Arg(1)) + "/logs"
+		case types.KWorkloadJob:
+			path = "/cloud/jobs/" + url.PathEscape(flags.Arg(1)) + "/logs"
+		default:
+			return errUsage
+		}
+		query := url.Values{}
+		if *tail != 0 {
+			query.Set("tail", strconv.Itoa(*tail))
+		}
+		if *since != 0 {
+			query.Set("since", strconv.FormatInt(*since, 10))
+		}
+		var lines []types.LogLine
+		err := c.client.do(http.MethodGet, path, query, nil, &lines)
+		if err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJson(lines)
+		}
+		for _, line := range lines {
+			fmt.Printf("[%s] %s\n", line.Pod, line.Text)
+		}
+		return nil
+	case "exec":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		container := flags.String("container", "", "Container of the pod. Defaults to the first container")
+		timeout := flags.Duration("timeout", 30*time.Second, "Time the command may run")
+		if err := flags.Parse(args); err != nil || flags.NArg() < 2 {
+			return errUsage
+		}
+		body := map[string]interface{}{
+			"command":   flags.Args()[1:],
+			"container": *container,
+			"timeout":   timeout.Seconds(),
+		}
+		var result types.ExecResult
+		err := c.client.do(http.MethodPost, "/cloud/pods/"+url.PathEscape(flags.Arg(0))+"/exec", nil, body, &result)
+		if err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJson(result)
+		}
+		fmt.Print(result.Stdout)
+		fmt.Fprint(os.Stderr, result.Stderr)
+		if result.Truncated {
+			fmt.Fprintln(os.Stderr, "Output truncated by the controller")
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("command exited with code %d", result.ExitCode)
+		}
+		return nil
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) metrics(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	timestamp := flags.Int64("time", 0, "UNIX timestamp of the query. Defaults to now")
+	from := flags.Int64("from", 0, "UNIX timestamp of the start of a range query")
+	to := flags.Int64("to", 0, "UNIX timestamp of the end of a range query. Defaults to now")
+	step := flags.Duration("step", 0, "Resolution of a range query, e.g. 15s. Setting it makes the query a range query")
+	query := url.Values{}
+	var path string
+	switch command {
+	case "edge":
+		containerId := flags.String("container", "", "Container ID. Required for container metrics")
+		promQL := flags.String("query", "", "PromQL query. Required for the promql command")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+		if *containerId != "" {
+			query.Set("containerId", *containerId)
+		}
+		if *promQL != "" {
+			query.Set("query", *promQL)
+		}
+		path = "/agents/" + url.PathEscape(flags.Arg(0)) + "/metrics/" + url.PathEscape(flags.Arg(1))
+	case "cloud":
+		target := flags.String("target", "", "Node name, node IP or pod name")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+			return errUsage
+		}
+		query.Set("target", *target)
+		path = "/cloud/metrics/" + url.PathEscape(flags.Arg(0))
+	default:
+		return errUsage
+	}
+	if *timestamp != 0 {
+		query.Set("time", strconv.FormatInt(*timestamp, 10))
+	}
+	if *step != 0 {
+		if *from == 0 {
+			return errUsage
+		}
+		query.Set("from", strconv.FormatInt(*from, 10))
+		query.Set("step", strconv.FormatInt(int64(step.Seconds()), 10))
+		if *to != 0 {
+			query.Set("to", strconv.FormatInt(*to, 10))
+		}
+	}
+	var result interface{}
+	err := c.client.do(http.MethodGet, path, query, nil, &result)
+	if err != nil {
+		return err
+	}
+	return printJson(result)
+}
+
+func (c *cli) events(command string, args []string) error {
+	if command != "list" {
+		return errUsage
+	}
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	agentId := flags.String("agent", "", "Only events of this agent")
+	eventType := flags.String("type", "", "Only events of this type, e.g. container-crash or pod-status")
+	from := flags.Int64("from", 0, "UNIX timestamp of the oldest event")
+	to := flags.Int64("to", 0, "UNIX timestamp of the newest event")
+	limit := flags.Int("limit", 0, "Maximum number of events. Defaults to 100")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+	query := url.Values{}
+	if *agentId != "" {
+		query.Set("agent", *agentId)
+	}
+	if *eventType != "" {
+		query.Set("type", *eventType)
+	}
+	if *from != 0 {
+		query.Set("from", strconv.FormatInt(*from, 10))
+	}
+	if *to != 0 {
+		query.Set("to", strconv.FormatInt(*to, 10))
+	}
+	if *limit != 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+	var events []types.Event
+	err := c.client.do(http.MethodGet, "/events", query, nil, &events)
+	if err != nil {
+		return err
+	}
+	if c.jsonOutput {
+		return printJson(events)
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tSOURCE\tAGENT\tTYPE\tTARGET")
+	for _, event := range events {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", event.Time.Local().Format(time.RFC3339), event.Source, event.AgentId, event.Type, event.Target)
+	}
+	return writer.Flush()
+}
+
+//Edge replica sets
+func (c *cli) replicaSets(command string, args []string) error {
+	switch command {
+	case "list":
+		if len(args) != 0 {
+			return errUsage
+		}
+		var sets []types.ReplicaSetStatus
+		err := c.client.do(http.MethodGet, "/replicasets", nil, nil, &sets)
+		if err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJson(sets)
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(writer, "NAME\tIMAGE\tREPLICAS\tRUNNING\tSPREAD\tAGENTS")
+		for _, set := range sets {
+			fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%s\t%d\n", set.Name, set.DeployArgs.Image, set.Replicas, set.Running, set.Spread, len(set.Placement))
+		}
+		return writer.Flush()
+	case "get":
+		if len(args) != 1 {
+			return errUsage
+		}
+		var set types.ReplicaSetStatus
+		err := c.client.do(http.MethodGet, "/replicasets/"+url.PathEscape(args[0]), nil, nil, &set)
+		if err != nil {
+			return err
+		}
+		return printJson(set)
+	case "apply":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		file := flags.String("f", "-", "YAML or JSON file of the replica set. - reads from stdin")
+		username := flags.String("username", "", "Registry username for pulling the image")
+		password := flags.String("password", "", "Registry password for pulling the image")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+			return errUsage
+		}
+		set, err := readReplicaSet(*file)
+		if err != nil {
+			return err
+		}
+		body := replicaSetBody{
+			ReplicaSet: set,
+			AuthInfo:   types.AuthInfo{Username: *username, Password: *password},
+		}
+		return c.client.do(http.MethodPost, "/replicasets", nil, body, nil)
+	case "scale":
+		if len(args) != 2 {
+			return errUsage
+		}
+		replicas, err := strconv.Atoi(args[1])
+		if err != nil || replicas < 0 {
+			return errUsage
+		}
+		body := map[string]interface{}{"replicas": replicas}
+		return c.client.do(http.MethodPost, "/replicasets/"+url.PathEscape(args[0])+"/scale", nil, body, nil)
+	case "delete":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return c.client.do(http.MethodDelete, "/replicasets/"+url.PathEscape(args[0]), nil, nil, nil)
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) tokens(command string, args []string) error {
+	switch command {
+	case "list":
+		if len(args) != 0 {
+			return errUsage
+		}
+		var tokens []types.BootstrapToken
+		err := c.client.do(http.MethodGet, "/tokens", nil, nil, &tokens)
+		if err != nil {
+			return err
+		}
+		if c.jsonOutput {
+			return printJson(tokens)
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(writer, "ID\tDESCRIPTION\tCREATED\tEXPIRES\tUSED")
+		for _, token := range tokens {
+			expires := "never"
+			if token.Expires != nil {
+				expires = token.Expires.Local().Format(time.RFC3339)
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%t\n", token.ID, token.Description, token.Created.Local().Format(time.RFC3339), expires, token.Used)
+		}
+		return writer.Flush()
+	case "create":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		description := flags.String("description", "", "Description of the token, e.g. the device it is for")
+		ttl := flags.Duration("ttl", 0, "Time until the token expires, e.g. 24h. 0 means the token does not expire")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+			return errUsage
+		}
+		body := map[string]interface{}{
+			"description": *description,
+			"ttl":         int64(ttl.Seconds()),
+		}
+		var created struct {
+			Token string `json:"token"`
+		}
+		err := c.client.do(http.MethodPost, "/tokens", nil, body, &created)
+		if err != nil {
+			return err
+		}
+		fmt.Println(created.Token)
+		return nil
+	case "delete":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return c.client.do(http.MethodDelete, "/tokens/"+url.PathEscape(args[0]), nil, nil, nil)
+	default:
+		return errUsage
+	}
+}
+
+//Builds the path of the containers endpoint from the agent ID and the optional container ID and action
+func containerPath(agentId string, segments ...string) string {
+	path := "/agents/" + url.PathEscape(agentId) + "/containers"
+	for _, segment := range segments {
+		path += "/" + url.PathEscape(segment)
+	}
+	return path
+}
+
+//Reads deploy arguments from a YAML or JSON file. YAML is a superset of JSON, so both are parsed the same way
+func readDeployArgs(file string) (types.DeployArgs, error) {
+	var deployArgs types.DeployArgs
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return deployArgs, err
+	}
+	err = yaml.Unmarshal(content, &deployArgs)
+	if err != nil {
+		return deployArgs, err
+	}
+	if deployArgs.Image == "" {
+		return deployArgs, errors.New("image is required")
+	}
+	return deployArgs, nil
+}
+
+//Reads a replica set from a YAML or JSON file
+func readReplicaSet(file string) (types.ReplicaSet, error) {
+	var set types.ReplicaSet
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return set, err
+	}
+	err = yaml.Unmarshal(content, &set)
+	if err != nil {
+		return set, err
+	}
+	if set.Name == "" || set.DeployArgs.Image == "" {
+		return set, errors.New("name and image are required")
+	}
+	return set, nil
+}
+
+//Splits a comma separated flag value. An empty value gives an empty list
+func splitList(value string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func printJson(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+//Formats the last heartbeat of an agent (UNIX seconds) as a duration
+func lastSeen(lastAlive int64) string {
+	if lastAlive == 0 {
+		return "never"
+	}
+	return time.Since(time.Unix(lastAlive, 0)).Round(time.Second).String() + " ago"
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+/*
+osmoticctl - Command line client for a running controller
+This talks to the REST API of the controller. The API must be enabled with "enable_rest_api" in the controller properties file.
+The controller address and token can be set with flags or the OSMOTIC_CONTROLLER and OSMOTIC_TOKEN environment variables.
+*/
+
+const usage = `Usage: osmoticctl [flags] <command> [args]
+
+Commands:
+  agents list
+  agents get <agentId>
+  agents revoke <agentId>
+  containers list <agentId>
+  containers inspect <agentId> <containerId>
+  containers spec <agentId> <containerId>
+  containers logs [-tail n] [-since unix] <agentId> <containerId>
+  containers exec [-timeout duration] <agentId> <containerId> <command> [args...]
+  containers run [-f file] [-username user] [-password pass] <agentId>
+  containers schedule [-f file] [-username user] [-password pass] [-device a,b] [-sensor a,b] [-exclude agentId,...]
+  containers update [-f file] [-username user] [-password pass] <agentId> <containerId>
+  containers stop <agentId> <containerId>
+  containers delete [-image] <agentId> <containerId>
+  pods logs [-tail n] [-since unix] deployment|job <name>
+  pods exec [-container name] [-timeout duration] <pod> <command> [args...]
+  metrics edge [-container containerId] [-query promql] [-time unix | -from unix [-to unix] -step duration] <agentId> <command>
+  metrics cloud [-target target] [-time unix | -from unix [-to unix] -step duration] <command>
+  events list [-agent agentId] [-type type] [-from unix] [-to unix] [-limit n]
+  replicasets list
+  replicasets get <name>
+  replicasets apply [-f file] [-username user] [-password pass]
+  replicasets scale <name> <replicas>
+  replicasets delete <name>
+  tokens list
+  tokens create [-description text] [-ttl duration]
+  tokens delete <tokenId>
+
+Deploy files are YAML or JSON documents of DeployArgs. See controller/types/Deploy.go
+Replica set files are YAML or JSON documents of ReplicaSet. See controller/types/ReplicaSet.go
+
+Flags:
+`
+
+func main() {
+	controller := flag.String("controller", envOrDefault("OSMOTIC_CONTROLLER", "http://localhost:8000"), "Address of the controller REST API")
+	token := flag.String("token", os.Getenv("OSMOTIC_TOKEN"), "Bearer token of the controller REST API")
+	jsonOutput := flag.Bool("json", false, "Print raw JSON instead of tables")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cli := &cli{
+		client:     newClient(*controller, *token),
+		jsonOutput: *jsonOutput,
+	}
+	var err error
+	switch args[0] {
+	case "agents":
+		err = cli.agents(args[1], args[2:])
+	case "containers":
+		err = cli.containers(args[1], args[2:])
+	case "pods":
+		err = cli.pods(args[1], args[2:])
+	case "metrics":
+		err = cli.metrics(args[1], args[2:])
+	case "events":
+		err = cli.events(args[1], args[2:])
+	case "replicasets":
+		err = cli.replicaSets(args[1], args[2:])
+	case "tokens":
+		err = cli.tokens(args[1], args[2:])
+	default:
+		err = errUsage
+	}
+	if err == errUsage {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -27,5 +27,51 @@ CREATE TABLE IF NOT EXISTS containers
     ContainerId CHAR(64)                       NOT NULL,
     AgentId     CHAR(22)                       NOT NULL,
     Status      VARCHAR(10)                    NOT NULL,
+    DeployArgs  JSON                           NULL,
+    RequestTime DATETIME                       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Origin      VARCHAR(16)                    NOT NULL DEFAULT 'api',
+    Restarts    INT                            NOT NULL DEFAULT 0,
     FOREIGN KEY (AgentId) REFERENCES registry (AgentId)
+);
+-- Per-agent secrets, created at registration. Kept when the agent is unregistered, so it can rejoin later
+CREATE TABLE IF NOT EXISTS agentKeys
+(
+    AgentId CHAR(22) PRIMARY KEY NOT NULL,
+    Secret  CHAR(64)             NOT NULL
+);
+-- One-time bootstrap tokens for registering agents. Only the SHA-256 hash of a token is stored
+CREATE TABLE IF NOT EXISTS bootstrapTokens
+(
+    TokenHash   CHAR(64) PRIMARY KEY NOT NULL,
+    Description VARCHAR(64)          NOT NULL DEFAULT '',
+    Created     DATETIME             NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Expires     DATETIME             NULL,
+    Used        BOOL                 NOT NULL DEFAULT FALSE
+);
+CREATE TABLE IF NOT EXISTS revokedAgents
+(
+    AgentId   CHAR(22) PRIMARY KEY NOT NULL,
+    RevokedAt DATETIME             NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Event history for post-mortems. Kept after the agents are unregistered, so there is no foreign key on AgentId
+CREATE TABLE IF NOT EXISTS events
+(
+    ID      BIGINT AUTO_INCREMENT PRIMARY KEY NOT NULL,
+    Time    DATETIME(3)                       NOT NULL,
+    Source  VARCHAR(8)                        NOT NULL,
+    AgentId CHAR(22)                          NULL,
+    Type    VARCHAR(32)                       NOT NULL,
+    Target  VARCHAR(253)                      NULL,
+    Payload JSON                              NULL,
+    INDEX (Time),
+    INDEX (AgentId, Time),
+    INDEX (Type, Time)
+);
+
+-- Edge replica sets. The authentication information of the images is not stored
+CREATE TABLE IF NOT EXISTS replicaSets
+(
+    Name VARCHAR(64) PRIMARY KEY NOT NULL,
+    Spec JSON                    NOT NULL
 );
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- Adds the deploy specs of containers to databases created before they were stored
+-- Run once against an existing database. New databases created from init.sql already have these columns
+USE agents;
+ALTER TABLE containers
+    ADD COLUMN DeployArgs  JSON        NULL,
+    ADD COLUMN RequestTime DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN Origin      VARCHAR(16) NOT NULL DEFAULT 'api';
@@ -0,0 +1,23 @@
+-- Adds the tables for authenticated registration to databases created before they existed
+-- Run once against an existing database. New databases created from init.sql already have these tables
+USE agents;
+-- Per-agent secrets, created at registration. Kept when the agent is unregistered, so it can rejoin later
+CREATE TABLE IF NOT EXISTS agentKeys
+(
+    AgentId CHAR(22) PRIMARY KEY NOT NULL,
+    Secret  CHAR(64)             NOT NULL
+);
+-- One-time bootstrap tokens for registering agents. Only the SHA-256 hash of a token is stored
+CREATE TABLE IF NOT EXISTS bootstrapTokens
+(
+    TokenHash   CHAR(64) PRIMARY KEY NOT NULL,
+    Description VARCHAR(64)          NOT NULL DEFAULT '',
+    Created     DATETIME             NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Expires     DATETIME             NULL,
+    Used        BOOL                 NOT NULL DEFAULT FALSE
+);
+CREATE TABLE IF NOT EXISTS revokedAgents
+(
+    AgentId   CHAR(22) PRIMARY KEY NOT NULL,
+    RevokedAt DATETIME             NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,17 @@
+-- Adds the event history table to databases created before it existed
+-- Run once against an existing database. New databases created from init.sql already have this table
+USE agents;
+-- Event history for post-mortems. Kept after the agents are unregistered, so there is no foreign key on AgentId
+CREATE TABLE IF NOT EXISTS events
+(
+    ID      BIGINT AUTO_INCREMENT PRIMARY KEY NOT NULL,
+    Time    DATETIME(3)                       NOT NULL,
+    Source  VARCHAR(8)                        NOT NULL,
+    AgentId CHAR(22)                          NULL,
+    Type    VARCHAR(32)                       NOT NULL,
+    Target  VARCHAR(253)                      NULL,
+    Payload JSON                              NULL,
+    INDEX (Time),
+    INDEX (AgentId, Time),
+    INDEX (Type, Time)
+);
@@ -0,0 +1,5 @@
+-- Adds the restart counts of containers to databases created before they were stored
+-- Run once against an existing database. New databases created from init.sql already have this column
+USE agents;
+ALTER TABLE containers
+    ADD COLUMN Restarts INT NOT NULL DEFAULT 0;
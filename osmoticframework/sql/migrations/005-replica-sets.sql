@@ -0,0 +1,9 @@
+-- Adds the edge replica sets to databases created before they existed
+-- Run once against an existing database. New databases created from init.sql already have this table
+USE agents;
+-- Edge replica sets. The authentication information of the images is not stored
+CREATE TABLE IF NOT EXISTS replicaSets
+(
+    Name VARCHAR(64) PRIMARY KEY NOT NULL,
+    Spec JSON                    NOT NULL
+);